@@ -5,6 +5,7 @@ Copyright 2024 The provider-kind authors.
 package v1alpha1
 
 import (
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	xpv1 "github.com/crossplane/crossplane-runtime/v2/apis/common/v1"
@@ -47,6 +48,383 @@ type ClusterParameters struct {
 	// ready after creation (e.g. "5m", "30s"). Defaults to no wait.
 	// +optional
 	WaitForReady *string `json:"waitForReady,omitempty"`
+
+	// TrustedCAs are additional PEM-encoded CA certificates installed into
+	// the trust store and the containerd registry configuration of every
+	// node. Nodes that join the cluster later receive them as well.
+	// +optional
+	TrustedCAs []CertificateSource `json:"trustedCAs,omitempty"`
+
+	// Registries configures how containerd on every node reaches image
+	// registries: mirror endpoints, TLS settings, and credentials. The
+	// provider renders containerd's hosts.toml files inside the nodes.
+	// Credentials are only ever read from referenced Secrets.
+	// +optional
+	// +listType=map
+	// +listMapKey=host
+	Registries []RegistryConfig `json:"registries,omitempty"`
+
+	// LocalRegistryRef references a Registry managed resource. The provider
+	// configures containerd on every node to pull from it, and publishes
+	// the local-registry-hosting ConfigMap in kube-public so that tools can
+	// discover it.
+	// +optional
+	LocalRegistryRef *LocalRegistryReference `json:"localRegistryRef,omitempty"`
+
+	// NetworkRef references a Network managed resource to create the nodes
+	// on. Mutually exclusive with DockerNetwork. Nodes are created on the
+	// shared "kind" network if neither is set.
+	// +optional
+	NetworkRef *NetworkReference `json:"networkRef,omitempty"`
+
+	// DockerNetwork is the name of an existing Docker network to create the
+	// nodes on. KIND creates the network if it does not exist. Mutually
+	// exclusive with NetworkRef.
+	// +optional
+	DockerNetwork *string `json:"dockerNetwork,omitempty"`
+
+	// LoadBalancer enables Services of type LoadBalancer. The provider
+	// assigns each one an address from the pool and adds the addresses to a
+	// node, where kube-proxy forwards the traffic to the Service.
+	// +optional
+	LoadBalancer *LoadBalancer `json:"loadBalancer,omitempty"`
+
+	// APIServerService publishes the API server as a Service in the
+	// management cluster, for pods that cannot reach the loopback address
+	// of the Docker host. A kubeconfig that uses the Service is added to the
+	// connection details as kubeconfig-service.
+	// +optional
+	APIServerService *APIServerService `json:"apiServerService,omitempty"`
+
+	// PrimaryKubeconfig selects the kubeconfig published under the
+	// kubeconfig connection detail key. Every kubeconfig is also published
+	// under its own key: kubeconfig-external, whose server is the API
+	// server port on the Docker host; kubeconfig-internal, whose server is
+	// the control plane container on the Docker network; and, with an
+	// apiServerService, kubeconfig-service. Defaults to external.
+	// +optional
+	// +kubebuilder:validation:Enum=external;internal;service
+	PrimaryKubeconfig *string `json:"primaryKubeconfig,omitempty"`
+
+	// ServiceAccountToken creates a ServiceAccount in the cluster, binds it
+	// to a ClusterRole, and publishes its long-lived token under the token
+	// connection detail. No ServiceAccount is created without it.
+	// +optional
+	ServiceAccountToken *ServiceAccountToken `json:"serviceAccountToken,omitempty"`
+
+	// CertificateRotation configures when the CertificatesExpiring
+	// condition is raised, and whether the control plane certificates are
+	// renewed automatically. kubeadm issues them for one year.
+	// +optional
+	CertificateRotation *CertificateRotation `json:"certificateRotation,omitempty"`
+
+	// ArgoCD registers the cluster with Argo CD by writing a declarative
+	// cluster Secret. The Secret follows the primary kubeconfig, so Argo CD
+	// running in the management cluster usually wants primaryKubeconfig
+	// service.
+	// +optional
+	ArgoCD *ArgoCD `json:"argocd,omitempty"`
+
+	// PublishProviderConfigs creates ProviderConfigs for provider-kubernetes
+	// and provider-helm that read the kubeconfig from the connection secret,
+	// once the cluster is ready. It requires writeConnectionSecretToRef.
+	// They are deleted with the cluster.
+	// +optional
+	PublishProviderConfigs *PublishProviderConfigs `json:"publishProviderConfigs,omitempty"`
+
+	// KubeconfigSecretFormats writes the primary kubeconfig to extra Secrets
+	// in the formats other tools read, next to the connection secret. The
+	// Secrets are owned by this managed resource and deleted with it.
+	// +optional
+	KubeconfigSecretFormats []KubeconfigSecretFormat `json:"kubeconfigSecretFormats,omitempty"`
+
+	// Bootstrap applies manifests to the cluster once it is created, such
+	// as namespaces, a CNI, RBAC, and CRDs. The cluster is not Ready until
+	// every step has been applied.
+	// +optional
+	Bootstrap *Bootstrap `json:"bootstrap,omitempty"`
+}
+
+// CertificateRotation configures the renewal of the control plane
+// certificates.
+type CertificateRotation struct {
+	// RenewBefore is how long before the kubeconfig client certificate
+	// expires the CertificatesExpiring condition is raised and, if
+	// Automatic is set, the certificates are renewed. Defaults to 720h.
+	// +optional
+	RenewBefore *string `json:"renewBefore,omitempty"`
+
+	// Automatic renews the certificates with kubeadm on every control plane
+	// node once they are due, restarts the control plane, and republishes
+	// the connection details.
+	// +optional
+	Automatic bool `json:"automatic,omitempty"`
+}
+
+// LoadBalancer configures the addresses assigned to LoadBalancer Services.
+type LoadBalancer struct {
+	// AddressPool is the range of addresses assigned to LoadBalancer
+	// Services, either a CIDR ("172.18.255.200/29") or an inclusive range
+	// ("172.18.255.200-172.18.255.250"). It must lie within a subnet of the
+	// cluster's Docker network, away from the low end Docker assigns
+	// container addresses from.
+	// +kubebuilder:validation:MinLength=1
+	AddressPool string `json:"addressPool"`
+}
+
+// APIServerService configures the Service that publishes the API server in
+// the management cluster.
+type APIServerService struct {
+	// Name of the Service. Defaults to the KIND cluster name.
+	// +optional
+	Name *string `json:"name,omitempty"`
+
+	// Namespace of the Service. Required for cluster-scoped Clusters.
+	// Namespaced Clusters always use their own namespace.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// ServiceAccountToken configures the ServiceAccount whose token is
+// published as a connection detail.
+type ServiceAccountToken struct {
+	// ClusterRole the ServiceAccount is bound to, for example view or
+	// cluster-admin.
+	// +kubebuilder:validation:MinLength=1
+	ClusterRole string `json:"clusterRole"`
+}
+
+// ArgoCD configures the Argo CD cluster Secret of a cluster.
+type ArgoCD struct {
+	// Namespace Argo CD runs in, where the Secret is written. Only used by
+	// cluster-scoped Clusters. Namespaced Clusters always write the Secret
+	// to their own namespace, so Argo CD only registers those created in the
+	// namespace it runs in.
+	// +optional
+	// +kubebuilder:default=argocd
+	Namespace string `json:"namespace,omitempty"`
+
+	// SecretName of the cluster Secret. Defaults to the KIND cluster name.
+	// +optional
+	SecretName *string `json:"secretName,omitempty"`
+
+	// ClusterName is the name Argo CD shows for the cluster. Defaults to the
+	// KIND cluster name.
+	// +optional
+	ClusterName *string `json:"clusterName,omitempty"`
+
+	// Credentials Argo CD authenticates with: the client certificate of the
+	// primary kubeconfig, or the bearer token of the ServiceAccount
+	// configured by serviceAccountToken, which token requires.
+	// +optional
+	// +kubebuilder:default=clientCertificate
+	// +kubebuilder:validation:Enum=token;clientCertificate
+	Credentials string `json:"credentials,omitempty"`
+
+	// Labels added to the Secret, for example to select the cluster with an
+	// ApplicationSet cluster generator.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// PublishProviderConfigs configures the ProviderConfigs published for a
+// cluster.
+type PublishProviderConfigs struct {
+	// Name of the ProviderConfigs. Defaults to the KIND cluster name.
+	// +optional
+	Name *string `json:"name,omitempty"`
+
+	// Kubernetes publishes a provider-kubernetes ProviderConfig. Defaults
+	// to true.
+	// +optional
+	Kubernetes *bool `json:"kubernetes,omitempty"`
+
+	// Helm publishes a provider-helm ProviderConfig. Defaults to true.
+	// +optional
+	Helm *bool `json:"helm,omitempty"`
+}
+
+// KubeconfigSecretFormat is an extra Secret holding the primary kubeconfig
+// under the value key.
+type KubeconfigSecretFormat struct {
+	// Format of the Secret. A flux Secret is an Opaque Secret for a Flux
+	// kubeConfig.secretRef. A clusterAPI Secret is named
+	// <cluster>-kubeconfig, has type cluster.x-k8s.io/secret, and is
+	// labelled cluster.x-k8s.io/cluster-name.
+	// +kubebuilder:validation:Enum=flux;clusterAPI
+	Format string `json:"format"`
+
+	// Namespace of the Secret. Required for cluster-scoped Clusters.
+	// Namespaced Clusters always use their own namespace.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Name of a flux Secret, or the Cluster API cluster name of a
+	// clusterAPI Secret. A flux Secret defaults to
+	// <KIND cluster name>-flux-kubeconfig; the Cluster API cluster name
+	// defaults to the KIND cluster name.
+	// +optional
+	Name *string `json:"name,omitempty"`
+}
+
+// Bootstrap configures the manifests applied to a new cluster.
+type Bootstrap struct {
+	// Steps are server-side applied in order. A step is applied once its
+	// predecessors have been, and again whenever its manifests change. A
+	// step that fails is retried. Objects whose kind is defined by a CRD
+	// must be in a later step than the CRD.
+	// +listType=map
+	// +listMapKey=name
+	Steps []BootstrapStep `json:"steps"`
+}
+
+// BootstrapStep applies the manifests of a Secret or ConfigMap.
+type BootstrapStep struct {
+	// Name of the step, reported in status.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// SecretRef selects a Secret containing the manifests.
+	// +optional
+	SecretRef *ManifestSelector `json:"secretRef,omitempty"`
+
+	// ConfigMapRef selects a ConfigMap containing the manifests.
+	// +optional
+	ConfigMapRef *ManifestSelector `json:"configMapRef,omitempty"`
+}
+
+// ManifestSelector selects YAML manifests in a Secret or ConfigMap.
+type ManifestSelector struct {
+	// Name of the Secret or ConfigMap.
+	Name string `json:"name"`
+
+	// Namespace of the Secret or ConfigMap. Required for cluster-scoped
+	// Clusters. Namespaced Clusters may only reference objects in their own
+	// namespace, so this field is ignored for them.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Key within the Secret or ConfigMap data. Every key is used, in
+	// lexical order, if it is not set.
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
+// NetworkReference references a Network managed resource.
+type NetworkReference struct {
+	// Name of the Network.
+	Name string `json:"name"`
+}
+
+// LocalRegistryReference references a Registry managed resource.
+type LocalRegistryReference struct {
+	// Name of the Registry.
+	Name string `json:"name"`
+}
+
+// RegistryConfig configures a single image registry host.
+type RegistryConfig struct {
+	// Host is the registry host as it appears in image references, for
+	// example "docker.io" or "registry.example.com:5000".
+	Host string `json:"host"`
+
+	// Server overrides the upstream URL of the registry host. Defaults to
+	// https://<host>, or https://registry-1.docker.io for docker.io.
+	// +optional
+	Server *string `json:"server,omitempty"`
+
+	// Mirrors are endpoints tried in order before the upstream server.
+	// +optional
+	Mirrors []RegistryMirror `json:"mirrors,omitempty"`
+
+	// TLS configures TLS for connections to the upstream server.
+	// +optional
+	TLS *RegistryTLS `json:"tls,omitempty"`
+
+	// AuthSecretRef references a Secret of type
+	// kubernetes.io/dockerconfigjson. Credentials it holds for this host
+	// and its mirrors are made available to the kubelet on every node.
+	// +optional
+	AuthSecretRef *SecretReference `json:"authSecretRef,omitempty"`
+}
+
+// RegistryMirror is a mirror endpoint for a registry host.
+type RegistryMirror struct {
+	// Endpoint is the URL of the mirror, for example
+	// "https://mirror.example.com".
+	Endpoint string `json:"endpoint"`
+
+	// Capabilities the mirror is trusted with. Defaults to pull and resolve.
+	// +optional
+	// +kubebuilder:validation:items:Enum=pull;resolve;push
+	Capabilities []string `json:"capabilities,omitempty"`
+
+	// OverridePath indicates the endpoint URL already includes the API
+	// root path, as is common for mirrors that are not at the host root.
+	// +optional
+	OverridePath *bool `json:"overridePath,omitempty"`
+
+	// TLS configures TLS for connections to the mirror.
+	// +optional
+	TLS *RegistryTLS `json:"tls,omitempty"`
+}
+
+// RegistryTLS configures TLS for a registry endpoint.
+type RegistryTLS struct {
+	// InsecureSkipVerify disables verification of the endpoint's
+	// certificate chain and host name.
+	// +optional
+	InsecureSkipVerify *bool `json:"insecureSkipVerify,omitempty"`
+
+	// CAs are additional CA certificates trusted for this endpoint, on top
+	// of the cluster's trustedCAs.
+	// +optional
+	CAs []CertificateSource `json:"cas,omitempty"`
+
+	// ClientCertSecretRef references a Secret of type kubernetes.io/tls
+	// holding a client certificate and key presented to the endpoint.
+	// +optional
+	ClientCertSecretRef *SecretReference `json:"clientCertSecretRef,omitempty"`
+}
+
+// SecretReference references a Secret.
+type SecretReference struct {
+	// Name of the Secret.
+	Name string `json:"name"`
+
+	// Namespace of the Secret. Required for cluster-scoped Clusters.
+	// Namespaced Clusters may only reference Secrets in their own
+	// namespace, so this field is ignored for them.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// CertificateSource selects a PEM-encoded certificate from a Secret or a
+// ConfigMap. Exactly one of SecretRef or ConfigMapRef must be set.
+type CertificateSource struct {
+	// SecretRef selects a key of a Secret containing the certificate.
+	// +optional
+	SecretRef *KeySelector `json:"secretRef,omitempty"`
+
+	// ConfigMapRef selects a key of a ConfigMap containing the certificate.
+	// +optional
+	ConfigMapRef *KeySelector `json:"configMapRef,omitempty"`
+}
+
+// KeySelector selects a key of a Secret or ConfigMap.
+type KeySelector struct {
+	// Name of the Secret or ConfigMap.
+	Name string `json:"name"`
+
+	// Namespace of the Secret or ConfigMap. Required for cluster-scoped
+	// Clusters. Namespaced Clusters may only reference objects in their own
+	// namespace, so this field is ignored for them.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Key within the Secret or ConfigMap data. Defaults to "ca.crt".
+	// +optional
+	Key string `json:"key,omitempty"`
 }
 
 // Node defines a KIND cluster node.
@@ -62,7 +440,7 @@ type Node struct {
 	Image *string `json:"image,omitempty"`
 
 	// ExtraMounts are additional directory or file mounts from the
-	// host into the node container.
+	// host, or from ConfigMaps and Secrets, into the node container.
 	// +optional
 	ExtraMounts []Mount `json:"extraMounts,omitempty"`
 
@@ -80,30 +458,88 @@ type Node struct {
 	// Labels are additional labels to apply to the node.
 	// +optional
 	Labels map[string]string `json:"labels,omitempty"`
+
+	// Resources limit the host resources the node container may use. Limits
+	// that are not set default to those of the ProviderConfig.
+	// +optional
+	Resources *NodeResources `json:"resources,omitempty"`
 }
 
-// Mount defines a bind mount from the host into a KIND node container.
+// NodeResources limit the host resources a node container may use. Limits
+// that are not set are not enforced.
+type NodeResources struct {
+	// CPUs the node may use, for example "2" or "1500m".
+	// +optional
+	CPUs *resource.Quantity `json:"cpus,omitempty"`
+
+	// Memory the node may use, for example "4Gi". The node gets no swap.
+	// +optional
+	Memory *resource.Quantity `json:"memory,omitempty"`
+
+	// PidsLimit is the maximum number of processes in the node.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	PidsLimit *int64 `json:"pidsLimit,omitempty"`
+}
+
+// Mount defines a bind mount from the host into a KIND node container, or
+// the content of a ConfigMap or Secret copied into it. Exactly one of
+// HostPath, ConfigMapRef, or SecretRef must be set.
 type Mount struct {
 	// HostPath is the absolute path on the host to mount.
-	HostPath string `json:"hostPath"`
+	// +optional
+	HostPath string `json:"hostPath,omitempty"`
+
+	// ConfigMapRef selects a ConfigMap whose content is copied into the
+	// node container once it was created, and again whenever it changes.
+	// +optional
+	ConfigMapRef *MountSource `json:"configMapRef,omitempty"`
+
+	// SecretRef selects a Secret whose content is copied into the node
+	// container once it was created, and again whenever it changes. The
+	// files are readable by root only.
+	// +optional
+	SecretRef *MountSource `json:"secretRef,omitempty"`
 
 	// ContainerPath is the path inside the node container to mount to.
 	ContainerPath string `json:"containerPath"`
 
-	// Readonly makes the mount read-only inside the container.
+	// Readonly makes the mount read-only inside the container. Only
+	// applies to host paths.
 	// +optional
 	Readonly *bool `json:"readonly,omitempty"`
 
 	// SelinuxRelabel enables SELinux relabeling on the mounted directory.
+	// Only applies to host paths.
 	// +optional
 	SelinuxRelabel *bool `json:"selinuxRelabel,omitempty"`
 
-	// Propagation sets the mount propagation mode.
+	// Propagation sets the mount propagation mode. Only applies to host
+	// paths.
 	// +optional
 	// +kubebuilder:validation:Enum=None;HostToContainer;Bidirectional
 	Propagation *string `json:"propagation,omitempty"`
 }
 
+// MountSource selects the content of a ConfigMap or Secret to copy into a
+// node container.
+type MountSource struct {
+	// Name of the ConfigMap or Secret.
+	Name string `json:"name"`
+
+	// Namespace of the ConfigMap or Secret. Required for cluster-scoped
+	// Clusters. Namespaced Clusters may only reference objects in their own
+	// namespace, so this field is ignored for them.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Key within the ConfigMap or Secret data, copied to the file at the
+	// container path. Every key is copied to a file of the same name in the
+	// container path directory if it is not set.
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
 // PortMapping defines a port forwarding from the node container to the host.
 type PortMapping struct {
 	// ContainerPort is the port inside the node container.
@@ -174,6 +610,80 @@ type ClusterObservation struct {
 	// APIServerEndpoint is the address of the Kubernetes API server.
 	// +optional
 	APIServerEndpoint *string `json:"apiServerEndpoint,omitempty"`
+
+	// LoadBalancers are the LoadBalancer Services of the cluster and the
+	// addresses assigned to them.
+	// +optional
+	LoadBalancers []LoadBalancerObservation `json:"loadBalancers,omitempty"`
+
+	// Certificates are the expiry times of the certificates in the primary
+	// kubeconfig.
+	// +optional
+	Certificates *CertificatesObservation `json:"certificates,omitempty"`
+
+	// Bootstrap is the observed state of each bootstrap step.
+	// +optional
+	Bootstrap []BootstrapStepObservation `json:"bootstrap,omitempty"`
+}
+
+// Phases of a bootstrap step.
+const (
+	BootstrapStepPending = "Pending"
+	BootstrapStepApplied = "Applied"
+	BootstrapStepFailed  = "Failed"
+)
+
+// BootstrapStepObservation is the observed state of a bootstrap step.
+type BootstrapStepObservation struct {
+	// Name of the step.
+	Name string `json:"name"`
+
+	// Phase of the step: Pending, Applied, or Failed.
+	Phase string `json:"phase"`
+
+	// Hash of the manifests the step last applied.
+	// +optional
+	Hash string `json:"hash,omitempty"`
+
+	// Attempts is the number of failed attempts since the step was last
+	// applied.
+	// +optional
+	Attempts int32 `json:"attempts,omitempty"`
+
+	// LastAttemptTime is when the step was last attempted.
+	// +optional
+	LastAttemptTime *metav1.Time `json:"lastAttemptTime,omitempty"`
+
+	// Message describes why the step failed.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// CertificatesObservation is the observed expiry of the cluster
+// certificates.
+type CertificatesObservation struct {
+	// ClientNotAfter is when the kubeconfig client certificate expires.
+	// +optional
+	ClientNotAfter *metav1.Time `json:"clientNotAfter,omitempty"`
+
+	// CANotAfter is when the cluster CA certificate expires. The CA is not
+	// renewed; the cluster must be recreated before it expires.
+	// +optional
+	CANotAfter *metav1.Time `json:"caNotAfter,omitempty"`
+}
+
+// LoadBalancerObservation is the observed state of a LoadBalancer Service.
+type LoadBalancerObservation struct {
+	// Namespace of the Service.
+	Namespace string `json:"namespace"`
+
+	// Name of the Service.
+	Name string `json:"name"`
+
+	// IP is the address assigned to the Service. Empty while the address
+	// pool is exhausted.
+	// +optional
+	IP string `json:"ip,omitempty"`
 }
 
 // NodeObservation is the observed state of a KIND cluster node.
@@ -196,6 +706,14 @@ type NodeObservation struct {
 	// IPv6Address is the IPv6 address of the node container.
 	// Only populated for IPv6 or dual-stack clusters.
 	IPv6Address string `json:"ipv6Address,omitempty"`
+
+	// Networks are the Docker networks the node container is attached to.
+	// +optional
+	Networks []string `json:"networks,omitempty"`
+
+	// Resources are the resource limits in effect on the node container.
+	// +optional
+	Resources *NodeResources `json:"resources,omitempty"`
 }
 
 // ClusterSpec defines the desired state of a Cluster.
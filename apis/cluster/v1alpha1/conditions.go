@@ -0,0 +1,109 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/v2/apis/common/v1"
+)
+
+// TypeCertificatesExpiring indicates whether the certificates of the cluster
+// are about to expire.
+const TypeCertificatesExpiring xpv1.ConditionType = "CertificatesExpiring"
+
+// Reasons a certificate is or is not about to expire.
+const (
+	ReasonCertificatesValid    xpv1.ConditionReason = "CertificatesValid"
+	ReasonCertificatesExpiring xpv1.ConditionReason = "CertificatesExpiring"
+)
+
+// CertificatesValid returns a condition that indicates no certificate of the
+// cluster is about to expire.
+func CertificatesValid() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeCertificatesExpiring,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonCertificatesValid,
+	}
+}
+
+// CertificatesExpiring returns a condition that indicates a certificate of
+// the cluster is about to expire.
+func CertificatesExpiring(msg string) xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeCertificatesExpiring,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonCertificatesExpiring,
+		Message:            msg,
+	}
+}
+
+// ReasonWaitingForCapacity indicates that the cluster is not created until
+// the Docker host has the capacity for it.
+const ReasonWaitingForCapacity xpv1.ConditionReason = "Waiting"
+
+// WaitingForCapacity returns a condition that indicates the cluster is not
+// created until the Docker host has the capacity for it.
+func WaitingForCapacity(msg string) xpv1.Condition {
+	return xpv1.Condition{
+		Type:               xpv1.TypeReady,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonWaitingForCapacity,
+		Message:            msg,
+	}
+}
+
+// ReasonQuotaExceeded indicates that the cluster is not created because it
+// would exceed the quotas of its ProviderConfig.
+const ReasonQuotaExceeded xpv1.ConditionReason = "QuotaExceeded"
+
+// QuotaExceeded returns a condition that indicates the cluster is not created
+// because it would exceed the quotas of its ProviderConfig.
+func QuotaExceeded(msg string) xpv1.Condition {
+	return xpv1.Condition{
+		Type:               xpv1.TypeReady,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonQuotaExceeded,
+		Message:            msg,
+	}
+}
+
+// ReasonPolicyViolation indicates that the cluster is not created because it
+// violates the policy of its ProviderConfig.
+const ReasonPolicyViolation xpv1.ConditionReason = "PolicyViolation"
+
+// PolicyViolation returns a condition that indicates the cluster is not
+// created because it violates the policy of its ProviderConfig.
+func PolicyViolation(msg string) xpv1.Condition {
+	return xpv1.Condition{
+		Type:               xpv1.TypeReady,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonPolicyViolation,
+		Message:            msg,
+	}
+}
+
+// ReasonNameConflict indicates that the cluster is not created or adopted
+// because its KIND cluster name belongs to another namespace.
+const ReasonNameConflict xpv1.ConditionReason = "NameConflict"
+
+// NameConflict returns a condition that indicates the cluster is not created
+// or adopted because its KIND cluster name belongs to another namespace.
+func NameConflict(msg string) xpv1.Condition {
+	return xpv1.Condition{
+		Type:               xpv1.TypeReady,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonNameConflict,
+		Message:            msg,
+	}
+}
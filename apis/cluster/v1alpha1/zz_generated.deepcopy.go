@@ -8,6 +8,192 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *APIServerService) DeepCopyInto(out *APIServerService) {
+	*out = *in
+	if in.Name != nil {
+		in, out := &in.Name, &out.Name
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new APIServerService.
+func (in *APIServerService) DeepCopy() *APIServerService {
+	if in == nil {
+		return nil
+	}
+	out := new(APIServerService)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArgoCD) DeepCopyInto(out *ArgoCD) {
+	*out = *in
+	if in.SecretName != nil {
+		in, out := &in.SecretName, &out.SecretName
+		*out = new(string)
+		**out = **in
+	}
+	if in.ClusterName != nil {
+		in, out := &in.ClusterName, &out.ClusterName
+		*out = new(string)
+		**out = **in
+	}
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCD.
+func (in *ArgoCD) DeepCopy() *ArgoCD {
+	if in == nil {
+		return nil
+	}
+	out := new(ArgoCD)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Bootstrap) DeepCopyInto(out *Bootstrap) {
+	*out = *in
+	if in.Steps != nil {
+		in, out := &in.Steps, &out.Steps
+		*out = make([]BootstrapStep, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Bootstrap.
+func (in *Bootstrap) DeepCopy() *Bootstrap {
+	if in == nil {
+		return nil
+	}
+	out := new(Bootstrap)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BootstrapStep) DeepCopyInto(out *BootstrapStep) {
+	*out = *in
+	if in.SecretRef != nil {
+		in, out := &in.SecretRef, &out.SecretRef
+		*out = new(ManifestSelector)
+		**out = **in
+	}
+	if in.ConfigMapRef != nil {
+		in, out := &in.ConfigMapRef, &out.ConfigMapRef
+		*out = new(ManifestSelector)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BootstrapStep.
+func (in *BootstrapStep) DeepCopy() *BootstrapStep {
+	if in == nil {
+		return nil
+	}
+	out := new(BootstrapStep)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BootstrapStepObservation) DeepCopyInto(out *BootstrapStepObservation) {
+	*out = *in
+	if in.LastAttemptTime != nil {
+		in, out := &in.LastAttemptTime, &out.LastAttemptTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BootstrapStepObservation.
+func (in *BootstrapStepObservation) DeepCopy() *BootstrapStepObservation {
+	if in == nil {
+		return nil
+	}
+	out := new(BootstrapStepObservation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CertificateRotation) DeepCopyInto(out *CertificateRotation) {
+	*out = *in
+	if in.RenewBefore != nil {
+		in, out := &in.RenewBefore, &out.RenewBefore
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertificateRotation.
+func (in *CertificateRotation) DeepCopy() *CertificateRotation {
+	if in == nil {
+		return nil
+	}
+	out := new(CertificateRotation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CertificateSource) DeepCopyInto(out *CertificateSource) {
+	*out = *in
+	if in.SecretRef != nil {
+		in, out := &in.SecretRef, &out.SecretRef
+		*out = new(KeySelector)
+		**out = **in
+	}
+	if in.ConfigMapRef != nil {
+		in, out := &in.ConfigMapRef, &out.ConfigMapRef
+		*out = new(KeySelector)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertificateSource.
+func (in *CertificateSource) DeepCopy() *CertificateSource {
+	if in == nil {
+		return nil
+	}
+	out := new(CertificateSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CertificatesObservation) DeepCopyInto(out *CertificatesObservation) {
+	*out = *in
+	if in.ClientNotAfter != nil {
+		in, out := &in.ClientNotAfter, &out.ClientNotAfter
+		*out = (*in).DeepCopy()
+	}
+	if in.CANotAfter != nil {
+		in, out := &in.CANotAfter, &out.CANotAfter
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertificatesObservation.
+func (in *CertificatesObservation) DeepCopy() *CertificatesObservation {
+	if in == nil {
+		return nil
+	}
+	out := new(CertificatesObservation)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Cluster) DeepCopyInto(out *Cluster) {
 	*out = *in
@@ -73,13 +259,32 @@ func (in *ClusterObservation) DeepCopyInto(out *ClusterObservation) {
 	if in.Nodes != nil {
 		in, out := &in.Nodes, &out.Nodes
 		*out = make([]NodeObservation, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 	if in.APIServerEndpoint != nil {
 		in, out := &in.APIServerEndpoint, &out.APIServerEndpoint
 		*out = new(string)
 		**out = **in
 	}
+	if in.LoadBalancers != nil {
+		in, out := &in.LoadBalancers, &out.LoadBalancers
+		*out = make([]LoadBalancerObservation, len(*in))
+		copy(*out, *in)
+	}
+	if in.Certificates != nil {
+		in, out := &in.Certificates, &out.Certificates
+		*out = new(CertificatesObservation)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Bootstrap != nil {
+		in, out := &in.Bootstrap, &out.Bootstrap
+		*out = make([]BootstrapStepObservation, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterObservation.
@@ -136,6 +341,82 @@ func (in *ClusterParameters) DeepCopyInto(out *ClusterParameters) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.TrustedCAs != nil {
+		in, out := &in.TrustedCAs, &out.TrustedCAs
+		*out = make([]CertificateSource, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Registries != nil {
+		in, out := &in.Registries, &out.Registries
+		*out = make([]RegistryConfig, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LocalRegistryRef != nil {
+		in, out := &in.LocalRegistryRef, &out.LocalRegistryRef
+		*out = new(LocalRegistryReference)
+		**out = **in
+	}
+	if in.NetworkRef != nil {
+		in, out := &in.NetworkRef, &out.NetworkRef
+		*out = new(NetworkReference)
+		**out = **in
+	}
+	if in.DockerNetwork != nil {
+		in, out := &in.DockerNetwork, &out.DockerNetwork
+		*out = new(string)
+		**out = **in
+	}
+	if in.LoadBalancer != nil {
+		in, out := &in.LoadBalancer, &out.LoadBalancer
+		*out = new(LoadBalancer)
+		**out = **in
+	}
+	if in.APIServerService != nil {
+		in, out := &in.APIServerService, &out.APIServerService
+		*out = new(APIServerService)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PrimaryKubeconfig != nil {
+		in, out := &in.PrimaryKubeconfig, &out.PrimaryKubeconfig
+		*out = new(string)
+		**out = **in
+	}
+	if in.ServiceAccountToken != nil {
+		in, out := &in.ServiceAccountToken, &out.ServiceAccountToken
+		*out = new(ServiceAccountToken)
+		**out = **in
+	}
+	if in.CertificateRotation != nil {
+		in, out := &in.CertificateRotation, &out.CertificateRotation
+		*out = new(CertificateRotation)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ArgoCD != nil {
+		in, out := &in.ArgoCD, &out.ArgoCD
+		*out = new(ArgoCD)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PublishProviderConfigs != nil {
+		in, out := &in.PublishProviderConfigs, &out.PublishProviderConfigs
+		*out = new(PublishProviderConfigs)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.KubeconfigSecretFormats != nil {
+		in, out := &in.KubeconfigSecretFormats, &out.KubeconfigSecretFormats
+		*out = make([]KubeconfigSecretFormat, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Bootstrap != nil {
+		in, out := &in.Bootstrap, &out.Bootstrap
+		*out = new(Bootstrap)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterParameters.
@@ -182,9 +463,114 @@ func (in *ClusterStatus) DeepCopy() *ClusterStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KeySelector) DeepCopyInto(out *KeySelector) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeySelector.
+func (in *KeySelector) DeepCopy() *KeySelector {
+	if in == nil {
+		return nil
+	}
+	out := new(KeySelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubeconfigSecretFormat) DeepCopyInto(out *KubeconfigSecretFormat) {
+	*out = *in
+	if in.Name != nil {
+		in, out := &in.Name, &out.Name
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubeconfigSecretFormat.
+func (in *KubeconfigSecretFormat) DeepCopy() *KubeconfigSecretFormat {
+	if in == nil {
+		return nil
+	}
+	out := new(KubeconfigSecretFormat)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LoadBalancer) DeepCopyInto(out *LoadBalancer) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LoadBalancer.
+func (in *LoadBalancer) DeepCopy() *LoadBalancer {
+	if in == nil {
+		return nil
+	}
+	out := new(LoadBalancer)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LoadBalancerObservation) DeepCopyInto(out *LoadBalancerObservation) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LoadBalancerObservation.
+func (in *LoadBalancerObservation) DeepCopy() *LoadBalancerObservation {
+	if in == nil {
+		return nil
+	}
+	out := new(LoadBalancerObservation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LocalRegistryReference) DeepCopyInto(out *LocalRegistryReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LocalRegistryReference.
+func (in *LocalRegistryReference) DeepCopy() *LocalRegistryReference {
+	if in == nil {
+		return nil
+	}
+	out := new(LocalRegistryReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ManifestSelector) DeepCopyInto(out *ManifestSelector) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ManifestSelector.
+func (in *ManifestSelector) DeepCopy() *ManifestSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(ManifestSelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Mount) DeepCopyInto(out *Mount) {
 	*out = *in
+	if in.ConfigMapRef != nil {
+		in, out := &in.ConfigMapRef, &out.ConfigMapRef
+		*out = new(MountSource)
+		**out = **in
+	}
+	if in.SecretRef != nil {
+		in, out := &in.SecretRef, &out.SecretRef
+		*out = new(MountSource)
+		**out = **in
+	}
 	if in.Readonly != nil {
 		in, out := &in.Readonly, &out.Readonly
 		*out = new(bool)
@@ -212,6 +598,36 @@ func (in *Mount) DeepCopy() *Mount {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MountSource) DeepCopyInto(out *MountSource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MountSource.
+func (in *MountSource) DeepCopy() *MountSource {
+	if in == nil {
+		return nil
+	}
+	out := new(MountSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NetworkReference) DeepCopyInto(out *NetworkReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkReference.
+func (in *NetworkReference) DeepCopy() *NetworkReference {
+	if in == nil {
+		return nil
+	}
+	out := new(NetworkReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Networking) DeepCopyInto(out *Networking) {
 	*out = *in
@@ -296,6 +712,11 @@ func (in *Node) DeepCopyInto(out *Node) {
 			(*out)[key] = val
 		}
 	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(NodeResources)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Node.
@@ -311,6 +732,16 @@ func (in *Node) DeepCopy() *Node {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NodeObservation) DeepCopyInto(out *NodeObservation) {
 	*out = *in
+	if in.Networks != nil {
+		in, out := &in.Networks, &out.Networks
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(NodeResources)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeObservation.
@@ -323,6 +754,36 @@ func (in *NodeObservation) DeepCopy() *NodeObservation {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeResources) DeepCopyInto(out *NodeResources) {
+	*out = *in
+	if in.CPUs != nil {
+		in, out := &in.CPUs, &out.CPUs
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Memory != nil {
+		in, out := &in.Memory, &out.Memory
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.PidsLimit != nil {
+		in, out := &in.PidsLimit, &out.PidsLimit
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeResources.
+func (in *NodeResources) DeepCopy() *NodeResources {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeResources)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PortMapping) DeepCopyInto(out *PortMapping) {
 	*out = *in
@@ -347,3 +808,162 @@ func (in *PortMapping) DeepCopy() *PortMapping {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PublishProviderConfigs) DeepCopyInto(out *PublishProviderConfigs) {
+	*out = *in
+	if in.Name != nil {
+		in, out := &in.Name, &out.Name
+		*out = new(string)
+		**out = **in
+	}
+	if in.Kubernetes != nil {
+		in, out := &in.Kubernetes, &out.Kubernetes
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Helm != nil {
+		in, out := &in.Helm, &out.Helm
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PublishProviderConfigs.
+func (in *PublishProviderConfigs) DeepCopy() *PublishProviderConfigs {
+	if in == nil {
+		return nil
+	}
+	out := new(PublishProviderConfigs)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegistryConfig) DeepCopyInto(out *RegistryConfig) {
+	*out = *in
+	if in.Server != nil {
+		in, out := &in.Server, &out.Server
+		*out = new(string)
+		**out = **in
+	}
+	if in.Mirrors != nil {
+		in, out := &in.Mirrors, &out.Mirrors
+		*out = make([]RegistryMirror, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.TLS != nil {
+		in, out := &in.TLS, &out.TLS
+		*out = new(RegistryTLS)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AuthSecretRef != nil {
+		in, out := &in.AuthSecretRef, &out.AuthSecretRef
+		*out = new(SecretReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistryConfig.
+func (in *RegistryConfig) DeepCopy() *RegistryConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(RegistryConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegistryMirror) DeepCopyInto(out *RegistryMirror) {
+	*out = *in
+	if in.Capabilities != nil {
+		in, out := &in.Capabilities, &out.Capabilities
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.OverridePath != nil {
+		in, out := &in.OverridePath, &out.OverridePath
+		*out = new(bool)
+		**out = **in
+	}
+	if in.TLS != nil {
+		in, out := &in.TLS, &out.TLS
+		*out = new(RegistryTLS)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistryMirror.
+func (in *RegistryMirror) DeepCopy() *RegistryMirror {
+	if in == nil {
+		return nil
+	}
+	out := new(RegistryMirror)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegistryTLS) DeepCopyInto(out *RegistryTLS) {
+	*out = *in
+	if in.InsecureSkipVerify != nil {
+		in, out := &in.InsecureSkipVerify, &out.InsecureSkipVerify
+		*out = new(bool)
+		**out = **in
+	}
+	if in.CAs != nil {
+		in, out := &in.CAs, &out.CAs
+		*out = make([]CertificateSource, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ClientCertSecretRef != nil {
+		in, out := &in.ClientCertSecretRef, &out.ClientCertSecretRef
+		*out = new(SecretReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistryTLS.
+func (in *RegistryTLS) DeepCopy() *RegistryTLS {
+	if in == nil {
+		return nil
+	}
+	out := new(RegistryTLS)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretReference) DeepCopyInto(out *SecretReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretReference.
+func (in *SecretReference) DeepCopy() *SecretReference {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServiceAccountToken) DeepCopyInto(out *ServiceAccountToken) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceAccountToken.
+func (in *ServiceAccountToken) DeepCopy() *ServiceAccountToken {
+	if in == nil {
+		return nil
+	}
+	out := new(ServiceAccountToken)
+	in.DeepCopyInto(out)
+	return out
+}
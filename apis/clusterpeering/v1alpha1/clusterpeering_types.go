@@ -0,0 +1,97 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/v2/apis/common/v1"
+)
+
+// ClusterPeeringParameters defines the clusters to route between.
+type ClusterPeeringParameters struct {
+	// ClusterRefs reference the Clusters to peer. Their pod and service
+	// subnets must not overlap, and their nodes must share a Docker
+	// network.
+	// +kubebuilder:validation:MinItems=2
+	// +listType=map
+	// +listMapKey=name
+	ClusterRefs []ClusterReference `json:"clusterRefs"`
+}
+
+// ClusterReference references a cluster-scoped Cluster.
+type ClusterReference struct {
+	// Name of the Cluster.
+	Name string `json:"name"`
+}
+
+// LinkObservation is the observed reachability from one cluster to another.
+type LinkObservation struct {
+	// From is the name of the Cluster the check ran in.
+	From string `json:"from"`
+
+	// To is the name of the Cluster whose kube-dns Service was dialed.
+	To string `json:"to"`
+
+	// Reachable is true if a node of From reached the kube-dns Service of
+	// To through the installed routes.
+	Reachable bool `json:"reachable"`
+
+	// Message describes why the link is not reachable.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// ClusterPeeringObservation is the observable state of a cluster peering.
+type ClusterPeeringObservation struct {
+	// Network is the Docker network the routes go through.
+	// +optional
+	Network string `json:"network,omitempty"`
+
+	// Links report the reachability of every ordered pair of clusters.
+	// +optional
+	Links []LinkObservation `json:"links,omitempty"`
+}
+
+// ClusterPeeringSpec defines the desired state of a ClusterPeering.
+type ClusterPeeringSpec struct {
+	xpv1.ResourceSpec `json:",inline"`
+	ForProvider       ClusterPeeringParameters `json:"forProvider"`
+}
+
+// ClusterPeeringStatus defines the observed state of a ClusterPeering.
+type ClusterPeeringStatus struct {
+	xpv1.ResourceStatus `json:",inline"`
+	AtProvider          ClusterPeeringObservation `json:"atProvider,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster,categories={crossplane,managed,kind}
+// +kubebuilder:printcolumn:name="READY",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status"
+// +kubebuilder:printcolumn:name="SYNCED",type="string",JSONPath=".status.conditions[?(@.type=='Synced')].status"
+// +kubebuilder:printcolumn:name="NETWORK",type="string",JSONPath=".status.atProvider.network"
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+
+// ClusterPeering installs static routes in the nodes of two or more KIND
+// clusters so that each can reach the pod and service subnets of the others,
+// as needed by multi-cluster networking such as Cilium ClusterMesh or
+// Submariner.
+type ClusterPeering struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ClusterPeeringSpec   `json:"spec"`
+	Status ClusterPeeringStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ClusterPeeringList contains a list of ClusterPeering.
+type ClusterPeeringList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterPeering `json:"items"`
+}
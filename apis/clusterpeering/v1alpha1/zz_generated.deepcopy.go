@@ -0,0 +1,172 @@
+//go:build !ignore_autogenerated
+
+// Code generated by controller-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	runtime "k8s.io/apimachinery/pkg/runtime"
+)
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterPeering) DeepCopyInto(out *ClusterPeering) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterPeering.
+func (in *ClusterPeering) DeepCopy() *ClusterPeering {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterPeering)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterPeering) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterPeeringList) DeepCopyInto(out *ClusterPeeringList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ClusterPeering, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterPeeringList.
+func (in *ClusterPeeringList) DeepCopy() *ClusterPeeringList {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterPeeringList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterPeeringList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterPeeringObservation) DeepCopyInto(out *ClusterPeeringObservation) {
+	*out = *in
+	if in.Links != nil {
+		in, out := &in.Links, &out.Links
+		*out = make([]LinkObservation, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterPeeringObservation.
+func (in *ClusterPeeringObservation) DeepCopy() *ClusterPeeringObservation {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterPeeringObservation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterPeeringParameters) DeepCopyInto(out *ClusterPeeringParameters) {
+	*out = *in
+	if in.ClusterRefs != nil {
+		in, out := &in.ClusterRefs, &out.ClusterRefs
+		*out = make([]ClusterReference, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterPeeringParameters.
+func (in *ClusterPeeringParameters) DeepCopy() *ClusterPeeringParameters {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterPeeringParameters)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterPeeringSpec) DeepCopyInto(out *ClusterPeeringSpec) {
+	*out = *in
+	in.ResourceSpec.DeepCopyInto(&out.ResourceSpec)
+	in.ForProvider.DeepCopyInto(&out.ForProvider)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterPeeringSpec.
+func (in *ClusterPeeringSpec) DeepCopy() *ClusterPeeringSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterPeeringSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterPeeringStatus) DeepCopyInto(out *ClusterPeeringStatus) {
+	*out = *in
+	in.ResourceStatus.DeepCopyInto(&out.ResourceStatus)
+	in.AtProvider.DeepCopyInto(&out.AtProvider)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterPeeringStatus.
+func (in *ClusterPeeringStatus) DeepCopy() *ClusterPeeringStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterPeeringStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterReference) DeepCopyInto(out *ClusterReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterReference.
+func (in *ClusterReference) DeepCopy() *ClusterReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LinkObservation) DeepCopyInto(out *LinkObservation) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LinkObservation.
+func (in *LinkObservation) DeepCopy() *LinkObservation {
+	if in == nil {
+		return nil
+	}
+	out := new(LinkObservation)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/v2/apis/common/v1"
+)
+
+// LoadedImageParameters defines the images to load into a KIND cluster.
+type LoadedImageParameters struct {
+	// ClusterRef references the cluster-scoped Cluster to load the images
+	// into. A LoadedImage is cluster-scoped, so it cannot reference a
+	// namespaced Cluster, whose tenant it would otherwise reach into.
+	ClusterRef ClusterReference `json:"clusterRef"`
+
+	// Images are references of images in the host Docker daemon, for
+	// example "my-app:dev". They are loaded like kind load docker-image.
+	// +optional
+	Images []string `json:"images,omitempty"`
+
+	// Archive is the path of an image tar archive readable by the provider,
+	// as produced by docker save. It is loaded like kind load image-archive.
+	// +optional
+	Archive *string `json:"archive,omitempty"`
+
+	// NodeSelector limits the nodes the images are loaded into. Images are
+	// loaded into every node if omitted.
+	// +optional
+	NodeSelector *NodeSelector `json:"nodeSelector,omitempty"`
+}
+
+// ClusterReference references a cluster-scoped Cluster.
+type ClusterReference struct {
+	// Name of the Cluster.
+	Name string `json:"name"`
+}
+
+// NodeSelector selects KIND nodes. A node is selected if it matches any of
+// the roles or names.
+type NodeSelector struct {
+	// Roles selects nodes by role.
+	// +optional
+	// +kubebuilder:validation:items:Enum=control-plane;worker
+	Roles []string `json:"roles,omitempty"`
+
+	// Names selects nodes by container name, for example "dev-worker2".
+	// +optional
+	Names []string `json:"names,omitempty"`
+}
+
+// ImageObservation is the observed state of a loaded image.
+type ImageObservation struct {
+	// Ref is the image reference as recorded in the loaded archive.
+	Ref string `json:"ref"`
+
+	// ID is the image ID containerd reports for the image.
+	ID string `json:"id"`
+
+	// Source is the entry of spec.forProvider.images or the archive path
+	// the image was loaded from.
+	Source string `json:"source"`
+
+	// SourceID is the host Docker daemon image ID of Source at the time it
+	// was loaded. It is empty for archives.
+	// +optional
+	SourceID string `json:"sourceID,omitempty"`
+}
+
+// LoadedImageObservation is the observable state of loaded images.
+type LoadedImageObservation struct {
+	// ClusterName is the name of the KIND cluster.
+	// +optional
+	ClusterName string `json:"clusterName,omitempty"`
+
+	// Images are the loaded images.
+	// +optional
+	Images []ImageObservation `json:"images,omitempty"`
+
+	// Nodes are the selected nodes that have every image.
+	// +optional
+	Nodes []string `json:"nodes,omitempty"`
+}
+
+// LoadedImageSpec defines the desired state of a LoadedImage.
+type LoadedImageSpec struct {
+	xpv1.ResourceSpec `json:",inline"`
+	ForProvider       LoadedImageParameters `json:"forProvider"`
+}
+
+// LoadedImageStatus defines the observed state of a LoadedImage.
+type LoadedImageStatus struct {
+	xpv1.ResourceStatus `json:",inline"`
+	AtProvider          LoadedImageObservation `json:"atProvider,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster,categories={crossplane,managed,kind}
+// +kubebuilder:printcolumn:name="READY",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status"
+// +kubebuilder:printcolumn:name="SYNCED",type="string",JSONPath=".status.conditions[?(@.type=='Synced')].status"
+// +kubebuilder:printcolumn:name="CLUSTER",type="string",JSONPath=".spec.forProvider.clusterRef.name"
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+
+// LoadedImage loads images from the host Docker daemon or an image archive
+// into the nodes of a KIND cluster, and reloads them into nodes that lack
+// them, for example after a node was recreated.
+type LoadedImage struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   LoadedImageSpec   `json:"spec"`
+	Status LoadedImageStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// LoadedImageList contains a list of LoadedImage.
+type LoadedImageList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []LoadedImage `json:"items"`
+}
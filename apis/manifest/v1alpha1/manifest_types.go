@@ -0,0 +1,102 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/v2/apis/common/v1"
+)
+
+// ManifestParameters defines the objects to keep in sync in a KIND cluster.
+type ManifestParameters struct {
+	// ClusterRef references the cluster-scoped Cluster to apply the objects
+	// to. A Manifest is cluster-scoped, so it cannot reference a namespaced
+	// Cluster, whose tenant it would otherwise reach into.
+	ClusterRef ClusterReference `json:"clusterRef"`
+
+	// Objects are server-side applied to the cluster. Namespaced objects
+	// without a namespace are applied to the default namespace. Objects
+	// removed from the list are deleted from the cluster.
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:EmbeddedResource
+	Objects []runtime.RawExtension `json:"objects"`
+}
+
+// ClusterReference references a cluster-scoped Cluster.
+type ClusterReference struct {
+	// Name of the Cluster.
+	Name string `json:"name"`
+}
+
+// ObjectReference identifies an object in a KIND cluster.
+type ObjectReference struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// ManifestObservation is the observable state of the objects.
+type ManifestObservation struct {
+	// ClusterName is the name of the KIND cluster.
+	// +optional
+	ClusterName string `json:"clusterName,omitempty"`
+
+	// Objects are the objects applied to the cluster. They are deleted when
+	// they are removed from spec.forProvider.objects, or the Manifest is
+	// deleted.
+	// +optional
+	Objects []ObjectReference `json:"objects,omitempty"`
+
+	// Drifted reports whether the objects in the cluster differ from the
+	// ones last applied.
+	// +optional
+	Drifted bool `json:"drifted,omitempty"`
+}
+
+// ManifestSpec defines the desired state of a Manifest.
+type ManifestSpec struct {
+	xpv1.ResourceSpec `json:",inline"`
+	ForProvider       ManifestParameters `json:"forProvider"`
+}
+
+// ManifestStatus defines the observed state of a Manifest.
+type ManifestStatus struct {
+	xpv1.ResourceStatus `json:",inline"`
+	AtProvider          ManifestObservation `json:"atProvider,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster,categories={crossplane,managed,kind}
+// +kubebuilder:printcolumn:name="READY",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status"
+// +kubebuilder:printcolumn:name="SYNCED",type="string",JSONPath=".status.conditions[?(@.type=='Synced')].status"
+// +kubebuilder:printcolumn:name="CLUSTER",type="string",JSONPath=".spec.forProvider.clusterRef.name"
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+
+// Manifest server-side applies objects to a KIND cluster with the cluster's
+// admin kubeconfig, reapplies them when they drift, and deletes them when
+// the Manifest is deleted.
+type Manifest struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ManifestSpec   `json:"spec"`
+	Status ManifestStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ManifestList contains a list of Manifest.
+type ManifestList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Manifest `json:"items"`
+}
@@ -35,6 +35,56 @@ type NamespacedClusterSpec struct {
 	WriteConnectionSecretToReference *xpv1.LocalSecretReference `json:"writeConnectionSecretToRef,omitempty"`
 
 	ForProvider clusterv1alpha1.ClusterParameters `json:"forProvider"`
+
+	// AccessGrants hand out least-privilege access to the cluster. For each
+	// grant the provider creates a ServiceAccount in the KIND cluster, binds
+	// it to a role, and writes a kubeconfig using its token to a Secret in
+	// the same namespace as this managed resource.
+	// +optional
+	// +listType=map
+	// +listMapKey=secretName
+	AccessGrants []AccessGrant `json:"accessGrants,omitempty"`
+}
+
+// AccessGrant binds a ServiceAccount in the KIND cluster to a role and
+// publishes a kubeconfig for it.
+type AccessGrant struct {
+	// SecretName is the Secret, in the same namespace as this managed
+	// resource, that the kubeconfig is written to under the kubeconfig key.
+	// The ServiceAccount token is written under the token key. It labels
+	// the objects created for the grant, so it is limited to 63 characters.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=63
+	SecretName string `json:"secretName"`
+
+	// Namespace in the KIND cluster that the ServiceAccount is created in,
+	// and that the role is bound in. It is created if it does not exist.
+	// +kubebuilder:validation:MinLength=1
+	Namespace string `json:"namespace"`
+
+	// ServiceAccountName is the name of the ServiceAccount. Defaults to
+	// SecretName.
+	// +optional
+	ServiceAccountName *string `json:"serviceAccountName,omitempty"`
+
+	// RoleRef is the Role or ClusterRole granted to the ServiceAccount.
+	RoleRef AccessGrantRoleRef `json:"roleRef"`
+
+	// ClusterWide binds a ClusterRole with a ClusterRoleBinding instead of
+	// a RoleBinding in Namespace.
+	// +optional
+	ClusterWide bool `json:"clusterWide,omitempty"`
+}
+
+// AccessGrantRoleRef references a Role or ClusterRole in the KIND cluster.
+type AccessGrantRoleRef struct {
+	// Kind of the role.
+	// +kubebuilder:validation:Enum=Role;ClusterRole
+	Kind string `json:"kind"`
+
+	// Name of the role. A Role must exist in the grant's Namespace.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
 }
 
 // NamespacedClusterStatus defines the observed state of a namespaced Cluster.
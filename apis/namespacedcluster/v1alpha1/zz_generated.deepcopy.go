@@ -10,6 +10,42 @@ import (
 	xpv1 "github.com/crossplane/crossplane-runtime/v2/apis/common/v1"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AccessGrant) DeepCopyInto(out *AccessGrant) {
+	*out = *in
+	if in.ServiceAccountName != nil {
+		in, out := &in.ServiceAccountName, &out.ServiceAccountName
+		*out = new(string)
+		**out = **in
+	}
+	out.RoleRef = in.RoleRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccessGrant.
+func (in *AccessGrant) DeepCopy() *AccessGrant {
+	if in == nil {
+		return nil
+	}
+	out := new(AccessGrant)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AccessGrantRoleRef) DeepCopyInto(out *AccessGrantRoleRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccessGrantRoleRef.
+func (in *AccessGrantRoleRef) DeepCopy() *AccessGrantRoleRef {
+	if in == nil {
+		return nil
+	}
+	out := new(AccessGrantRoleRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Cluster) DeepCopyInto(out *Cluster) {
 	*out = *in
@@ -88,6 +124,13 @@ func (in *NamespacedClusterSpec) DeepCopyInto(out *NamespacedClusterSpec) {
 		**out = **in
 	}
 	in.ForProvider.DeepCopyInto(&out.ForProvider)
+	if in.AccessGrants != nil {
+		in, out := &in.AccessGrants, &out.AccessGrants
+		*out = make([]AccessGrant, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespacedClusterSpec.
@@ -0,0 +1,43 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+// Package v1alpha1 contains managed resources for Docker networks that KIND
+// cluster nodes are attached to.
+// +kubebuilder:object:generate=true
+// +groupName=kind.crossplane.io
+// +versionName=v1alpha1
+package v1alpha1
+
+import (
+	"reflect"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/scheme"
+)
+
+// Package type metadata.
+const (
+	Group   = "kind.crossplane.io"
+	Version = "v1alpha1"
+)
+
+var (
+	// SchemeGroupVersion is the group version used to register these objects.
+	SchemeGroupVersion = schema.GroupVersion{Group: Group, Version: Version}
+
+	// SchemeBuilder is used to add Go types to the GroupVersionKind scheme.
+	SchemeBuilder = &scheme.Builder{GroupVersion: SchemeGroupVersion}
+)
+
+// Network type metadata.
+var (
+	NetworkKind             = reflect.TypeOf(Network{}).Name()
+	NetworkGroupKind        = schema.GroupKind{Group: Group, Kind: NetworkKind}.String()
+	NetworkKindAPIVersion   = NetworkKind + "." + SchemeGroupVersion.String()
+	NetworkGroupVersionKind = SchemeGroupVersion.WithKind(NetworkKind)
+)
+
+func init() {
+	SchemeBuilder.Register(&Network{}, &NetworkList{})
+}
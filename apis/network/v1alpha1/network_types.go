@@ -0,0 +1,103 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/v2/apis/common/v1"
+)
+
+// NetworkParameters defines the desired state of a Docker network. Docker
+// networks cannot be changed in place, so a changed network is recreated
+// once no containers are attached to it.
+type NetworkParameters struct {
+	// Subnet is the IPv4 subnet of the network in CIDR notation. Docker
+	// picks one if omitted.
+	// +optional
+	Subnet *string `json:"subnet,omitempty"`
+
+	// Gateway is the IPv4 gateway of the subnet.
+	// +optional
+	Gateway *string `json:"gateway,omitempty"`
+
+	// IPv6 enables IPv6 on the network. Required for ipv6 and dual stack
+	// clusters.
+	// +optional
+	IPv6 *bool `json:"ipv6,omitempty"`
+
+	// IPv6Subnet is the IPv6 subnet of the network in CIDR notation.
+	// +optional
+	IPv6Subnet *string `json:"ipv6Subnet,omitempty"`
+
+	// MTU of the network. Defaults to the Docker daemon default.
+	// +optional
+	// +kubebuilder:validation:Minimum=68
+	MTU *int32 `json:"mtu,omitempty"`
+
+	// Labels are set on the Docker network.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// NetworkObservation is the observable state of a Docker network.
+type NetworkObservation struct {
+	// ID is the Docker network ID.
+	// +optional
+	ID string `json:"id,omitempty"`
+
+	// Name is the Docker network name. Clusters select the network by
+	// this name.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Subnets are the subnets of the network.
+	// +optional
+	Subnets []string `json:"subnets,omitempty"`
+
+	// Containers is the number of containers attached to the network.
+	// +optional
+	Containers int `json:"containers,omitempty"`
+}
+
+// NetworkSpec defines the desired state of a Network.
+type NetworkSpec struct {
+	xpv1.ResourceSpec `json:",inline"`
+	ForProvider       NetworkParameters `json:"forProvider"`
+}
+
+// NetworkStatus defines the observed state of a Network.
+type NetworkStatus struct {
+	xpv1.ResourceStatus `json:",inline"`
+	AtProvider          NetworkObservation `json:"atProvider,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster,categories={crossplane,managed,kind}
+// +kubebuilder:printcolumn:name="READY",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status"
+// +kubebuilder:printcolumn:name="SYNCED",type="string",JSONPath=".status.conditions[?(@.type=='Synced')].status"
+// +kubebuilder:printcolumn:name="CONTAINERS",type="integer",JSONPath=".status.atProvider.containers"
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+
+// Network is a Docker bridge network that KIND cluster nodes can be created
+// on. Clusters reference it with spec.forProvider.networkRef; clusters on
+// different networks are isolated from each other.
+type Network struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   NetworkSpec   `json:"spec"`
+	Status NetworkStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// NetworkList contains a list of Network.
+type NetworkList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Network `json:"items"`
+}
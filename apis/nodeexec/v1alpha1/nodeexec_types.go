@@ -0,0 +1,127 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/v2/apis/common/v1"
+)
+
+// NodeExecParameters defines a script to run inside the nodes of a KIND
+// cluster.
+type NodeExecParameters struct {
+	// ClusterRef references the cluster-scoped Cluster whose nodes run the
+	// script. A NodeExec is cluster-scoped, so it cannot reference a
+	// namespaced Cluster, whose tenant it would otherwise reach into.
+	ClusterRef ClusterReference `json:"clusterRef"`
+
+	// NodeSelector limits the nodes the script runs on. It runs on every
+	// node if omitted.
+	// +optional
+	NodeSelector *NodeSelector `json:"nodeSelector,omitempty"`
+
+	// Script is run with bash as root inside each node. It runs once per
+	// node, again on nodes that are added or recreated, and again on every
+	// node when it changes. A script that exits non-zero is retried.
+	// +kubebuilder:validation:MinLength=1
+	Script string `json:"script"`
+}
+
+// ClusterReference references a cluster-scoped Cluster.
+type ClusterReference struct {
+	// Name of the Cluster.
+	Name string `json:"name"`
+}
+
+// NodeSelector selects KIND nodes. A node is selected if it matches any of
+// the roles or names.
+type NodeSelector struct {
+	// Roles selects nodes by role.
+	// +optional
+	// +kubebuilder:validation:items:Enum=control-plane;worker
+	Roles []string `json:"roles,omitempty"`
+
+	// Names selects nodes by container name, for example "dev-worker2".
+	// +optional
+	Names []string `json:"names,omitempty"`
+}
+
+// NodeExecResult is the outcome of the last run of the script on a node.
+type NodeExecResult struct {
+	// Node is the container name of the node.
+	Node string `json:"node"`
+
+	// ExitCode of the script.
+	ExitCode int32 `json:"exitCode"`
+
+	// Stdout is the end of what the script wrote to stdout.
+	// +optional
+	Stdout string `json:"stdout,omitempty"`
+
+	// Stderr is the end of what the script wrote to stderr.
+	// +optional
+	Stderr string `json:"stderr,omitempty"`
+
+	// RunTime is when the script ran.
+	// +optional
+	RunTime *metav1.Time `json:"runTime,omitempty"`
+}
+
+// NodeExecObservation is the observable state of the script.
+type NodeExecObservation struct {
+	// ClusterName is the name of the KIND cluster.
+	// +optional
+	ClusterName string `json:"clusterName,omitempty"`
+
+	// Results are the outcomes of the last run on each node.
+	// +optional
+	Results []NodeExecResult `json:"results,omitempty"`
+
+	// Nodes are the selected nodes that ran the current script
+	// successfully.
+	// +optional
+	Nodes []string `json:"nodes,omitempty"`
+}
+
+// NodeExecSpec defines the desired state of a NodeExec.
+type NodeExecSpec struct {
+	xpv1.ResourceSpec `json:",inline"`
+	ForProvider       NodeExecParameters `json:"forProvider"`
+}
+
+// NodeExecStatus defines the observed state of a NodeExec.
+type NodeExecStatus struct {
+	xpv1.ResourceStatus `json:",inline"`
+	AtProvider          NodeExecObservation `json:"atProvider,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster,categories={crossplane,managed,kind}
+// +kubebuilder:printcolumn:name="READY",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status"
+// +kubebuilder:printcolumn:name="SYNCED",type="string",JSONPath=".status.conditions[?(@.type=='Synced')].status"
+// +kubebuilder:printcolumn:name="CLUSTER",type="string",JSONPath=".spec.forProvider.clusterRef.name"
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+
+// NodeExec runs a script inside the nodes of a KIND cluster, for node-level
+// setup such as sysctls, kernel modules, or /etc/hosts entries. It runs the
+// script on nodes that are added or recreated later as well.
+type NodeExec struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   NodeExecSpec   `json:"spec"`
+	Status NodeExecStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// NodeExecList contains a list of NodeExec.
+type NodeExecList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []NodeExec `json:"items"`
+}
@@ -0,0 +1,43 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+// Package v1alpha1 contains managed resources for caching KIND node images on
+// the Docker host.
+// +kubebuilder:object:generate=true
+// +groupName=kind.crossplane.io
+// +versionName=v1alpha1
+package v1alpha1
+
+import (
+	"reflect"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/scheme"
+)
+
+// Package type metadata.
+const (
+	Group   = "kind.crossplane.io"
+	Version = "v1alpha1"
+)
+
+var (
+	// SchemeGroupVersion is the group version used to register these objects.
+	SchemeGroupVersion = schema.GroupVersion{Group: Group, Version: Version}
+
+	// SchemeBuilder is used to add Go types to the GroupVersionKind scheme.
+	SchemeBuilder = &scheme.Builder{GroupVersion: SchemeGroupVersion}
+)
+
+// NodeImageCache type metadata.
+var (
+	NodeImageCacheKind             = reflect.TypeOf(NodeImageCache{}).Name()
+	NodeImageCacheGroupKind        = schema.GroupKind{Group: Group, Kind: NodeImageCacheKind}.String()
+	NodeImageCacheKindAPIVersion   = NodeImageCacheKind + "." + SchemeGroupVersion.String()
+	NodeImageCacheGroupVersionKind = SchemeGroupVersion.WithKind(NodeImageCacheKind)
+)
+
+func init() {
+	SchemeBuilder.Register(&NodeImageCache{}, &NodeImageCacheList{})
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/v2/apis/common/v1"
+)
+
+// Image pull states.
+const (
+	ImagePending      = "Pending"
+	ImagePulling      = "Pulling"
+	ImagePulled       = "Pulled"
+	ImageFailed       = "Failed"
+	ImageUnreferenced = "Unreferenced"
+)
+
+// NodeImageCacheParameters defines the node images to keep on the Docker
+// host.
+type NodeImageCacheParameters struct {
+	// Images are node images to pull before any Cluster needs them, for
+	// example "kindest/node:v1.31.0".
+	// +kubebuilder:validation:MinItems=1
+	Images []string `json:"images"`
+
+	// GarbageCollect removes images that were dropped from images, or that
+	// were cached when the NodeImageCache is deleted, unless a Cluster
+	// still references them.
+	// +optional
+	// +kubebuilder:default=false
+	GarbageCollect *bool `json:"garbageCollect,omitempty"`
+}
+
+// CachedImageObservation is the observed state of a cached node image.
+type CachedImageObservation struct {
+	// Image is the image reference.
+	Image string `json:"image"`
+
+	// State is one of Pending, Pulling, Pulled, Failed, or Unreferenced.
+	// Unreferenced images were removed from the spec but are still
+	// present, because garbage collection is disabled or a Cluster uses
+	// them.
+	State string `json:"state"`
+
+	// Progress reports pull progress, for example "5/9 layers".
+	// +optional
+	Progress string `json:"progress,omitempty"`
+
+	// Message describes why a pull failed.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// ID is the Docker image ID.
+	// +optional
+	ID string `json:"id,omitempty"`
+
+	// SizeBytes is the size of the image on the Docker host.
+	// +optional
+	SizeBytes int64 `json:"sizeBytes,omitempty"`
+}
+
+// NodeImageCacheObservation is the observable state of a node image cache.
+type NodeImageCacheObservation struct {
+	// Images are the cached images.
+	// +optional
+	Images []CachedImageObservation `json:"images,omitempty"`
+
+	// TotalSizeBytes is the combined size of the cached images.
+	// +optional
+	TotalSizeBytes int64 `json:"totalSizeBytes,omitempty"`
+}
+
+// NodeImageCacheSpec defines the desired state of a NodeImageCache.
+type NodeImageCacheSpec struct {
+	xpv1.ResourceSpec `json:",inline"`
+	ForProvider       NodeImageCacheParameters `json:"forProvider"`
+}
+
+// NodeImageCacheStatus defines the observed state of a NodeImageCache.
+type NodeImageCacheStatus struct {
+	xpv1.ResourceStatus `json:",inline"`
+	AtProvider          NodeImageCacheObservation `json:"atProvider,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster,categories={crossplane,managed,kind}
+// +kubebuilder:printcolumn:name="READY",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status"
+// +kubebuilder:printcolumn:name="SYNCED",type="string",JSONPath=".status.conditions[?(@.type=='Synced')].status"
+// +kubebuilder:printcolumn:name="SIZE",type="integer",JSONPath=".status.atProvider.totalSizeBytes",priority=1
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+
+// NodeImageCache pre-pulls KIND node images on the Docker host so that
+// creating a Cluster does not wait for the node image to download.
+type NodeImageCache struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   NodeImageCacheSpec   `json:"spec"`
+	Status NodeImageCacheStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// NodeImageCacheList contains a list of NodeImageCache.
+type NodeImageCacheList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []NodeImageCache `json:"items"`
+}
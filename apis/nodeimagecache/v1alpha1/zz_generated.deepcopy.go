@@ -0,0 +1,162 @@
+//go:build !ignore_autogenerated
+
+// Code generated by controller-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	runtime "k8s.io/apimachinery/pkg/runtime"
+)
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CachedImageObservation) DeepCopyInto(out *CachedImageObservation) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CachedImageObservation.
+func (in *CachedImageObservation) DeepCopy() *CachedImageObservation {
+	if in == nil {
+		return nil
+	}
+	out := new(CachedImageObservation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeImageCache) DeepCopyInto(out *NodeImageCache) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeImageCache.
+func (in *NodeImageCache) DeepCopy() *NodeImageCache {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeImageCache)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *NodeImageCache) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeImageCacheList) DeepCopyInto(out *NodeImageCacheList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]NodeImageCache, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeImageCacheList.
+func (in *NodeImageCacheList) DeepCopy() *NodeImageCacheList {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeImageCacheList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *NodeImageCacheList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeImageCacheObservation) DeepCopyInto(out *NodeImageCacheObservation) {
+	*out = *in
+	if in.Images != nil {
+		in, out := &in.Images, &out.Images
+		*out = make([]CachedImageObservation, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeImageCacheObservation.
+func (in *NodeImageCacheObservation) DeepCopy() *NodeImageCacheObservation {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeImageCacheObservation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeImageCacheParameters) DeepCopyInto(out *NodeImageCacheParameters) {
+	*out = *in
+	if in.Images != nil {
+		in, out := &in.Images, &out.Images
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.GarbageCollect != nil {
+		in, out := &in.GarbageCollect, &out.GarbageCollect
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeImageCacheParameters.
+func (in *NodeImageCacheParameters) DeepCopy() *NodeImageCacheParameters {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeImageCacheParameters)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeImageCacheSpec) DeepCopyInto(out *NodeImageCacheSpec) {
+	*out = *in
+	in.ResourceSpec.DeepCopyInto(&out.ResourceSpec)
+	in.ForProvider.DeepCopyInto(&out.ForProvider)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeImageCacheSpec.
+func (in *NodeImageCacheSpec) DeepCopy() *NodeImageCacheSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeImageCacheSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeImageCacheStatus) DeepCopyInto(out *NodeImageCacheStatus) {
+	*out = *in
+	in.ResourceStatus.DeepCopyInto(&out.ResourceStatus)
+	in.AtProvider.DeepCopyInto(&out.AtProvider)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeImageCacheStatus.
+func (in *NodeImageCacheStatus) DeepCopy() *NodeImageCacheStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeImageCacheStatus)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/v2/apis/common/v1"
+)
+
+// RegistryParameters defines the desired state of a local image registry.
+type RegistryParameters struct {
+	// Image is the registry container image.
+	// +optional
+	// +kubebuilder:default="registry:2"
+	Image *string `json:"image,omitempty"`
+
+	// HostPort publishes the registry on the Docker host, so images can be
+	// pushed to localhost:<hostPort>. The registry is not published on the
+	// host if omitted.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	HostPort *int32 `json:"hostPort,omitempty"`
+
+	// ListenAddress is the host IP address to publish the registry on.
+	// Defaults to 127.0.0.1.
+	// +optional
+	ListenAddress *string `json:"listenAddress,omitempty"`
+
+	// StorageVolume is the name of a Docker volume mounted as the registry
+	// storage. The volume outlives the Registry. Storage is ephemeral if
+	// omitted. Host paths are not allowed, so that a Registry cannot mount
+	// arbitrary directories of the Docker host.
+	// +optional
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z0-9][a-zA-Z0-9_.-]+$`
+	StorageVolume *string `json:"storageVolume,omitempty"`
+
+	// Network is the Docker network the registry is attached to so that
+	// KIND nodes can reach it. Defaults to "kind".
+	// +optional
+	Network *string `json:"network,omitempty"`
+
+	// PullThroughCache turns the registry into a pull-through cache of an
+	// upstream registry.
+	// +optional
+	PullThroughCache *PullThroughCache `json:"pullThroughCache,omitempty"`
+}
+
+// PullThroughCache configures a registry as a pull-through cache.
+type PullThroughCache struct {
+	// RemoteURL is the URL of the upstream registry, for example
+	// "https://registry-1.docker.io".
+	RemoteURL string `json:"remoteURL"`
+
+	// CredentialsSecretRef references a Secret with "username" and
+	// "password" keys used to authenticate to the upstream registry.
+	// +optional
+	CredentialsSecretRef *xpv1.SecretReference `json:"credentialsSecretRef,omitempty"`
+}
+
+// RegistryObservation is the observable state of a local image registry.
+type RegistryObservation struct {
+	// ContainerID is the Docker container ID of the registry.
+	// +optional
+	ContainerID string `json:"containerID,omitempty"`
+
+	// ContainerName is the Docker container name of the registry. KIND
+	// nodes reach the registry at <containerName>:5000.
+	// +optional
+	ContainerName string `json:"containerName,omitempty"`
+
+	// Status is the Docker container status.
+	// +optional
+	Status string `json:"status,omitempty"`
+
+	// HostEndpoint is the address the registry is published at on the
+	// Docker host, for example localhost:5001.
+	// +optional
+	HostEndpoint string `json:"hostEndpoint,omitempty"`
+
+	// NetworkEndpoint is the address KIND nodes reach the registry at on
+	// the Docker network.
+	// +optional
+	NetworkEndpoint string `json:"networkEndpoint,omitempty"`
+
+	// UpstreamHost is the registry host mirrored by a pull-through cache.
+	// +optional
+	UpstreamHost string `json:"upstreamHost,omitempty"`
+}
+
+// RegistrySpec defines the desired state of a Registry.
+type RegistrySpec struct {
+	xpv1.ResourceSpec `json:",inline"`
+	ForProvider       RegistryParameters `json:"forProvider"`
+}
+
+// RegistryStatus defines the observed state of a Registry.
+type RegistryStatus struct {
+	xpv1.ResourceStatus `json:",inline"`
+	AtProvider          RegistryObservation `json:"atProvider,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster,categories={crossplane,managed,kind}
+// +kubebuilder:printcolumn:name="READY",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status"
+// +kubebuilder:printcolumn:name="SYNCED",type="string",JSONPath=".status.conditions[?(@.type=='Synced')].status"
+// +kubebuilder:printcolumn:name="HOST-ENDPOINT",type="string",JSONPath=".status.atProvider.hostEndpoint"
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+
+// Registry is a local image registry container, such as registry:2, that
+// KIND clusters pull images from. Clusters reference it with
+// spec.forProvider.localRegistryRef.
+type Registry struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   RegistrySpec   `json:"spec"`
+	Status RegistryStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// RegistryList contains a list of Registry.
+type RegistryList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Registry `json:"items"`
+}
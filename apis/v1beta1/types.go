@@ -5,6 +5,7 @@ Copyright 2024 The provider-kind authors.
 package v1beta1
 
 import (
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	xpv1 "github.com/crossplane/crossplane-runtime/v2/apis/common/v1"
@@ -18,6 +19,50 @@ type ProviderConfigSpec struct {
 	// using the local Docker daemon which does not require authentication.
 	// +optional
 	Credentials *ProviderCredentials `json:"credentials,omitempty"`
+
+	// NodeResources are the default resource limits of the nodes of
+	// Clusters that use this ProviderConfig. Nodes may override each limit.
+	// +optional
+	NodeResources *NodeResources `json:"nodeResources,omitempty"`
+
+	// HostCapacity defers creating clusters that use this ProviderConfig
+	// while the Docker host lacks the capacity for them.
+	// +optional
+	HostCapacity *HostCapacity `json:"hostCapacity,omitempty"`
+
+	// Quotas limit the clusters and nodes of the Clusters that use this
+	// ProviderConfig.
+	// +optional
+	Quotas *Quotas `json:"quotas,omitempty"`
+
+	// Policy restricts what the Clusters that use this ProviderConfig may
+	// request of the Docker host.
+	// +optional
+	Policy *Policy `json:"policy,omitempty"`
+
+	// Namespaces are the namespaces whose namespaced Clusters may use this
+	// ProviderConfig, so that an administrator decides which policy, quotas,
+	// and defaults apply to each namespace. Namespaced Clusters of every
+	// namespace may use a ProviderConfig that lists none.
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+}
+
+// NodeResources limit the host resources a node container may use. Limits
+// that are not set are not enforced.
+type NodeResources struct {
+	// CPUs a node may use, for example "2" or "1500m".
+	// +optional
+	CPUs *resource.Quantity `json:"cpus,omitempty"`
+
+	// Memory a node may use, for example "4Gi". Nodes get no swap.
+	// +optional
+	Memory *resource.Quantity `json:"memory,omitempty"`
+
+	// PidsLimit is the maximum number of processes in a node.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	PidsLimit *int64 `json:"pidsLimit,omitempty"`
 }
 
 // ProviderCredentials required to authenticate (optional for KIND).
@@ -29,9 +74,135 @@ type ProviderCredentials struct {
 	xpv1.CommonCredentialSelectors `json:",inline"`
 }
 
+// HostCapacity is the capacity the Docker host must have left for a cluster
+// to be created. Thresholds that are not set are not checked. Free memory and
+// disk are read by a container of a KIND node image already present on the
+// host, or of the probe image. Without either, they cannot be measured and
+// clusters are not created until one is present.
+type HostCapacity struct {
+	// MinFreeMemory is the memory the host must have available, for example
+	// "4Gi".
+	// +optional
+	MinFreeMemory *resource.Quantity `json:"minFreeMemory,omitempty"`
+
+	// MinFreeDisk is the free space the filesystem of the Docker data root
+	// must have, for example "20Gi".
+	// +optional
+	MinFreeDisk *resource.Quantity `json:"minFreeDisk,omitempty"`
+
+	// ProbeImage is a small image with sh, grep, and df, for example
+	// "busybox:stable", that reads free memory and disk when no KIND node
+	// image is present on the host. It is pulled if it is missing. Node
+	// images are never pulled for this.
+	// +optional
+	ProbeImage *string `json:"probeImage,omitempty"`
+
+	// MaxNodes is the maximum number of running KIND nodes on the host,
+	// including the nodes of the new cluster.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	MaxNodes *int32 `json:"maxNodes,omitempty"`
+}
+
+// Quotas limit the clusters and nodes of the Clusters that use a
+// ProviderConfig, cluster-scoped and namespaced. Clusters that would exceed
+// them are not created. Quotas that are not set are not enforced.
+type Quotas struct {
+	// MaxClusters is the maximum number of clusters.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MaxClusters *int32 `json:"maxClusters,omitempty"`
+
+	// MaxNodes is the maximum number of nodes of all clusters.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MaxNodes *int32 `json:"maxNodes,omitempty"`
+
+	// MaxControlPlaneNodes is the maximum number of control plane nodes of
+	// each cluster.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	MaxControlPlaneNodes *int32 `json:"maxControlPlaneNodes,omitempty"`
+
+	// MaxClustersPerNamespace is the maximum number of namespaced clusters
+	// in each namespace.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MaxClustersPerNamespace *int32 `json:"maxClustersPerNamespace,omitempty"`
+}
+
+// Policy restricts what Clusters may request of the Docker host. KIND nodes
+// are privileged containers, so host mounts and port mappings reach the host
+// itself, and the Docker network decides what they can reach. Clusters that
+// violate the policy are not created. Lists that are empty allow anything,
+// except AllowedNetworks for namespaced Clusters.
+type Policy struct {
+	// AllowedHostPathPrefixes are the host paths that nodes may mount,
+	// along with everything below them.
+	// +optional
+	AllowedHostPathPrefixes []string `json:"allowedHostPathPrefixes,omitempty"`
+
+	// AllowedHostPorts are the ranges of host ports that node ports and the
+	// API server may be mapped to. Port 0, which lets Docker pick a free
+	// port, is always allowed.
+	// +optional
+	AllowedHostPorts []PortRange `json:"allowedHostPorts,omitempty"`
+
+	// AllowedListenAddresses are the host addresses that node ports and the
+	// API server may be mapped on. Node ports are mapped on 0.0.0.0, and the
+	// API server on 127.0.0.1, if no address is set.
+	// +optional
+	AllowedListenAddresses []string `json:"allowedListenAddresses,omitempty"`
+
+	// AllowedImageRegistries are the registries that node images may be
+	// pulled from, for example "docker.io" or "registry.example.com:5000".
+	// Images that name no registry are pulled from docker.io.
+	// +optional
+	AllowedImageRegistries []string `json:"allowedImageRegistries,omitempty"`
+
+	// AllowedNetworks are the Docker networks that nodes may be created on.
+	// Nodes are created on the default network of the provider, normally
+	// "kind", if no network is set. Namespaced Clusters may only use the
+	// default network if none are listed.
+	// +optional
+	AllowedNetworks []string `json:"allowedNetworks,omitempty"`
+}
+
+// PortRange is an inclusive range of ports.
+type PortRange struct {
+	// Min is the first port of the range.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Min int32 `json:"min"`
+
+	// Max is the last port of the range.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Max int32 `json:"max"`
+}
+
 // A ProviderConfigStatus reflects the observed state of a ProviderConfig.
 type ProviderConfigStatus struct {
 	xpv1.ProviderConfigStatus `json:",inline"`
+
+	// Usage counts the clusters and nodes of the Clusters that use this
+	// ProviderConfig.
+	// +optional
+	Usage *QuotaUsage `json:"usage,omitempty"`
+}
+
+// QuotaUsage counts the clusters and nodes of the Clusters that use a
+// ProviderConfig.
+type QuotaUsage struct {
+	// Clusters is the number of clusters.
+	Clusters int32 `json:"clusters"`
+
+	// Nodes is the number of nodes of all clusters.
+	Nodes int32 `json:"nodes"`
+
+	// Namespaces is the number of namespaced clusters in each namespace.
+	// +optional
+	Namespaces map[string]int32 `json:"namespaces,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -40,6 +211,8 @@ type ProviderConfigStatus struct {
 // +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
 // +kubebuilder:printcolumn:name="READY",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status"
+// +kubebuilder:printcolumn:name="CLUSTERS",type="integer",JSONPath=".status.usage.clusters"
+// +kubebuilder:printcolumn:name="NODES",type="integer",JSONPath=".status.usage.nodes"
 // +kubebuilder:resource:scope=Cluster,categories={crossplane,provider,kind}
 type ProviderConfig struct {
 	metav1.TypeMeta   `json:",inline"`
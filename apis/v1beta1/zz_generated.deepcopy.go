@@ -8,6 +8,126 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HostCapacity) DeepCopyInto(out *HostCapacity) {
+	*out = *in
+	if in.MinFreeMemory != nil {
+		in, out := &in.MinFreeMemory, &out.MinFreeMemory
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.MinFreeDisk != nil {
+		in, out := &in.MinFreeDisk, &out.MinFreeDisk
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.ProbeImage != nil {
+		in, out := &in.ProbeImage, &out.ProbeImage
+		*out = new(string)
+		**out = **in
+	}
+	if in.MaxNodes != nil {
+		in, out := &in.MaxNodes, &out.MaxNodes
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HostCapacity.
+func (in *HostCapacity) DeepCopy() *HostCapacity {
+	if in == nil {
+		return nil
+	}
+	out := new(HostCapacity)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeResources) DeepCopyInto(out *NodeResources) {
+	*out = *in
+	if in.CPUs != nil {
+		in, out := &in.CPUs, &out.CPUs
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Memory != nil {
+		in, out := &in.Memory, &out.Memory
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.PidsLimit != nil {
+		in, out := &in.PidsLimit, &out.PidsLimit
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeResources.
+func (in *NodeResources) DeepCopy() *NodeResources {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeResources)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Policy) DeepCopyInto(out *Policy) {
+	*out = *in
+	if in.AllowedHostPathPrefixes != nil {
+		in, out := &in.AllowedHostPathPrefixes, &out.AllowedHostPathPrefixes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedHostPorts != nil {
+		in, out := &in.AllowedHostPorts, &out.AllowedHostPorts
+		*out = make([]PortRange, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedListenAddresses != nil {
+		in, out := &in.AllowedListenAddresses, &out.AllowedListenAddresses
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedImageRegistries != nil {
+		in, out := &in.AllowedImageRegistries, &out.AllowedImageRegistries
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedNetworks != nil {
+		in, out := &in.AllowedNetworks, &out.AllowedNetworks
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Policy.
+func (in *Policy) DeepCopy() *Policy {
+	if in == nil {
+		return nil
+	}
+	out := new(Policy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PortRange) DeepCopyInto(out *PortRange) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PortRange.
+func (in *PortRange) DeepCopy() *PortRange {
+	if in == nil {
+		return nil
+	}
+	out := new(PortRange)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ProviderConfig) DeepCopyInto(out *ProviderConfig) {
 	*out = *in
@@ -75,6 +195,31 @@ func (in *ProviderConfigSpec) DeepCopyInto(out *ProviderConfigSpec) {
 		in.Credentials.DeepCopyInto(outCreds)
 		out.Credentials = outCreds
 	}
+	if in.NodeResources != nil {
+		in, out := &in.NodeResources, &out.NodeResources
+		*out = new(NodeResources)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HostCapacity != nil {
+		in, out := &in.HostCapacity, &out.HostCapacity
+		*out = new(HostCapacity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Quotas != nil {
+		in, out := &in.Quotas, &out.Quotas
+		*out = new(Quotas)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Policy != nil {
+		in, out := &in.Policy, &out.Policy
+		*out = new(Policy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProviderConfigSpec.
@@ -91,6 +236,11 @@ func (in *ProviderConfigSpec) DeepCopy() *ProviderConfigSpec {
 func (in *ProviderConfigStatus) DeepCopyInto(out *ProviderConfigStatus) {
 	*out = *in
 	in.ProviderConfigStatus.DeepCopyInto(&out.ProviderConfigStatus)
+	if in.Usage != nil {
+		in, out := &in.Usage, &out.Usage
+		*out = new(QuotaUsage)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProviderConfigStatus.
@@ -176,3 +326,60 @@ func (in *ProviderCredentials) DeepCopy() *ProviderCredentials {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *QuotaUsage) DeepCopyInto(out *QuotaUsage) {
+	*out = *in
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make(map[string]int32, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new QuotaUsage.
+func (in *QuotaUsage) DeepCopy() *QuotaUsage {
+	if in == nil {
+		return nil
+	}
+	out := new(QuotaUsage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Quotas) DeepCopyInto(out *Quotas) {
+	*out = *in
+	if in.MaxClusters != nil {
+		in, out := &in.MaxClusters, &out.MaxClusters
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxNodes != nil {
+		in, out := &in.MaxNodes, &out.MaxNodes
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxControlPlaneNodes != nil {
+		in, out := &in.MaxControlPlaneNodes, &out.MaxControlPlaneNodes
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxClustersPerNamespace != nil {
+		in, out := &in.MaxClustersPerNamespace, &out.MaxClustersPerNamespace
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Quotas.
+func (in *Quotas) DeepCopy() *Quotas {
+	if in == nil {
+		return nil
+	}
+	out := new(Quotas)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -9,7 +9,14 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 
 	clusterv1alpha1 "github.com/humoflife/provider-kind/apis/cluster/v1alpha1"
+	clusterpeeringv1alpha1 "github.com/humoflife/provider-kind/apis/clusterpeering/v1alpha1"
+	loadedimagev1alpha1 "github.com/humoflife/provider-kind/apis/loadedimage/v1alpha1"
+	manifestv1alpha1 "github.com/humoflife/provider-kind/apis/manifest/v1alpha1"
 	namespacedclusterv1alpha1 "github.com/humoflife/provider-kind/apis/namespacedcluster/v1alpha1"
+	networkv1alpha1 "github.com/humoflife/provider-kind/apis/network/v1alpha1"
+	nodeexecv1alpha1 "github.com/humoflife/provider-kind/apis/nodeexec/v1alpha1"
+	nodeimagecachev1alpha1 "github.com/humoflife/provider-kind/apis/nodeimagecache/v1alpha1"
+	registryv1alpha1 "github.com/humoflife/provider-kind/apis/registry/v1alpha1"
 	v1beta1 "github.com/humoflife/provider-kind/apis/v1beta1"
 )
 
@@ -17,7 +24,14 @@ func init() {
 	// Register the types with the Scheme so the components can map objects to GroupVersionKinds and back
 	AddToSchemes = append(AddToSchemes,
 		clusterv1alpha1.SchemeBuilder.AddToScheme,
+		clusterpeeringv1alpha1.SchemeBuilder.AddToScheme,
+		loadedimagev1alpha1.SchemeBuilder.AddToScheme,
+		manifestv1alpha1.SchemeBuilder.AddToScheme,
 		namespacedclusterv1alpha1.SchemeBuilder.AddToScheme,
+		networkv1alpha1.SchemeBuilder.AddToScheme,
+		nodeexecv1alpha1.SchemeBuilder.AddToScheme,
+		nodeimagecachev1alpha1.SchemeBuilder.AddToScheme,
+		registryv1alpha1.SchemeBuilder.AddToScheme,
 		v1beta1.SchemeBuilder.AddToScheme,
 	)
 }
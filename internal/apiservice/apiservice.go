@@ -0,0 +1,220 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+// Package apiservice publishes the API server of a KIND cluster as a Service
+// in the management cluster. The Service has no selector; an EndpointSlice
+// points it at the cluster's API server address on the Docker network, so
+// pods of a management cluster that shares the Docker host can reach it.
+package apiservice
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"strconv"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/kind/pkg/cluster/constants"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+)
+
+// Port is the port KIND API servers, and their load balancer, listen on
+// within the Docker network. The Service exposes the same port.
+const Port = 6443
+
+const (
+	// LabelCluster is set on the Service and EndpointSlice to the name of
+	// the KIND cluster they publish.
+	LabelCluster = "kind.crossplane.io/cluster"
+
+	managedBy = "provider-kind"
+)
+
+const (
+	errNoAPIServer      = "cluster has no control plane node"
+	errNodeIP           = "cannot get the address of node %s"
+	errGetService       = "cannot get Service %s/%s"
+	errApplyService     = "cannot apply Service %s/%s"
+	errApplySlice       = "cannot apply EndpointSlice %s/%s"
+	errDeleteService    = "cannot delete Service %s/%s"
+	errLoadKubeConfig   = "cannot load kubeconfig"
+	errWriteKubeConfig  = "cannot write kubeconfig"
+	errServiceConflict  = "Service %s/%s exists and is not managed by provider-kind"
+	errParseServer      = "cannot parse kubeconfig server %q"
+	errEmptyClusterList = "kubeconfig has no clusters"
+)
+
+// Target returns the address of the API server on the Docker network: the
+// external load balancer of a cluster with several control plane nodes, or
+// its only control plane node.
+func Target(all []nodes.Node) (netip.Addr, error) {
+	var target nodes.Node
+	for _, n := range all {
+		role, err := n.Role()
+		if err != nil {
+			continue
+		}
+		if role == constants.ExternalLoadBalancerNodeRoleValue {
+			target = n
+			break
+		}
+		if role == constants.ControlPlaneNodeRoleValue && target == nil {
+			target = n
+		}
+	}
+	if target == nil {
+		return netip.Addr{}, errors.New(errNoAPIServer)
+	}
+
+	ipv4, ipv6, err := target.IP()
+	if err != nil {
+		return netip.Addr{}, errors.Wrapf(err, errNodeIP, target.String())
+	}
+	ip := ipv4
+	if ip == "" {
+		ip = ipv6
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return netip.Addr{}, errors.Wrapf(err, errNodeIP, target.String())
+	}
+	return addr, nil
+}
+
+// UpToDate reports whether the Service exists and points at the target.
+func UpToDate(ctx context.Context, kube client.Client, namespace, name string, target netip.Addr) bool {
+	es := &discoveryv1.EndpointSlice{}
+	if err := kube.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, es); err != nil {
+		return false
+	}
+	if len(es.Endpoints) != 1 || len(es.Endpoints[0].Addresses) != 1 {
+		return false
+	}
+	return es.Endpoints[0].Addresses[0] == target.String() &&
+		kube.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &corev1.Service{}) == nil
+}
+
+// Apply creates or updates the Service and the EndpointSlice that points it
+// at the target.
+func Apply(ctx context.Context, kube client.Client, namespace, name, cluster string, target netip.Addr) error {
+	svc := &corev1.Service{}
+	err := kube.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, svc)
+	switch {
+	case kerrors.IsNotFound(err):
+		svc = &corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+	case err != nil:
+		return errors.Wrapf(err, errGetService, namespace, name)
+	case svc.GetLabels()[LabelCluster] != cluster:
+		return errors.Errorf(errServiceConflict, namespace, name)
+	}
+	svc.SetLabels(map[string]string{LabelCluster: cluster})
+	svc.Spec.Type = corev1.ServiceTypeClusterIP
+	svc.Spec.Selector = nil
+	svc.Spec.Ports = []corev1.ServicePort{{
+		Name:       "https",
+		Protocol:   corev1.ProtocolTCP,
+		Port:       Port,
+		TargetPort: intstr.FromInt32(Port),
+	}}
+	if svc.GetResourceVersion() == "" {
+		err = kube.Create(ctx, svc)
+	} else {
+		err = kube.Update(ctx, svc)
+	}
+	if err != nil {
+		return errors.Wrapf(err, errApplyService, namespace, name)
+	}
+
+	family := discoveryv1.AddressTypeIPv4
+	if target.Is6() {
+		family = discoveryv1.AddressTypeIPv6
+	}
+	es := &discoveryv1.EndpointSlice{}
+	err = kube.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, es)
+	if kerrors.IsNotFound(err) {
+		es = &discoveryv1.EndpointSlice{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+	} else if err != nil {
+		return errors.Wrapf(err, errApplySlice, namespace, name)
+	}
+	es.SetLabels(map[string]string{
+		LabelCluster:                 cluster,
+		discoveryv1.LabelServiceName: name,
+		discoveryv1.LabelManagedBy:   managedBy,
+	})
+	es.SetOwnerReferences([]metav1.OwnerReference{{
+		APIVersion: "v1",
+		Kind:       "Service",
+		Name:       svc.GetName(),
+		UID:        svc.GetUID(),
+	}})
+	ready, portName, protocol, port := true, "https", corev1.ProtocolTCP, int32(Port)
+	es.AddressType = family
+	es.Endpoints = []discoveryv1.Endpoint{{
+		Addresses:  []string{target.String()},
+		Conditions: discoveryv1.EndpointConditions{Ready: &ready},
+	}}
+	es.Ports = []discoveryv1.EndpointPort{{
+		Name:     &portName,
+		Protocol: &protocol,
+		Port:     &port,
+	}}
+	if es.GetResourceVersion() == "" {
+		err = kube.Create(ctx, es)
+	} else {
+		err = kube.Update(ctx, es)
+	}
+	return errors.Wrapf(err, errApplySlice, namespace, name)
+}
+
+// Delete deletes the Service, and with it the EndpointSlice it owns. Services
+// not created for the cluster are left alone.
+func Delete(ctx context.Context, kube client.Client, namespace, name, cluster string) error {
+	svc := &corev1.Service{}
+	err := kube.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, svc)
+	if kerrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, errGetService, namespace, name)
+	}
+	if svc.GetLabels()[LabelCluster] != cluster {
+		return nil
+	}
+	return errors.Wrapf(client.IgnoreNotFound(kube.Delete(ctx, svc)), errDeleteService, namespace, name)
+}
+
+// KubeConfig rewrites an internal kubeconfig, whose server is the API
+// server's host name on the Docker network, to use the Service instead. The
+// original host name is kept as the TLS server name, since the API server
+// certificate is not valid for the Service.
+func KubeConfig(internal []byte, namespace, name string) ([]byte, error) {
+	cfg, err := clientcmd.Load(internal)
+	if err != nil {
+		return nil, errors.Wrap(err, errLoadKubeConfig)
+	}
+	if len(cfg.Clusters) == 0 {
+		return nil, errors.New(errEmptyClusterList)
+	}
+	server := "https://" + net.JoinHostPort(fmt.Sprintf("%s.%s.svc", name, namespace), strconv.Itoa(Port))
+	for _, c := range cfg.Clusters {
+		u, err := url.Parse(c.Server)
+		if err != nil {
+			return nil, errors.Wrapf(err, errParseServer, c.Server)
+		}
+		c.Server = server
+		c.TLSServerName = u.Hostname()
+	}
+	out, err := clientcmd.Write(*cfg)
+	return out, errors.Wrap(err, errWriteKubeConfig)
+}
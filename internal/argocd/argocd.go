@@ -0,0 +1,213 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+// Package argocd registers KIND clusters with Argo CD by writing declarative
+// cluster Secrets to the namespace Argo CD runs in.
+package argocd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/humoflife/provider-kind/internal/apiservice"
+)
+
+const (
+	// LabelSecretType marks a Secret as an Argo CD cluster.
+	LabelSecretType = "argocd.argoproj.io/secret-type"
+
+	secretTypeCluster = "cluster"
+)
+
+const (
+	errLoadKubeConfig = "cannot load kubeconfig"
+	errNoContext      = "kubeconfig has no context %q"
+	errNoCluster      = "kubeconfig has no cluster %q"
+	errNoAuthInfo     = "kubeconfig has no user %q"
+	errMarshalConfig  = "cannot marshal Argo CD cluster config"
+	errGetSecret      = "cannot get Secret %s/%s"
+	errApplySecret    = "cannot apply Secret %s/%s"
+	errDeleteSecret   = "cannot delete Secret %s/%s"
+	errSecretConflict = "Secret %s/%s exists and is not managed by provider-kind"
+)
+
+// Cluster is an Argo CD cluster.
+type Cluster struct {
+	// Name Argo CD shows for the cluster.
+	Name string
+
+	// Server is the URL of the API server.
+	Server string
+
+	Config Config
+
+	// Labels are added to the Secret.
+	Labels map[string]string
+}
+
+// Config is the connection configuration of an Argo CD cluster.
+type Config struct {
+	BearerToken     string          `json:"bearerToken,omitempty"`
+	TLSClientConfig TLSClientConfig `json:"tlsClientConfig"`
+}
+
+// TLSClientConfig is the TLS configuration of an Argo CD cluster. Byte
+// fields are PEM data, which Argo CD expects base64 encoded.
+type TLSClientConfig struct {
+	Insecure   bool   `json:"insecure"`
+	ServerName string `json:"serverName,omitempty"`
+	CAData     []byte `json:"caData,omitempty"`
+	CertData   []byte `json:"certData,omitempty"`
+	KeyData    []byte `json:"keyData,omitempty"`
+}
+
+// FromKubeConfig returns the Argo CD cluster of the kubeconfig's current
+// context. It authenticates with the bearer token, or with the kubeconfig's
+// client certificate if the token is empty.
+func FromKubeConfig(kubeconfig []byte, name, token string) (Cluster, error) {
+	cfg, err := clientcmd.Load(kubeconfig)
+	if err != nil {
+		return Cluster{}, errors.Wrap(err, errLoadKubeConfig)
+	}
+	current, ok := cfg.Contexts[cfg.CurrentContext]
+	if !ok {
+		return Cluster{}, errors.Errorf(errNoContext, cfg.CurrentContext)
+	}
+	c, ok := cfg.Clusters[current.Cluster]
+	if !ok {
+		return Cluster{}, errors.Errorf(errNoCluster, current.Cluster)
+	}
+
+	out := Cluster{
+		Name:   name,
+		Server: c.Server,
+		Config: Config{TLSClientConfig: TLSClientConfig{
+			Insecure:   c.InsecureSkipTLSVerify,
+			ServerName: c.TLSServerName,
+			CAData:     c.CertificateAuthorityData,
+		}},
+	}
+	if token != "" {
+		out.Config.BearerToken = token
+		return out, nil
+	}
+	a, ok := cfg.AuthInfos[current.AuthInfo]
+	if !ok {
+		return Cluster{}, errors.Errorf(errNoAuthInfo, current.AuthInfo)
+	}
+	out.Config.TLSClientConfig.CertData = a.ClientCertificateData
+	out.Config.TLSClientConfig.KeyData = a.ClientKeyData
+	return out, nil
+}
+
+// data returns the Secret data of the cluster.
+func (c Cluster) data() (map[string][]byte, error) {
+	config, err := json.Marshal(c.Config)
+	if err != nil {
+		return nil, errors.Wrap(err, errMarshalConfig)
+	}
+	return map[string][]byte{
+		"name":   []byte(c.Name),
+		"server": []byte(c.Server),
+		"config": config,
+	}, nil
+}
+
+// labels returns the Secret labels of the cluster.
+func (c Cluster) labels(cluster string) map[string]string {
+	labels := map[string]string{}
+	for k, v := range c.Labels {
+		labels[k] = v
+	}
+	labels[LabelSecretType] = secretTypeCluster
+	labels[apiservice.LabelCluster] = cluster
+	return labels
+}
+
+// UpToDate reports whether the Secret exists and holds the cluster.
+func UpToDate(ctx context.Context, kube client.Client, namespace, name, cluster string, c Cluster) (bool, error) {
+	s := &corev1.Secret{}
+	err := kube.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, s)
+	if kerrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrapf(err, errGetSecret, namespace, name)
+	}
+	want, err := c.data()
+	if err != nil {
+		return false, err
+	}
+	labels := c.labels(cluster)
+	if len(s.Data) != len(want) || len(s.GetLabels()) != len(labels) {
+		return false, nil
+	}
+	for k, v := range want {
+		if !bytes.Equal(s.Data[k], v) {
+			return false, nil
+		}
+	}
+	for k, v := range labels {
+		if s.GetLabels()[k] != v {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Apply creates or updates the Secret that registers the cluster.
+func Apply(ctx context.Context, kube client.Client, namespace, name, cluster string, c Cluster) error {
+	data, err := c.data()
+	if err != nil {
+		return err
+	}
+
+	s := &corev1.Secret{}
+	err = kube.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, s)
+	switch {
+	case kerrors.IsNotFound(err):
+		s = &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+	case err != nil:
+		return errors.Wrapf(err, errGetSecret, namespace, name)
+	case s.GetLabels()[apiservice.LabelCluster] != cluster:
+		return errors.Errorf(errSecretConflict, namespace, name)
+	}
+
+	s.SetLabels(c.labels(cluster))
+	s.Type = corev1.SecretTypeOpaque
+	s.Data = data
+
+	if s.GetResourceVersion() == "" {
+		err = kube.Create(ctx, s)
+	} else {
+		err = kube.Update(ctx, s)
+	}
+	return errors.Wrapf(err, errApplySecret, namespace, name)
+}
+
+// Delete deletes the Secret. Secrets not written for the cluster are left
+// alone.
+func Delete(ctx context.Context, kube client.Client, namespace, name, cluster string) error {
+	s := &corev1.Secret{}
+	err := kube.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, s)
+	if kerrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, errGetSecret, namespace, name)
+	}
+	if s.GetLabels()[apiservice.LabelCluster] != cluster {
+		return nil
+	}
+	return errors.Wrapf(client.IgnoreNotFound(kube.Delete(ctx, s)), errDeleteSecret, namespace, name)
+}
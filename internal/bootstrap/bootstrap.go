@@ -0,0 +1,80 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+// Package bootstrap applies the bootstrap steps of a KIND cluster and records
+// their state, for the cluster-scoped and the namespaced Cluster alike.
+package bootstrap
+
+import (
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+
+	clusterv1alpha1 "github.com/humoflife/provider-kind/apis/cluster/v1alpha1"
+	"github.com/humoflife/provider-kind/internal/kindnode"
+)
+
+const (
+	errBootstrapStep = "bootstrap step %q failed"
+)
+
+// Applied reports whether every bootstrap step was applied with its current
+// manifests, and no steps that were removed are reported.
+func Applied(obs *clusterv1alpha1.ClusterObservation, steps []kindnode.BootstrapStep) bool {
+	if len(obs.Bootstrap) != len(steps) {
+		return false
+	}
+	applied := make(map[string]string, len(obs.Bootstrap))
+	for _, o := range obs.Bootstrap {
+		if o.Phase == clusterv1alpha1.BootstrapStepApplied {
+			applied[o.Name] = o.Hash
+		}
+	}
+	for _, s := range steps {
+		if applied[s.Name] != s.Hash() {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply applies, in order, the bootstrap steps that were not applied
+// with their current manifests, and records the state of each. It stops at
+// the first step that fails; the steps after it stay pending until it is
+// retried.
+func Apply(obs *clusterv1alpha1.ClusterObservation, all []nodes.Node, steps []kindnode.BootstrapStep) error {
+	previous := make(map[string]clusterv1alpha1.BootstrapStepObservation, len(obs.Bootstrap))
+	for _, o := range obs.Bootstrap {
+		previous[o.Name] = o
+	}
+
+	out := make([]clusterv1alpha1.BootstrapStepObservation, 0, len(steps))
+	var failed error
+	for _, s := range steps {
+		o := previous[s.Name]
+		o.Name = s.Name
+		switch {
+		case o.Phase == clusterv1alpha1.BootstrapStepApplied && o.Hash == s.Hash():
+		case failed != nil:
+			o.Phase = clusterv1alpha1.BootstrapStepPending
+		default:
+			now := metav1.Now()
+			o.LastAttemptTime = &now
+			if err := kindnode.ApplyBootstrapStep(all, s); err != nil {
+				o.Phase = clusterv1alpha1.BootstrapStepFailed
+				o.Attempts++
+				o.Message = err.Error()
+				failed = errors.Wrapf(err, errBootstrapStep, s.Name)
+				break
+			}
+			o.Phase = clusterv1alpha1.BootstrapStepApplied
+			o.Hash = s.Hash()
+			o.Attempts = 0
+			o.Message = ""
+		}
+		out = append(out, o)
+	}
+	obs.Bootstrap = out
+	return failed
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+// Package capacity checks that the Docker host has the capacity a
+// ProviderConfig requires before a cluster is created.
+package capacity
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/kind/pkg/apis/config/defaults"
+
+	clusterv1alpha1 "github.com/humoflife/provider-kind/apis/cluster/v1alpha1"
+	"github.com/humoflife/provider-kind/apis/v1beta1"
+	"github.com/humoflife/provider-kind/internal/docker"
+)
+
+// Check checks that the Docker host has the capacity the ProviderConfig
+// requires for a cluster of the supplied parameters. It returns why the host
+// lacks capacity, or an empty string if it does not.
+func Check(ctx context.Context, hc *v1beta1.HostCapacity, p clusterv1alpha1.ClusterParameters) (string, error) {
+	if hc == nil {
+		return "", nil
+	}
+	var short []string
+
+	if hc.MaxNodes != nil {
+		running, err := docker.KindNodes(ctx)
+		if err != nil {
+			return "", err
+		}
+		// KIND creates a single control plane node if none are listed.
+		adds := max(len(p.Nodes), 1)
+		if running+adds > int(*hc.MaxNodes) {
+			short = append(short, fmt.Sprintf("%d KIND nodes are running and the cluster adds %d, more than the maximum of %d", running, adds, *hc.MaxNodes))
+		}
+	}
+
+	if hc.MinFreeMemory != nil || hc.MinFreeDisk != nil {
+		// The node image is probed with if it was pulled already.
+		image := defaults.Image
+		if len(p.Nodes) > 0 && p.Nodes[0].Image != nil {
+			image = *p.Nodes[0].Image
+		}
+		probe := ""
+		if hc.ProbeImage != nil {
+			probe = *hc.ProbeImage
+		}
+		c, err := docker.InspectHostCapacity(ctx, probe, image)
+		if err != nil {
+			return "", err
+		}
+		// Thresholds that cannot be measured are not met, so that the first
+		// cluster on a fresh host is checked like any other.
+		if !c.Probed {
+			short = append(short, fmt.Sprintf("free memory and disk cannot be measured until node image %q or a probe image is present on the host", image))
+		}
+		if c.Probed && hc.MinFreeMemory != nil && c.FreeMemory < hc.MinFreeMemory.Value() {
+			short = append(short, fmt.Sprintf("%s of memory is available, less than the minimum of %s", bytesOf(c.FreeMemory), hc.MinFreeMemory))
+		}
+		if c.Probed && hc.MinFreeDisk != nil && c.FreeDisk < hc.MinFreeDisk.Value() {
+			short = append(short, fmt.Sprintf("%s of disk is free, less than the minimum of %s", bytesOf(c.FreeDisk), hc.MinFreeDisk))
+		}
+	}
+
+	return strings.Join(short, "; "), nil
+}
+
+// bytesOf formats a number of bytes as a binary quantity, rounded down to
+// MiB.
+func bytesOf(b int64) *resource.Quantity {
+	return resource.NewQuantity(b/(1<<20)*(1<<20), resource.BinarySI)
+}
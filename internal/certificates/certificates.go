@@ -0,0 +1,104 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+// Package certificates observes the expiry of the certificates of a KIND
+// cluster, for the cluster-scoped and the namespaced Cluster alike.
+package certificates
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+
+	clusterv1alpha1 "github.com/humoflife/provider-kind/apis/cluster/v1alpha1"
+)
+
+// defaultRenewBefore is how long before expiry certificates are due for
+// renewal unless certificateRotation.renewBefore is set.
+const defaultRenewBefore = 720 * time.Hour
+
+const (
+	errParseRenewBefore = "cannot parse certificateRotation.renewBefore"
+	errParseCertificate = "cannot parse %s certificate"
+	errNoCertificate    = "%s certificate is not PEM encoded"
+)
+
+// Observe records in obs when the client and CA certificates of the primary
+// kubeconfig expire, and sets the CertificatesExpiring condition on c. It
+// returns whether the client certificate is due for renewal.
+func Observe(c resource.Conditioned, obs *clusterv1alpha1.ClusterObservation, r *clusterv1alpha1.CertificateRotation, conn managed.ConnectionDetails) (bool, error) {
+	renewBefore, err := renewBeforeOf(r)
+	if err != nil {
+		return false, err
+	}
+
+	certs := &clusterv1alpha1.CertificatesObservation{}
+	var expiring []string
+	due := false
+	if data := conn["clientCert"]; len(data) > 0 {
+		notAfter, err := notAfterOf("client", data)
+		if err != nil {
+			return false, err
+		}
+		certs.ClientNotAfter = &metav1.Time{Time: notAfter}
+		if time.Until(notAfter) < renewBefore {
+			due = true
+			expiring = append(expiring, fmt.Sprintf("client certificate expires at %s", notAfter.Format(time.RFC3339)))
+		}
+	}
+	if data := conn["clusterCA"]; len(data) > 0 {
+		notAfter, err := notAfterOf("CA", data)
+		if err != nil {
+			return false, err
+		}
+		certs.CANotAfter = &metav1.Time{Time: notAfter}
+		if time.Until(notAfter) < renewBefore {
+			expiring = append(expiring, fmt.Sprintf("CA certificate expires at %s; recreate the cluster", notAfter.Format(time.RFC3339)))
+		}
+	}
+	obs.Certificates = certs
+
+	if len(expiring) == 0 {
+		c.SetConditions(clusterv1alpha1.CertificatesValid())
+		return false, nil
+	}
+	c.SetConditions(clusterv1alpha1.CertificatesExpiring(strings.Join(expiring, "; ")))
+	return due, nil
+}
+
+// Automatic reports whether certificates are renewed automatically.
+func Automatic(r *clusterv1alpha1.CertificateRotation) bool {
+	return r != nil && r.Automatic
+}
+
+// renewBeforeOf returns how long before expiry certificates are due for
+// renewal.
+func renewBeforeOf(r *clusterv1alpha1.CertificateRotation) (time.Duration, error) {
+	if r == nil || r.RenewBefore == nil {
+		return defaultRenewBefore, nil
+	}
+	d, err := time.ParseDuration(*r.RenewBefore)
+	return d, errors.Wrap(err, errParseRenewBefore)
+}
+
+// notAfterOf returns when the first certificate in the PEM data expires.
+func notAfterOf(kind string, data []byte) (time.Time, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return time.Time{}, errors.Errorf(errNoCertificate, kind)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return time.Time{}, errors.Wrapf(err, errParseCertificate, kind)
+	}
+	return cert.NotAfter, nil
+}
@@ -0,0 +1,551 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+// Package clusterpeering implements the Crossplane managed reconciler for
+// routing between KIND clusters.
+package clusterpeering
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/kind/pkg/apis/config/v1alpha4"
+	kindcluster "sigs.k8s.io/kind/pkg/cluster"
+	"sigs.k8s.io/kind/pkg/cluster/constants"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/v2/apis/common/v1"
+	xpcontroller "github.com/crossplane/crossplane-runtime/v2/pkg/controller"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/event"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/ratelimiter"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+
+	clusterv1alpha1 "github.com/humoflife/provider-kind/apis/cluster/v1alpha1"
+	clusterpeeringv1alpha1 "github.com/humoflife/provider-kind/apis/clusterpeering/v1alpha1"
+	"github.com/humoflife/provider-kind/apis/v1beta1"
+	"github.com/humoflife/provider-kind/internal/docker"
+	"github.com/humoflife/provider-kind/internal/kindnode"
+)
+
+const (
+	errNotClusterPeering = "managed resource is not a ClusterPeering custom resource"
+	errTrackUsage        = "cannot track ProviderConfig usage"
+	errGetCluster        = "cannot get Cluster %q"
+	errListClusters      = "cannot list KIND clusters"
+	errGetNodes          = "cannot list nodes of KIND cluster %q"
+	errNoNodes           = "KIND cluster %q has no nodes"
+	errInspectNode       = "cannot inspect node %s"
+	errNoSharedNetwork   = "the nodes of the peered clusters share no Docker network"
+	errParseSubnet       = "cannot parse subnet %q of Cluster %q"
+	errOverlap           = "%s of Cluster %q overlaps %s of Cluster %q"
+	errGetPodCIDRs       = "cannot get pod CIDRs of KIND cluster %q"
+	errInstallRoutes     = "cannot install routes"
+	errRemoveRoutes      = "cannot remove routes"
+)
+
+// dnsPort is the port of the kube-dns Service dialed to check reachability.
+const dnsPort = 53
+
+// Setup adds a controller that reconciles ClusterPeering managed resources.
+func Setup(mgr ctrl.Manager, o xpcontroller.Options) error {
+	name := managed.ControllerName(clusterpeeringv1alpha1.ClusterPeeringGroupVersionKind.String())
+
+	reconcilerOpts := []managed.ReconcilerOption{
+		managed.WithExternalConnecter(&connector{kube: mgr.GetClient()}),
+		managed.WithLogger(o.Logger.WithValues("controller", name)),
+		managed.WithRecorder(event.NewAPIRecorder(mgr.GetEventRecorderFor(name))),
+		managed.WithPollInterval(o.PollInterval),
+	}
+
+	r := managed.NewReconciler(mgr,
+		resource.ManagedKind(clusterpeeringv1alpha1.ClusterPeeringGroupVersionKind),
+		reconcilerOpts...,
+	)
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(name).
+		WithOptions(o.ForControllerRuntime()).
+		For(&clusterpeeringv1alpha1.ClusterPeering{}).
+		Complete(ratelimiter.NewReconciler(name, r, o.GlobalRateLimiter))
+}
+
+// connector creates a KIND cluster provider for each reconcile.
+type connector struct {
+	kube client.Client
+}
+
+func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.ExternalClient, error) {
+	cr, ok := mg.(*clusterpeeringv1alpha1.ClusterPeering)
+	if !ok {
+		return nil, errors.New(errNotClusterPeering)
+	}
+
+	tracker := resource.NewLegacyProviderConfigUsageTracker(c.kube, &v1beta1.ProviderConfigUsage{})
+	if err := tracker.Track(ctx, cr); err != nil {
+		return nil, errors.Wrap(err, errTrackUsage)
+	}
+
+	return &external{provider: kindcluster.NewProvider(), kube: c.kube}, nil
+}
+
+// external implements managed.ExternalClient for cluster peerings.
+type external struct {
+	provider *kindcluster.Provider
+	kube     client.Client
+}
+
+// member is a peered cluster.
+type member struct {
+	// name is the name of the Cluster.
+	name     string
+	pods     []netip.Prefix
+	services []netip.Prefix
+	nodes    []memberNode
+}
+
+// memberNode is a node of a peered cluster.
+type memberNode struct {
+	node         nodes.Node
+	controlPlane bool
+	ipv4         netip.Addr
+	ipv6         netip.Addr
+	podCIDRs     []netip.Prefix
+}
+
+// Observe checks that every node has the routes to the other clusters, and
+// whether each cluster reaches the others.
+func (e *external) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
+	cr, ok := mg.(*clusterpeeringv1alpha1.ClusterPeering)
+	if !ok {
+		return managed.ExternalObservation{}, errors.New(errNotClusterPeering)
+	}
+
+	// The peered Clusters may already be gone, so look for installed routes
+	// on every KIND cluster.
+	if meta.WasDeleted(cr) {
+		installed, err := e.installed(cr, nil)
+		if err != nil {
+			return managed.ExternalObservation{}, err
+		}
+		return managed.ExternalObservation{ResourceExists: len(installed) > 0}, nil
+	}
+
+	members, network, err := e.resolve(ctx, cr)
+	if err != nil {
+		return managed.ExternalObservation{}, err
+	}
+	cr.Status.AtProvider.Network = network
+
+	exists := false
+	upToDate := true
+	for _, m := range members {
+		want := routes(m, members)
+		for _, n := range m.nodes {
+			if kindnode.HasAnyRoutes(n.node, cr.GetName()) {
+				exists = true
+			}
+			if !kindnode.HasRoutes(n.node, want) {
+				upToDate = false
+			}
+		}
+	}
+
+	links := make([]clusterpeeringv1alpha1.LinkObservation, 0, len(members)*(len(members)-1))
+	allReachable := true
+	for _, from := range members {
+		for _, to := range members {
+			if from.name == to.name {
+				continue
+			}
+			l := clusterpeeringv1alpha1.LinkObservation{From: from.name, To: to.name}
+			switch addr, ok := kubeDNS(to.services); {
+			case !upToDate:
+				l.Message = "routes are not installed"
+			case !ok:
+				l.Message = "no service subnet"
+			default:
+				l.Reachable = kindnode.CanReach(from.nodes[0].node, netip.AddrPortFrom(addr, dnsPort))
+				if !l.Reachable {
+					l.Message = fmt.Sprintf("cannot connect to kube-dns at %s", netip.AddrPortFrom(addr, dnsPort))
+				}
+			}
+			allReachable = allReachable && l.Reachable
+			links = append(links, l)
+		}
+	}
+	cr.Status.AtProvider.Links = links
+
+	if allReachable {
+		cr.SetConditions(xpv1.Available())
+	} else {
+		cr.SetConditions(xpv1.Unavailable())
+	}
+
+	return managed.ExternalObservation{
+		ResourceExists:   exists,
+		ResourceUpToDate: upToDate,
+	}, nil
+}
+
+// Create installs the routes.
+func (e *external) Create(ctx context.Context, mg resource.Managed) (managed.ExternalCreation, error) {
+	cr, ok := mg.(*clusterpeeringv1alpha1.ClusterPeering)
+	if !ok {
+		return managed.ExternalCreation{}, errors.New(errNotClusterPeering)
+	}
+
+	cr.SetConditions(xpv1.Creating())
+
+	return managed.ExternalCreation{}, e.install(ctx, cr)
+}
+
+// Update reinstalls the routes, for example after a node restarted or a
+// cluster joined or left the peering.
+func (e *external) Update(ctx context.Context, mg resource.Managed) (managed.ExternalUpdate, error) {
+	cr, ok := mg.(*clusterpeeringv1alpha1.ClusterPeering)
+	if !ok {
+		return managed.ExternalUpdate{}, errors.New(errNotClusterPeering)
+	}
+
+	return managed.ExternalUpdate{}, e.install(ctx, cr)
+}
+
+// Delete removes the routes from every node they were installed on.
+func (e *external) Delete(_ context.Context, mg resource.Managed) (managed.ExternalDelete, error) {
+	cr, ok := mg.(*clusterpeeringv1alpha1.ClusterPeering)
+	if !ok {
+		return managed.ExternalDelete{}, errors.New(errNotClusterPeering)
+	}
+
+	cr.SetConditions(xpv1.Deleting())
+
+	installed, err := e.installed(cr, nil)
+	if err != nil {
+		return managed.ExternalDelete{}, err
+	}
+	for _, n := range installed {
+		if err := kindnode.RemoveRoutes(n, cr.GetName()); err != nil {
+			return managed.ExternalDelete{}, errors.Wrap(err, errRemoveRoutes)
+		}
+	}
+	return managed.ExternalDelete{}, nil
+}
+
+// Disconnect is a no-op because the KIND provider uses the local Docker daemon
+// and holds no persistent connection that needs to be cleaned up.
+func (e *external) Disconnect(_ context.Context) error {
+	return nil
+}
+
+// install installs the routes on every node of the peered clusters, and
+// removes them from clusters that left the peering.
+func (e *external) install(ctx context.Context, cr *clusterpeeringv1alpha1.ClusterPeering) error {
+	members, _, err := e.resolve(ctx, cr)
+	if err != nil {
+		return err
+	}
+
+	peered := map[string]bool{}
+	for _, m := range members {
+		want := routes(m, members)
+		for _, n := range m.nodes {
+			peered[n.node.String()] = true
+			if err := kindnode.InstallRoutes(n.node, cr.GetName(), want); err != nil {
+				return errors.Wrap(err, errInstallRoutes)
+			}
+		}
+	}
+
+	stale, err := e.installed(cr, peered)
+	if err != nil {
+		return err
+	}
+	for _, n := range stale {
+		if err := kindnode.RemoveRoutes(n, cr.GetName()); err != nil {
+			return errors.Wrap(err, errRemoveRoutes)
+		}
+	}
+	return nil
+}
+
+// installed returns the nodes of any KIND cluster that have routes installed
+// for the peering, except those in skip.
+func (e *external) installed(cr *clusterpeeringv1alpha1.ClusterPeering, skip map[string]bool) ([]nodes.Node, error) {
+	clusters, err := e.provider.List()
+	if err != nil {
+		return nil, errors.Wrap(err, errListClusters)
+	}
+	var out []nodes.Node
+	for _, c := range clusters {
+		all, err := e.provider.ListNodes(c)
+		if err != nil {
+			return nil, errors.Wrapf(err, errGetNodes, c)
+		}
+		for _, n := range all {
+			if !skip[n.String()] && kindnode.HasAnyRoutes(n, cr.GetName()) {
+				out = append(out, n)
+			}
+		}
+	}
+	return out, nil
+}
+
+// resolve looks up the peered clusters and the Docker network their nodes
+// share, and checks that their subnets do not overlap.
+func (e *external) resolve(ctx context.Context, cr *clusterpeeringv1alpha1.ClusterPeering) ([]member, string, error) {
+	members := make([]member, 0, len(cr.Spec.ForProvider.ClusterRefs))
+	networks := map[string]map[string]docker.Container{}
+	total := 0
+
+	for _, ref := range cr.Spec.ForProvider.ClusterRefs {
+		c := &clusterv1alpha1.Cluster{}
+		if err := e.kube.Get(ctx, types.NamespacedName{Name: ref.Name}, c); err != nil {
+			return nil, "", errors.Wrapf(err, errGetCluster, ref.Name)
+		}
+		m := member{name: ref.Name}
+		var err error
+		if m.pods, m.services, err = subnets(c); err != nil {
+			return nil, "", err
+		}
+
+		clusterName := meta.GetExternalName(c)
+		if clusterName == "" {
+			clusterName = c.GetName()
+		}
+		all, err := e.provider.ListNodes(clusterName)
+		if err != nil {
+			return nil, "", errors.Wrapf(err, errGetNodes, clusterName)
+		}
+		for _, n := range all {
+			role, err := n.Role()
+			if err != nil || role == constants.ExternalLoadBalancerNodeRoleValue {
+				continue
+			}
+			ctr, err := docker.InspectContainer(ctx, n.String())
+			if err != nil {
+				return nil, "", errors.Wrapf(err, errInspectNode, n.String())
+			}
+			if ctr == nil {
+				// The node was removed after it was listed.
+				return nil, "", errors.Errorf(errInspectNode, n.String())
+			}
+			for net := range ctr.NetworkSettings.Networks {
+				if networks[net] == nil {
+					networks[net] = map[string]docker.Container{}
+				}
+				networks[net][n.String()] = *ctr
+			}
+			total++
+			m.nodes = append(m.nodes, memberNode{node: n, controlPlane: role == constants.ControlPlaneNodeRoleValue})
+		}
+		if len(m.nodes) == 0 {
+			return nil, "", errors.Errorf(errNoNodes, clusterName)
+		}
+		if err := podCIDRs(&m, clusterName); err != nil {
+			return nil, "", err
+		}
+		members = append(members, m)
+	}
+
+	if err := checkOverlap(members); err != nil {
+		return nil, "", err
+	}
+
+	shared := make([]string, 0, len(networks))
+	for net, ctrs := range networks {
+		if len(ctrs) == total {
+			shared = append(shared, net)
+		}
+	}
+	if len(shared) == 0 {
+		return nil, "", errors.New(errNoSharedNetwork)
+	}
+	sort.Strings(shared)
+	network := shared[0]
+
+	for i := range members {
+		for j := range members[i].nodes {
+			n := &members[i].nodes[j]
+			settings := networks[network][n.node.String()].NetworkSettings.Networks[network]
+			n.ipv4, _ = netip.ParseAddr(settings.IPAddress)
+			n.ipv6, _ = netip.ParseAddr(settings.GlobalIPv6Address)
+		}
+	}
+	return members, network, nil
+}
+
+// subnets returns the pod and service subnets of the Cluster, applying the
+// KIND defaults.
+func subnets(c *clusterv1alpha1.Cluster) ([]netip.Prefix, []netip.Prefix, error) {
+	cfg := &v1alpha4.Cluster{}
+	if n := c.Spec.ForProvider.Networking; n != nil {
+		if n.IPFamily != nil {
+			cfg.Networking.IPFamily = v1alpha4.ClusterIPFamily(*n.IPFamily)
+		}
+		if n.PodSubnet != nil {
+			cfg.Networking.PodSubnet = *n.PodSubnet
+		}
+		if n.ServiceSubnet != nil {
+			cfg.Networking.ServiceSubnet = *n.ServiceSubnet
+		}
+	}
+	v1alpha4.SetDefaultsCluster(cfg)
+
+	pods, err := parsePrefixes(cfg.Networking.PodSubnet, c.GetName())
+	if err != nil {
+		return nil, nil, err
+	}
+	services, err := parsePrefixes(cfg.Networking.ServiceSubnet, c.GetName())
+	if err != nil {
+		return nil, nil, err
+	}
+	return pods, services, nil
+}
+
+// parsePrefixes parses a comma separated list of subnets.
+func parsePrefixes(s, cluster string) ([]netip.Prefix, error) {
+	var out []netip.Prefix
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		p, err := netip.ParsePrefix(part)
+		if err != nil {
+			return nil, errors.Wrapf(err, errParseSubnet, part, cluster)
+		}
+		out = append(out, p.Masked())
+	}
+	return out, nil
+}
+
+// checkOverlap returns an error if a pod or service subnet of one cluster
+// overlaps one of another cluster.
+func checkOverlap(members []member) error {
+	type subnet struct {
+		kind   string
+		prefix netip.Prefix
+	}
+	all := func(m member) []subnet {
+		out := make([]subnet, 0, len(m.pods)+len(m.services))
+		for _, p := range m.pods {
+			out = append(out, subnet{"pod subnet " + p.String(), p})
+		}
+		for _, p := range m.services {
+			out = append(out, subnet{"service subnet " + p.String(), p})
+		}
+		return out
+	}
+	for i := range members {
+		for j := i + 1; j < len(members); j++ {
+			for _, a := range all(members[i]) {
+				for _, b := range all(members[j]) {
+					if a.prefix.Overlaps(b.prefix) {
+						return errors.Errorf(errOverlap, a.kind, members[i].name, b.kind, members[j].name)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// podCIDRs reads the pod CIDR allocated to each node from the cluster API
+// server, using kubectl on a control plane node.
+func podCIDRs(m *member, clusterName string) error {
+	var cp nodes.Node
+	for _, n := range m.nodes {
+		if n.controlPlane {
+			cp = n.node
+			break
+		}
+	}
+	if cp == nil {
+		return errors.Errorf(errGetPodCIDRs, clusterName)
+	}
+
+	var out bytes.Buffer
+	cmd := cp.Command("kubectl", "--kubeconfig=/etc/kubernetes/admin.conf", "get", "nodes",
+		"--output=jsonpath={range .items[*]}{.metadata.name}{\" \"}{.spec.podCIDRs[*]}{\"\\n\"}{end}")
+	if err := cmd.SetStdout(&out).Run(); err != nil {
+		return errors.Wrapf(err, errGetPodCIDRs, clusterName)
+	}
+
+	cidrs := map[string][]netip.Prefix{}
+	for _, line := range strings.Split(out.String(), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		for _, f := range fields[1:] {
+			if p, err := netip.ParsePrefix(f); err == nil {
+				cidrs[fields[0]] = append(cidrs[fields[0]], p.Masked())
+			}
+		}
+	}
+	for i := range m.nodes {
+		m.nodes[i].podCIDRs = cidrs[m.nodes[i].node.String()]
+	}
+	return nil
+}
+
+// routes returns the routes a node of m needs to reach the pod and service
+// subnets of the other members. Pod CIDRs are routed to the node they are
+// allocated to, service subnets to a control plane node, whose kube-proxy
+// forwards the traffic.
+func routes(m member, members []member) []kindnode.Route {
+	var out []kindnode.Route
+	for _, o := range members {
+		if o.name == m.name {
+			continue
+		}
+		for _, n := range o.nodes {
+			for _, cidr := range n.podCIDRs {
+				if via := n.addr(cidr); via.IsValid() {
+					out = append(out, kindnode.Route{Dest: cidr, Via: via})
+				}
+			}
+		}
+		for _, svc := range o.services {
+			for _, n := range o.nodes {
+				if via := n.addr(svc); n.controlPlane && via.IsValid() {
+					out = append(out, kindnode.Route{Dest: svc, Via: via})
+					break
+				}
+			}
+		}
+	}
+	return out
+}
+
+// addr returns the node address of the same family as the prefix.
+func (n memberNode) addr(p netip.Prefix) netip.Addr {
+	if p.Addr().Is4() {
+		return n.ipv4
+	}
+	return n.ipv6
+}
+
+// kubeDNS returns the kube-dns Service address of the first service subnet,
+// which kubeadm places at the tenth address.
+func kubeDNS(services []netip.Prefix) (netip.Addr, bool) {
+	if len(services) == 0 {
+		return netip.Addr{}, false
+	}
+	a := services[0].Addr()
+	for range 10 {
+		a = a.Next()
+	}
+	return a, true
+}
@@ -0,0 +1,228 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+package clusterpeering
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/test"
+
+	"github.com/humoflife/provider-kind/internal/kindnode"
+)
+
+// equateNetIP compares netip values, whose fields are unexported.
+var equateNetIP = cmp.Options{
+	cmp.Comparer(func(a, b netip.Addr) bool { return a == b }),
+	cmp.Comparer(func(a, b netip.Prefix) bool { return a == b }),
+}
+
+func prefixes(s ...string) []netip.Prefix {
+	out := make([]netip.Prefix, 0, len(s))
+	for _, p := range s {
+		out = append(out, netip.MustParsePrefix(p))
+	}
+	return out
+}
+
+func TestParsePrefixes(t *testing.T) {
+	type want struct {
+		prefixes []netip.Prefix
+		err      error
+	}
+
+	cases := map[string]struct {
+		reason string
+		s      string
+		want   want
+	}{
+		"Empty": {
+			reason: "An empty list should have no prefixes.",
+			s:      "",
+		},
+		"DualStack": {
+			reason: "Comma separated prefixes should be parsed and masked, ignoring spaces.",
+			s:      "10.244.1.0/16, fd00:10:244::/56",
+			want:   want{prefixes: prefixes("10.244.0.0/16", "fd00:10:244::/56")},
+		},
+		"Invalid": {
+			reason: "An invalid prefix should return an error naming it and the cluster.",
+			s:      "10.244.0.0",
+			want: want{err: errors.Wrapf(errors.New(`netip.ParsePrefix("10.244.0.0"): no '/'`),
+				errParseSubnet, "10.244.0.0", "a")},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := parsePrefixes(tc.s, "a")
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nparsePrefixes(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.prefixes, got, equateNetIP); diff != "" {
+				t.Errorf("\n%s\nparsePrefixes(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestCheckOverlap(t *testing.T) {
+	cases := map[string]struct {
+		reason  string
+		members []member
+		want    error
+	}{
+		"Disjoint": {
+			reason: "Clusters with disjoint subnets should not overlap.",
+			members: []member{
+				{name: "a", pods: prefixes("10.244.0.0/16"), services: prefixes("10.96.0.0/16")},
+				{name: "b", pods: prefixes("10.245.0.0/16"), services: prefixes("10.97.0.0/16")},
+			},
+		},
+		"SameCluster": {
+			reason: "Subnets of the same cluster should not be compared with each other.",
+			members: []member{
+				{name: "a", pods: prefixes("10.0.0.0/8"), services: prefixes("10.96.0.0/16")},
+			},
+		},
+		"PodSubnets": {
+			reason: "Overlapping pod subnets should be reported.",
+			members: []member{
+				{name: "a", pods: prefixes("10.244.0.0/16")},
+				{name: "b", pods: prefixes("10.244.128.0/17")},
+			},
+			want: errors.Errorf(errOverlap, "pod subnet 10.244.0.0/16", "a", "pod subnet 10.244.128.0/17", "b"),
+		},
+		"PodAndServiceSubnets": {
+			reason: "A pod subnet overlapping a service subnet of another cluster should be reported.",
+			members: []member{
+				{name: "a", pods: prefixes("10.244.0.0/16"), services: prefixes("10.96.0.0/16")},
+				{name: "b", pods: prefixes("10.96.0.0/12")},
+			},
+			want: errors.Errorf(errOverlap, "service subnet 10.96.0.0/16", "a", "pod subnet 10.96.0.0/12", "b"),
+		},
+		"DifferentFamilies": {
+			reason: "IPv4 and IPv6 subnets should never overlap.",
+			members: []member{
+				{name: "a", pods: prefixes("10.244.0.0/16")},
+				{name: "b", pods: prefixes("fd00:10:244::/56")},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := checkOverlap(tc.members)
+			if diff := cmp.Diff(tc.want, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ncheckOverlap(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	a := member{
+		name:     "a",
+		services: prefixes("10.96.0.0/16"),
+		nodes: []memberNode{
+			{controlPlane: true, ipv4: netip.MustParseAddr("172.18.0.2"), podCIDRs: prefixes("10.244.0.0/24")},
+		},
+	}
+	b := member{
+		name:     "b",
+		services: prefixes("10.97.0.0/16", "fd00:10:97::/112"),
+		nodes: []memberNode{
+			{ipv4: netip.MustParseAddr("172.18.0.3"), podCIDRs: prefixes("10.245.1.0/24")},
+			{
+				controlPlane: true,
+				ipv4:         netip.MustParseAddr("172.18.0.4"),
+				ipv6:         netip.MustParseAddr("fc00:f853:ccd:e793::4"),
+				podCIDRs:     prefixes("10.245.0.0/24", "fd00:10:245::/64"),
+			},
+		},
+	}
+
+	cases := map[string]struct {
+		reason  string
+		m       member
+		members []member
+		want    []kindnode.Route
+	}{
+		"Alone": {
+			reason:  "A cluster should need no routes to itself.",
+			m:       a,
+			members: []member{a},
+		},
+		"ToOtherCluster": {
+			reason:  "Pod CIDRs should be routed to their node, and service subnets to a control plane node, of the same family.",
+			m:       a,
+			members: []member{a, b},
+			want: []kindnode.Route{
+				{Dest: netip.MustParsePrefix("10.245.1.0/24"), Via: netip.MustParseAddr("172.18.0.3")},
+				{Dest: netip.MustParsePrefix("10.245.0.0/24"), Via: netip.MustParseAddr("172.18.0.4")},
+				{Dest: netip.MustParsePrefix("fd00:10:245::/64"), Via: netip.MustParseAddr("fc00:f853:ccd:e793::4")},
+				{Dest: netip.MustParsePrefix("10.97.0.0/16"), Via: netip.MustParseAddr("172.18.0.4")},
+				{Dest: netip.MustParsePrefix("fd00:10:97::/112"), Via: netip.MustParseAddr("fc00:f853:ccd:e793::4")},
+			},
+		},
+		"NoAddressOfFamily": {
+			reason:  "Subnets of a family the nodes have no address of should not be routed.",
+			m:       b,
+			members: []member{a, b, {name: "c", pods: prefixes("fd00::/56"), nodes: []memberNode{{podCIDRs: prefixes("fd00::/64")}}}},
+			want: []kindnode.Route{
+				{Dest: netip.MustParsePrefix("10.244.0.0/24"), Via: netip.MustParseAddr("172.18.0.2")},
+				{Dest: netip.MustParsePrefix("10.96.0.0/16"), Via: netip.MustParseAddr("172.18.0.2")},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := routes(tc.m, tc.members)
+			if diff := cmp.Diff(tc.want, got, equateNetIP); diff != "" {
+				t.Errorf("\n%s\nroutes(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestKubeDNS(t *testing.T) {
+	type want struct {
+		addr netip.Addr
+		ok   bool
+	}
+
+	cases := map[string]struct {
+		reason   string
+		services []netip.Prefix
+		want     want
+	}{
+		"NoServiceSubnet": {
+			reason: "Without a service subnet there should be no kube-dns address.",
+		},
+		"KINDDefault": {
+			reason:   "kube-dns should be the tenth address of the KIND default service subnet.",
+			services: prefixes("10.96.0.0/16"),
+			want:     want{addr: netip.MustParseAddr("10.96.0.10"), ok: true},
+		},
+		"FirstSubnet": {
+			reason:   "kube-dns should be in the first service subnet of a dual-stack cluster.",
+			services: prefixes("fd00:10:96::/112", "10.96.0.0/16"),
+			want:     want{addr: netip.MustParseAddr("fd00:10:96::a"), ok: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			addr, ok := kubeDNS(tc.services)
+			if diff := cmp.Diff(tc.want, want{addr: addr, ok: ok}, cmp.AllowUnexported(want{}), equateNetIP); diff != "" {
+				t.Errorf("\n%s\nkubeDNS(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,389 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+// Package loadedimage implements the Crossplane managed reconciler for images
+// loaded into KIND cluster nodes.
+package loadedimage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/pkg/errors"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	kindcluster "sigs.k8s.io/kind/pkg/cluster"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/cluster/nodeutils"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/v2/apis/common/v1"
+	xpcontroller "github.com/crossplane/crossplane-runtime/v2/pkg/controller"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/event"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/ratelimiter"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+
+	loadedimagev1alpha1 "github.com/humoflife/provider-kind/apis/loadedimage/v1alpha1"
+	"github.com/humoflife/provider-kind/apis/v1beta1"
+	"github.com/humoflife/provider-kind/internal/docker"
+	"github.com/humoflife/provider-kind/internal/kindnode"
+	"github.com/humoflife/provider-kind/internal/sources"
+)
+
+const (
+	errNotLoadedImage = "managed resource is not a LoadedImage custom resource"
+	errTrackUsage     = "cannot track ProviderConfig usage"
+	errGetNodes       = "cannot list KIND cluster nodes"
+	errNoNodes        = "no nodes of KIND cluster %q match the node selector"
+	errNoImages       = "at least one of images or archive must be set"
+	errInspectImage   = "cannot inspect image"
+	errImageNotFound  = "image %q not found in the host Docker daemon"
+	errSaveImages     = "cannot save images from the host Docker daemon"
+	errReadArchive    = "cannot read image archive"
+	errLoadImages     = "cannot load images into node %s"
+	errRemoveImage    = "cannot remove image %q from node %s"
+	errTempDir        = "cannot create temporary directory"
+)
+
+// Setup adds a controller that reconciles LoadedImage managed resources.
+func Setup(mgr ctrl.Manager, o xpcontroller.Options) error {
+	name := managed.ControllerName(loadedimagev1alpha1.LoadedImageGroupVersionKind.String())
+
+	reconcilerOpts := []managed.ReconcilerOption{
+		managed.WithExternalConnecter(&connector{kube: mgr.GetClient()}),
+		managed.WithLogger(o.Logger.WithValues("controller", name)),
+		managed.WithRecorder(event.NewAPIRecorder(mgr.GetEventRecorderFor(name))),
+		managed.WithPollInterval(o.PollInterval),
+	}
+
+	r := managed.NewReconciler(mgr,
+		resource.ManagedKind(loadedimagev1alpha1.LoadedImageGroupVersionKind),
+		reconcilerOpts...,
+	)
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(name).
+		WithOptions(o.ForControllerRuntime()).
+		For(&loadedimagev1alpha1.LoadedImage{}).
+		Complete(ratelimiter.NewReconciler(name, r, o.GlobalRateLimiter))
+}
+
+// connector creates a KIND cluster provider for each reconcile.
+type connector struct {
+	kube client.Client
+}
+
+func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.ExternalClient, error) {
+	cr, ok := mg.(*loadedimagev1alpha1.LoadedImage)
+	if !ok {
+		return nil, errors.New(errNotLoadedImage)
+	}
+
+	tracker := resource.NewLegacyProviderConfigUsageTracker(c.kube, &v1beta1.ProviderConfigUsage{})
+	if err := tracker.Track(ctx, cr); err != nil {
+		return nil, errors.Wrap(err, errTrackUsage)
+	}
+
+	return &external{provider: kindcluster.NewProvider(), kube: c.kube}, nil
+}
+
+// external implements managed.ExternalClient for loaded images.
+type external struct {
+	provider *kindcluster.Provider
+	kube     client.Client
+}
+
+// Observe checks that every selected node has every image.
+func (e *external) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
+	cr, ok := mg.(*loadedimagev1alpha1.LoadedImage)
+	if !ok {
+		return managed.ExternalObservation{}, errors.New(errNotLoadedImage)
+	}
+
+	clusterName, err := e.clusterName(ctx, cr)
+	if err != nil {
+		return managed.ExternalObservation{}, err
+	}
+	cr.Status.AtProvider.ClusterName = clusterName
+
+	targets, err := e.nodes(cr, clusterName)
+	if err != nil {
+		return managed.ExternalObservation{}, err
+	}
+
+	want, current, err := desiredImages(ctx, cr)
+	if err != nil {
+		return managed.ExternalObservation{}, err
+	}
+
+	loaded := []string{}
+	exists := false
+	for _, n := range targets {
+		have := 0
+		for _, img := range want {
+			if id, err := nodeutils.ImageID(n, img.Ref); err == nil && id == img.ID {
+				have++
+			}
+		}
+		if have > 0 {
+			exists = true
+		}
+		if have == len(want) {
+			loaded = append(loaded, n.String())
+		}
+	}
+	cr.Status.AtProvider.Nodes = loaded
+
+	upToDate := current && len(loaded) == len(targets)
+	if upToDate {
+		cr.SetConditions(xpv1.Available())
+	} else {
+		cr.SetConditions(xpv1.Unavailable())
+	}
+
+	return managed.ExternalObservation{
+		ResourceExists:   exists,
+		ResourceUpToDate: upToDate,
+	}, nil
+}
+
+// Create loads the images into the selected nodes.
+func (e *external) Create(ctx context.Context, mg resource.Managed) (managed.ExternalCreation, error) {
+	cr, ok := mg.(*loadedimagev1alpha1.LoadedImage)
+	if !ok {
+		return managed.ExternalCreation{}, errors.New(errNotLoadedImage)
+	}
+
+	cr.SetConditions(xpv1.Creating())
+
+	return managed.ExternalCreation{}, e.load(ctx, cr)
+}
+
+// Update loads the images into selected nodes that lack them, and reloads
+// images that changed in the host Docker daemon.
+func (e *external) Update(ctx context.Context, mg resource.Managed) (managed.ExternalUpdate, error) {
+	cr, ok := mg.(*loadedimagev1alpha1.LoadedImage)
+	if !ok {
+		return managed.ExternalUpdate{}, errors.New(errNotLoadedImage)
+	}
+
+	return managed.ExternalUpdate{}, e.load(ctx, cr)
+}
+
+// Delete removes the loaded images from the selected nodes. Nothing is
+// removed if the cluster no longer exists.
+func (e *external) Delete(ctx context.Context, mg resource.Managed) (managed.ExternalDelete, error) {
+	cr, ok := mg.(*loadedimagev1alpha1.LoadedImage)
+	if !ok {
+		return managed.ExternalDelete{}, errors.New(errNotLoadedImage)
+	}
+
+	cr.SetConditions(xpv1.Deleting())
+
+	clusterName, err := e.clusterName(ctx, cr)
+	if kerrors.IsNotFound(errors.Cause(err)) {
+		return managed.ExternalDelete{}, nil
+	}
+	if err != nil {
+		return managed.ExternalDelete{}, err
+	}
+
+	all, err := e.provider.ListNodes(clusterName)
+	if err != nil {
+		return managed.ExternalDelete{}, errors.Wrap(err, errGetNodes)
+	}
+
+	for _, n := range kindnode.Select(all, selector(cr)) {
+		for _, img := range cr.Status.AtProvider.Images {
+			if id, err := nodeutils.ImageID(n, img.Ref); err != nil || id != img.ID {
+				continue
+			}
+			if err := n.Command("crictl", "rmi", img.Ref).Run(); err != nil {
+				return managed.ExternalDelete{}, errors.Wrapf(err, errRemoveImage, img.Ref, n.String())
+			}
+		}
+	}
+	return managed.ExternalDelete{}, nil
+}
+
+// Disconnect is a no-op because the KIND provider uses the local Docker daemon
+// and holds no persistent connection that needs to be cleaned up.
+func (e *external) Disconnect(_ context.Context) error {
+	return nil
+}
+
+// load writes the images to an archive, unless an archive was supplied, and
+// imports it into every selected node that lacks any of them.
+func (e *external) load(ctx context.Context, cr *loadedimagev1alpha1.LoadedImage) error {
+	p := cr.Spec.ForProvider
+
+	clusterName, err := e.clusterName(ctx, cr)
+	if err != nil {
+		return err
+	}
+	targets, err := e.nodes(cr, clusterName)
+	if err != nil {
+		return err
+	}
+
+	var images []loadedimagev1alpha1.ImageObservation
+	var archives []string
+
+	if len(p.Images) > 0 {
+		dir, err := os.MkdirTemp("", "provider-kind-images-")
+		if err != nil {
+			return errors.Wrap(err, errTempDir)
+		}
+		defer os.RemoveAll(dir)
+
+		for i, ref := range p.Images {
+			hostID, err := docker.ImageID(ctx, ref)
+			if err != nil {
+				return errors.Wrap(err, errInspectImage)
+			}
+			if hostID == "" {
+				return errors.Errorf(errImageNotFound, ref)
+			}
+			// Save each image on its own so the tags in the archive can be
+			// attributed to the image they were loaded from.
+			file := filepath.Join(dir, "image-"+strconv.Itoa(i)+".tar")
+			if err := docker.SaveImages(ctx, file, ref); err != nil {
+				return errors.Wrap(err, errSaveImages)
+			}
+			saved, err := docker.ArchiveImages(file)
+			if err != nil {
+				return errors.Wrap(err, errReadArchive)
+			}
+			images = append(images, observations(saved, ref, hostID)...)
+			archives = append(archives, file)
+		}
+	}
+
+	if p.Archive != nil {
+		saved, err := docker.ArchiveImages(*p.Archive)
+		if err != nil {
+			return errors.Wrap(err, errReadArchive)
+		}
+		images = append(images, observations(saved, *p.Archive, "")...)
+		archives = append(archives, *p.Archive)
+	}
+
+	if len(archives) == 0 {
+		return errors.New(errNoImages)
+	}
+
+	for _, n := range targets {
+		if err := loadInto(n, images, archives); err != nil {
+			return err
+		}
+	}
+
+	cr.Status.AtProvider.Images = images
+	return nil
+}
+
+// loadInto imports the archives into the node unless it already has every
+// image.
+func loadInto(n nodes.Node, images []loadedimagev1alpha1.ImageObservation, archives []string) error {
+	missing := false
+	for _, img := range images {
+		if id, err := nodeutils.ImageID(n, img.Ref); err != nil || id != img.ID {
+			missing = true
+			break
+		}
+	}
+	if !missing {
+		return nil
+	}
+
+	for _, file := range archives {
+		f, err := os.Open(file)
+		if err != nil {
+			return errors.Wrapf(err, errLoadImages, n.String())
+		}
+		err = nodeutils.LoadImageArchive(n, f)
+		_ = f.Close()
+		if err != nil {
+			return errors.Wrapf(err, errLoadImages, n.String())
+		}
+	}
+	return nil
+}
+
+// clusterName returns the KIND cluster name of the referenced Cluster.
+func (e *external) clusterName(ctx context.Context, cr *loadedimagev1alpha1.LoadedImage) (string, error) {
+	return sources.Resolver{Client: e.kube}.ClusterName(ctx, cr.Spec.ForProvider.ClusterRef.Name)
+}
+
+// nodes returns the selected nodes of the cluster.
+func (e *external) nodes(cr *loadedimagev1alpha1.LoadedImage, clusterName string) ([]nodes.Node, error) {
+	all, err := e.provider.ListNodes(clusterName)
+	if err != nil {
+		return nil, errors.Wrap(err, errGetNodes)
+	}
+	targets := kindnode.Select(all, selector(cr))
+	if len(targets) == 0 {
+		return nil, errors.Errorf(errNoNodes, clusterName)
+	}
+	return targets, nil
+}
+
+// desiredImages returns the images that should be present on the nodes. It
+// also reports whether those are still the images in the host Docker
+// daemon; if not, the images must be saved again to learn their new IDs.
+func desiredImages(ctx context.Context, cr *loadedimagev1alpha1.LoadedImage) ([]loadedimagev1alpha1.ImageObservation, bool, error) {
+	p := cr.Spec.ForProvider
+	var want []loadedimagev1alpha1.ImageObservation
+	current := true
+
+	for _, ref := range p.Images {
+		hostID, err := docker.ImageID(ctx, ref)
+		if err != nil {
+			return nil, false, errors.Wrap(err, errInspectImage)
+		}
+		found := false
+		for _, img := range cr.Status.AtProvider.Images {
+			if img.Source == ref && img.SourceID == hostID {
+				want = append(want, img)
+				found = true
+			}
+		}
+		if !found {
+			current = false
+		}
+	}
+
+	if p.Archive != nil {
+		saved, err := docker.ArchiveImages(*p.Archive)
+		if err != nil {
+			return nil, false, errors.Wrap(err, errReadArchive)
+		}
+		want = append(want, observations(saved, *p.Archive, "")...)
+	}
+
+	return want, current, nil
+}
+
+// observations converts the images of an archive into status entries,
+// sorted by reference.
+func observations(images map[string]string, source, sourceID string) []loadedimagev1alpha1.ImageObservation {
+	out := make([]loadedimagev1alpha1.ImageObservation, 0, len(images))
+	for ref, id := range images {
+		out = append(out, loadedimagev1alpha1.ImageObservation{Ref: ref, ID: id, Source: source, SourceID: sourceID})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Ref < out[j].Ref })
+	return out
+}
+
+// selector returns the node selector of the LoadedImage.
+func selector(cr *loadedimagev1alpha1.LoadedImage) kindnode.Selector {
+	if sel := cr.Spec.ForProvider.NodeSelector; sel != nil {
+		return kindnode.Selector{Roles: sel.Roles, Names: sel.Names}
+	}
+	return kindnode.Selector{}
+}
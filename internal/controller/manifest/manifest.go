@@ -0,0 +1,306 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+// Package manifest implements the Crossplane managed reconciler for objects
+// kept in sync in KIND clusters.
+package manifest
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	kindcluster "sigs.k8s.io/kind/pkg/cluster"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/v2/apis/common/v1"
+	xpcontroller "github.com/crossplane/crossplane-runtime/v2/pkg/controller"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/event"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/ratelimiter"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+
+	manifestv1alpha1 "github.com/humoflife/provider-kind/apis/manifest/v1alpha1"
+	"github.com/humoflife/provider-kind/apis/v1beta1"
+	"github.com/humoflife/provider-kind/internal/kindnode"
+	"github.com/humoflife/provider-kind/internal/sources"
+)
+
+const (
+	errNotManifest    = "managed resource is not a Manifest custom resource"
+	errTrackUsage     = "cannot track ProviderConfig usage"
+	errGetNodes       = "cannot list KIND cluster nodes"
+	errParseObject    = "cannot parse object %d"
+	errObjectIdentity = "object %d must have an apiVersion, kind, and metadata.name"
+	errMarshalObjects = "cannot marshal objects"
+	errDiffObjects    = "cannot diff objects"
+	errApplyObjects   = "cannot apply objects"
+	errPruneObjects   = "cannot delete objects removed from the Manifest"
+	errDeleteObjects  = "cannot delete objects"
+)
+
+// Setup adds a controller that reconciles Manifest managed resources.
+func Setup(mgr ctrl.Manager, o xpcontroller.Options) error {
+	name := managed.ControllerName(manifestv1alpha1.ManifestGroupVersionKind.String())
+
+	reconcilerOpts := []managed.ReconcilerOption{
+		managed.WithExternalConnecter(&connector{kube: mgr.GetClient()}),
+		managed.WithLogger(o.Logger.WithValues("controller", name)),
+		managed.WithRecorder(event.NewAPIRecorder(mgr.GetEventRecorderFor(name))),
+		managed.WithPollInterval(o.PollInterval),
+	}
+
+	r := managed.NewReconciler(mgr,
+		resource.ManagedKind(manifestv1alpha1.ManifestGroupVersionKind),
+		reconcilerOpts...,
+	)
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(name).
+		WithOptions(o.ForControllerRuntime()).
+		For(&manifestv1alpha1.Manifest{}).
+		Complete(ratelimiter.NewReconciler(name, r, o.GlobalRateLimiter))
+}
+
+// connector creates a KIND cluster provider for each reconcile.
+type connector struct {
+	kube client.Client
+}
+
+func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.ExternalClient, error) {
+	cr, ok := mg.(*manifestv1alpha1.Manifest)
+	if !ok {
+		return nil, errors.New(errNotManifest)
+	}
+
+	tracker := resource.NewLegacyProviderConfigUsageTracker(c.kube, &v1beta1.ProviderConfigUsage{})
+	if err := tracker.Track(ctx, cr); err != nil {
+		return nil, errors.Wrap(err, errTrackUsage)
+	}
+
+	return &external{provider: kindcluster.NewProvider(), kube: c.kube}, nil
+}
+
+// external implements managed.ExternalClient for Manifests.
+type external struct {
+	provider *kindcluster.Provider
+	kube     client.Client
+}
+
+// Observe checks whether the objects were applied, and whether they drifted
+// from the Manifest since.
+func (e *external) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
+	cr, ok := mg.(*manifestv1alpha1.Manifest)
+	if !ok {
+		return managed.ExternalObservation{}, errors.New(errNotManifest)
+	}
+
+	clusterName, err := e.clusterName(ctx, cr)
+	if err != nil {
+		return managed.ExternalObservation{}, err
+	}
+	cr.Status.AtProvider.ClusterName = clusterName
+
+	all, err := e.provider.ListNodes(clusterName)
+	if err != nil {
+		return managed.ExternalObservation{}, errors.Wrap(err, errGetNodes)
+	}
+	manifest, refs, err := objects(cr)
+	if err != nil {
+		return managed.ExternalObservation{}, err
+	}
+
+	// Objects are recorded once they were applied. Objects that exist but
+	// were not recorded, for example because the status was lost, are
+	// applied again to record them.
+	if len(cr.Status.AtProvider.Objects) == 0 {
+		return managed.ExternalObservation{ResourceExists: kindnode.ObjectsExist(all, manifest)}, nil
+	}
+	drifted, err := kindnode.ObjectsDrifted(all, manifest)
+	if err != nil {
+		return managed.ExternalObservation{}, errors.Wrap(err, errDiffObjects)
+	}
+	cr.Status.AtProvider.Drifted = drifted
+
+	upToDate := !drifted && len(removed(cr.Status.AtProvider.Objects, refs)) == 0
+	if upToDate {
+		cr.SetConditions(xpv1.Available())
+	} else {
+		cr.SetConditions(xpv1.Unavailable())
+	}
+
+	return managed.ExternalObservation{
+		ResourceExists:   true,
+		ResourceUpToDate: upToDate,
+	}, nil
+}
+
+// Create applies the objects.
+func (e *external) Create(ctx context.Context, mg resource.Managed) (managed.ExternalCreation, error) {
+	cr, ok := mg.(*manifestv1alpha1.Manifest)
+	if !ok {
+		return managed.ExternalCreation{}, errors.New(errNotManifest)
+	}
+
+	cr.SetConditions(xpv1.Creating())
+
+	return managed.ExternalCreation{}, e.apply(ctx, cr)
+}
+
+// Update reapplies the objects, and deletes the ones removed from the
+// Manifest.
+func (e *external) Update(ctx context.Context, mg resource.Managed) (managed.ExternalUpdate, error) {
+	cr, ok := mg.(*manifestv1alpha1.Manifest)
+	if !ok {
+		return managed.ExternalUpdate{}, errors.New(errNotManifest)
+	}
+
+	return managed.ExternalUpdate{}, e.apply(ctx, cr)
+}
+
+// Delete deletes the applied objects. Nothing is deleted if the cluster no
+// longer exists.
+func (e *external) Delete(ctx context.Context, mg resource.Managed) (managed.ExternalDelete, error) {
+	cr, ok := mg.(*manifestv1alpha1.Manifest)
+	if !ok {
+		return managed.ExternalDelete{}, errors.New(errNotManifest)
+	}
+
+	cr.SetConditions(xpv1.Deleting())
+
+	clusterName, err := e.clusterName(ctx, cr)
+	if kerrors.IsNotFound(errors.Cause(err)) {
+		return managed.ExternalDelete{}, nil
+	}
+	if err != nil {
+		return managed.ExternalDelete{}, err
+	}
+
+	all, err := e.provider.ListNodes(clusterName)
+	if err != nil {
+		return managed.ExternalDelete{}, errors.Wrap(err, errGetNodes)
+	}
+	if len(all) == 0 || len(cr.Status.AtProvider.Objects) == 0 {
+		return managed.ExternalDelete{}, nil
+	}
+
+	manifest, err := references(cr.Status.AtProvider.Objects)
+	if err != nil {
+		return managed.ExternalDelete{}, err
+	}
+	if err := kindnode.DeleteObjects(all, manifest); err != nil {
+		return managed.ExternalDelete{}, errors.Wrap(err, errDeleteObjects)
+	}
+	return managed.ExternalDelete{}, nil
+}
+
+// Disconnect is a no-op because the KIND provider uses the local Docker daemon
+// and holds no persistent connection that needs to be cleaned up.
+func (e *external) Disconnect(_ context.Context) error {
+	return nil
+}
+
+// apply server-side applies the objects, deletes the ones that were removed
+// from the Manifest, and records the applied ones.
+func (e *external) apply(ctx context.Context, cr *manifestv1alpha1.Manifest) error {
+	clusterName, err := e.clusterName(ctx, cr)
+	if err != nil {
+		return err
+	}
+	all, err := e.provider.ListNodes(clusterName)
+	if err != nil {
+		return errors.Wrap(err, errGetNodes)
+	}
+
+	manifest, refs, err := objects(cr)
+	if err != nil {
+		return err
+	}
+	if err := kindnode.ApplyObjects(all, manifest); err != nil {
+		return errors.Wrap(err, errApplyObjects)
+	}
+
+	if stale := removed(cr.Status.AtProvider.Objects, refs); len(stale) > 0 {
+		manifest, err := references(stale)
+		if err != nil {
+			return err
+		}
+		if err := kindnode.DeleteObjects(all, manifest); err != nil {
+			return errors.Wrap(err, errPruneObjects)
+		}
+	}
+
+	cr.Status.AtProvider.Objects = refs
+	cr.Status.AtProvider.Drifted = false
+	return nil
+}
+
+// clusterName returns the KIND cluster name of the referenced Cluster.
+func (e *external) clusterName(ctx context.Context, cr *manifestv1alpha1.Manifest) (string, error) {
+	return sources.Resolver{Client: e.kube}.ClusterName(ctx, cr.Spec.ForProvider.ClusterRef.Name)
+}
+
+// objects returns the objects of the Manifest as a List manifest, and their
+// references.
+func objects(cr *manifestv1alpha1.Manifest) ([]byte, []manifestv1alpha1.ObjectReference, error) {
+	items := make([]any, 0, len(cr.Spec.ForProvider.Objects))
+	refs := make([]manifestv1alpha1.ObjectReference, 0, len(cr.Spec.ForProvider.Objects))
+	for i, raw := range cr.Spec.ForProvider.Objects {
+		u := &unstructured.Unstructured{}
+		if err := u.UnmarshalJSON(raw.Raw); err != nil {
+			return nil, nil, errors.Wrapf(err, errParseObject, i)
+		}
+		ref := manifestv1alpha1.ObjectReference{
+			APIVersion: u.GetAPIVersion(),
+			Kind:       u.GetKind(),
+			Name:       u.GetName(),
+			Namespace:  u.GetNamespace(),
+		}
+		if ref.APIVersion == "" || ref.Kind == "" || ref.Name == "" {
+			return nil, nil, errors.Errorf(errObjectIdentity, i)
+		}
+		items = append(items, u.Object)
+		refs = append(refs, ref)
+	}
+	manifest, err := json.Marshal(list(items))
+	return manifest, refs, errors.Wrap(err, errMarshalObjects)
+}
+
+// references returns a List manifest that identifies the referenced
+// objects, to delete them.
+func references(refs []manifestv1alpha1.ObjectReference) ([]byte, error) {
+	items := make([]any, 0, len(refs))
+	for _, r := range refs {
+		md := map[string]any{"name": r.Name}
+		if r.Namespace != "" {
+			md["namespace"] = r.Namespace
+		}
+		items = append(items, map[string]any{"apiVersion": r.APIVersion, "kind": r.Kind, "metadata": md})
+	}
+	manifest, err := json.Marshal(list(items))
+	return manifest, errors.Wrap(err, errMarshalObjects)
+}
+
+// list wraps objects in a List.
+func list(items []any) map[string]any {
+	return map[string]any{"apiVersion": "v1", "kind": "List", "items": items}
+}
+
+// removed returns the applied objects that are no longer in the Manifest.
+func removed(applied, want []manifestv1alpha1.ObjectReference) []manifestv1alpha1.ObjectReference {
+	keep := make(map[manifestv1alpha1.ObjectReference]bool, len(want))
+	for _, r := range want {
+		keep[r] = true
+	}
+	var out []manifestv1alpha1.ObjectReference
+	for _, r := range applied {
+		if !keep[r] {
+			out = append(out, r)
+		}
+	}
+	return out
+}
@@ -0,0 +1,215 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+package namespacedcluster
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
+
+	namespacedclusterv1alpha1 "github.com/humoflife/provider-kind/apis/namespacedcluster/v1alpha1"
+	"github.com/humoflife/provider-kind/internal/kindnode"
+)
+
+const (
+	// annotationAccessGrantHash records the grant and cluster endpoint an
+	// access grant Secret was written for.
+	annotationAccessGrantHash = "kind.crossplane.io/access-grant-hash"
+)
+
+const (
+	errListGrantSecrets  = "cannot list access grant Secrets"
+	errGetGrantSecret    = "cannot get access grant Secret %q"
+	errWriteGrantSecret  = "cannot write access grant Secret %q"
+	errDeleteGrantSecret = "cannot delete access grant Secret %q"
+	errGrantSecretExists = "Secret %q exists and does not belong to this Cluster"
+	errGrantKubeConfig   = "cannot render kubeconfig for access grant %q"
+)
+
+// accessGrantsUpToDate reports whether every access grant Secret was
+// written for the current grant and primary kubeconfig, and no Secrets of
+// removed grants remain.
+func (e *external) accessGrantsUpToDate(ctx context.Context, cr *namespacedclusterv1alpha1.Cluster, kubeconfig []byte) (bool, error) {
+	owned, err := e.accessGrantSecrets(ctx, cr)
+	if err != nil {
+		return false, err
+	}
+	if len(owned) != len(cr.Spec.AccessGrants) {
+		return false, nil
+	}
+	for _, g := range cr.Spec.AccessGrants {
+		s, ok := owned[g.SecretName]
+		if !ok || s.GetAnnotations()[annotationAccessGrantHash] != accessGrantHash(g, kubeconfig) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// syncAccessGrants creates the ServiceAccount and role binding of every
+// access grant in the KIND cluster and writes its kubeconfig Secret once its
+// token has been issued. Grants that were removed are revoked and their
+// Secrets deleted.
+func (e *external) syncAccessGrants(ctx context.Context, cr *namespacedclusterv1alpha1.Cluster, all []nodes.Node, kubeconfig []byte) error {
+	owned, err := e.accessGrantSecrets(ctx, cr)
+	if err != nil {
+		return err
+	}
+
+	keep := make([]string, 0, len(cr.Spec.AccessGrants))
+	for _, g := range cr.Spec.AccessGrants {
+		keep = append(keep, g.SecretName)
+		token, err := kindnode.ApplyAccessGrant(all, nodeAccessGrant(g))
+		if err != nil {
+			return err
+		}
+		delete(owned, g.SecretName)
+		if token == "" {
+			// The Secret is written by a later update, once the token
+			// controller has issued the token.
+			continue
+		}
+		if err := e.writeAccessGrantSecret(ctx, cr, g, kubeconfig, token); err != nil {
+			return err
+		}
+	}
+
+	if len(owned) == 0 {
+		return nil
+	}
+	if err := kindnode.RevokeAccessGrants(all, keep); err != nil {
+		return err
+	}
+	for name, s := range owned {
+		if err := client.IgnoreNotFound(e.kube.Delete(ctx, s)); err != nil {
+			return errors.Wrapf(err, errDeleteGrantSecret, name)
+		}
+	}
+	return nil
+}
+
+// accessGrantSecrets returns the access grant Secrets owned by the Cluster,
+// by name.
+func (e *external) accessGrantSecrets(ctx context.Context, cr *namespacedclusterv1alpha1.Cluster) (map[string]*corev1.Secret, error) {
+	l := &corev1.SecretList{}
+	if err := e.kube.List(ctx, l, client.InNamespace(cr.GetNamespace()), client.HasLabels{kindnode.LabelAccessGrant}); err != nil {
+		return nil, errors.Wrap(err, errListGrantSecrets)
+	}
+	owned := map[string]*corev1.Secret{}
+	for i := range l.Items {
+		if metav1.IsControlledBy(&l.Items[i], cr) {
+			owned[l.Items[i].GetName()] = &l.Items[i]
+		}
+	}
+	return owned, nil
+}
+
+// writeAccessGrantSecret creates or updates the Secret holding the grant's
+// kubeconfig and token.
+func (e *external) writeAccessGrantSecret(ctx context.Context, cr *namespacedclusterv1alpha1.Cluster, g namespacedclusterv1alpha1.AccessGrant, kubeconfig []byte, token string) error {
+	kc, err := accessGrantKubeConfig(kubeconfig, g, token)
+	if err != nil {
+		return errors.Wrapf(err, errGrantKubeConfig, g.SecretName)
+	}
+
+	s := &corev1.Secret{}
+	err = e.kube.Get(ctx, types.NamespacedName{Namespace: cr.GetNamespace(), Name: g.SecretName}, s)
+	switch {
+	case kerrors.IsNotFound(err):
+		s = &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: cr.GetNamespace(), Name: g.SecretName}}
+	case err != nil:
+		return errors.Wrapf(err, errGetGrantSecret, g.SecretName)
+	case !metav1.IsControlledBy(s, cr):
+		return errors.Errorf(errGrantSecretExists, g.SecretName)
+	}
+
+	meta.AddLabels(s, map[string]string{kindnode.LabelAccessGrant: g.SecretName})
+	meta.AddAnnotations(s, map[string]string{annotationAccessGrantHash: accessGrantHash(g, kubeconfig)})
+	if err := meta.AddControllerReference(s, meta.AsController(meta.TypedReferenceTo(cr, namespacedclusterv1alpha1.ClusterGroupVersionKind))); err != nil {
+		return errors.Wrapf(err, errWriteGrantSecret, g.SecretName)
+	}
+	s.Type = corev1.SecretTypeOpaque
+	s.Data = map[string][]byte{
+		"kubeconfig": kc,
+		"token":      []byte(token),
+		"namespace":  []byte(g.Namespace),
+	}
+
+	if s.GetResourceVersion() == "" {
+		err = e.kube.Create(ctx, s)
+	} else {
+		err = e.kube.Update(ctx, s)
+	}
+	return errors.Wrapf(err, errWriteGrantSecret, g.SecretName)
+}
+
+// accessGrantKubeConfig renders a kubeconfig that authenticates with the
+// grant's token against the cluster of the primary kubeconfig, defaulting to
+// the grant's namespace.
+func accessGrantKubeConfig(kubeconfig []byte, g namespacedclusterv1alpha1.AccessGrant, token string) ([]byte, error) {
+	base, err := clientcmd.Load(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	current, ok := base.Contexts[base.CurrentContext]
+	if !ok {
+		return nil, errors.Errorf(errNoNSContext, base.CurrentContext)
+	}
+	cluster, ok := base.Clusters[current.Cluster]
+	if !ok {
+		return nil, errors.Errorf(errNoNSContext, base.CurrentContext)
+	}
+
+	user := serviceAccountName(g)
+	cfg := clientcmdapi.NewConfig()
+	cfg.Clusters[current.Cluster] = cluster
+	cfg.AuthInfos[user] = &clientcmdapi.AuthInfo{Token: token}
+	cfg.Contexts[user] = &clientcmdapi.Context{Cluster: current.Cluster, AuthInfo: user, Namespace: g.Namespace}
+	cfg.CurrentContext = user
+	return clientcmd.Write(*cfg)
+}
+
+// accessGrantHash returns a digest of the grant and the primary kubeconfig.
+func accessGrantHash(g namespacedclusterv1alpha1.AccessGrant, kubeconfig []byte) string {
+	spec, _ := json.Marshal(g)
+	h := sha256.New()
+	h.Write(spec)
+	h.Write([]byte{0})
+	h.Write(kubeconfig)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// nodeAccessGrant converts an access grant for kindnode.
+func nodeAccessGrant(g namespacedclusterv1alpha1.AccessGrant) kindnode.AccessGrant {
+	return kindnode.AccessGrant{
+		Name:           g.SecretName,
+		Namespace:      g.Namespace,
+		ServiceAccount: serviceAccountName(g),
+		RoleKind:       g.RoleRef.Kind,
+		RoleName:       g.RoleRef.Name,
+		ClusterWide:    g.ClusterWide,
+	}
+}
+
+// serviceAccountName returns the name of the grant's ServiceAccount.
+func serviceAccountName(g namespacedclusterv1alpha1.AccessGrant) string {
+	if g.ServiceAccountName != nil {
+		return *g.ServiceAccountName
+	}
+	return g.SecretName
+}
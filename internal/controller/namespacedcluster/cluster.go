@@ -14,11 +14,13 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/tools/clientcmd"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/kind/pkg/apis/config/v1alpha4"
 	kindcluster "sigs.k8s.io/kind/pkg/cluster"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
 
 	xpv1 "github.com/crossplane/crossplane-runtime/v2/apis/common/v1"
 	xpcontroller "github.com/crossplane/crossplane-runtime/v2/pkg/controller"
@@ -31,17 +33,63 @@ import (
 	clusterv1alpha1 "github.com/humoflife/provider-kind/apis/cluster/v1alpha1"
 	namespacedclusterv1alpha1 "github.com/humoflife/provider-kind/apis/namespacedcluster/v1alpha1"
 	"github.com/humoflife/provider-kind/apis/v1beta1"
+	"github.com/humoflife/provider-kind/internal/apiservice"
+	"github.com/humoflife/provider-kind/internal/argocd"
+	"github.com/humoflife/provider-kind/internal/bootstrap"
+	"github.com/humoflife/provider-kind/internal/capacity"
+	"github.com/humoflife/provider-kind/internal/certificates"
+	"github.com/humoflife/provider-kind/internal/docker"
+	"github.com/humoflife/provider-kind/internal/kindnetwork"
+	"github.com/humoflife/provider-kind/internal/kindnode"
+	"github.com/humoflife/provider-kind/internal/kubeconfigsecrets"
+	"github.com/humoflife/provider-kind/internal/noderesources"
+	"github.com/humoflife/provider-kind/internal/policy"
+	"github.com/humoflife/provider-kind/internal/providerconfigs"
+	"github.com/humoflife/provider-kind/internal/quota"
+	"github.com/humoflife/provider-kind/internal/sources"
 )
 
 const (
-	errNotNamespacedCluster = "managed resource is not a namespaced Cluster custom resource"
-	errTrackNSUsage         = "cannot track ProviderConfig usage"
-	errListNSClusters       = "cannot list KIND clusters"
-	errCreateNSCluster      = "cannot create KIND cluster"
-	errDeleteNSCluster      = "cannot delete KIND cluster"
-	errGetNSKubeConfig      = "cannot get kubeconfig for KIND cluster"
-	errGetNSNodes           = "cannot list KIND cluster nodes"
-	errParseNSWait          = "cannot parse waitForReady duration"
+	errNotNamespacedCluster       = "managed resource is not a namespaced Cluster custom resource"
+	errTrackNSUsage               = "cannot track ProviderConfig usage"
+	errNoNSProviderConfig         = "providerConfigRef is required"
+	errListNSClusters             = "cannot list KIND clusters"
+	errCreateNSCluster            = "cannot create KIND cluster"
+	errDeleteNSCluster            = "cannot delete KIND cluster"
+	errGetNSKubeConfig            = "cannot get kubeconfig for KIND cluster"
+	errGetNSNodes                 = "cannot list KIND cluster nodes"
+	errParseNSWait                = "cannot parse waitForReady duration"
+	errResolveNSNodeConfig        = "cannot resolve node configuration"
+	errLimitNSNodeResources       = "cannot limit node resources"
+	errNSPolicyViolation          = "cluster violates the policy of its ProviderConfig: %s"
+	errCheckNSQuotas              = "cannot check quotas"
+	errNSQuotaExceeded            = "cluster would exceed the quotas of its ProviderConfig: %s"
+	errApplyNSNodeConfig          = "cannot apply node configuration"
+	errResolveNSNetwork           = "cannot resolve Docker network"
+	errResolveNSLoadBalancer      = "cannot resolve load balancer address pool"
+	errSyncNSLoadBalancers        = "cannot assign load balancer addresses"
+	errPublishNSAPIServer         = "cannot publish API server Service"
+	errDeleteNSAPIServer          = "cannot delete API server Service"
+	errPrimaryNSKubeConfig        = "primaryKubeconfig %q requires apiServerService"
+	errParseNSKubeConfig          = "cannot parse kubeconfig"
+	errNoNSContext                = "kubeconfig has no context %q"
+	errGetNSToken                 = "cannot get ServiceAccount token"
+	errNSArgoCDToken              = "argocd credentials token requires serviceAccountToken"
+	errSyncNSAccessGrants         = "cannot sync access grants"
+	errObserveNSCertificates      = "cannot observe cluster certificates"
+	errRenewNSCertificates        = "cannot renew cluster certificates"
+	errCheckNSCapacity            = "cannot check Docker host capacity"
+	errNoNSCapacity               = "Docker host lacks capacity for the cluster: %s"
+	errPublishNSArgoCD            = "cannot publish Argo CD cluster Secret"
+	errDeleteNSArgoCD             = "cannot delete Argo CD cluster Secret"
+	errPublishNSProviderConfigs   = "cannot publish ProviderConfigs"
+	errDeleteNSProviderConfigs    = "cannot delete ProviderConfigs"
+	errNSProviderConfigsSecret    = "publishProviderConfigs requires writeConnectionSecretToRef"
+	errPublishNSKubeconfigSecrets = "cannot publish kubeconfig Secrets"
+	errDeleteNSKubeconfigSecrets  = "cannot delete kubeconfig Secrets"
+	errResolveNSBootstrap         = "cannot resolve bootstrap manifests"
+	errNSBootstrap                = "cannot bootstrap cluster"
+	errRecordNSOwner              = "cannot record the namespace that owns the KIND cluster"
 )
 
 // Setup adds a controller that reconciles namespaced Cluster managed resources.
@@ -53,6 +101,7 @@ func Setup(mgr ctrl.Manager, o xpcontroller.Options) error {
 		managed.WithLogger(o.Logger.WithValues("controller", name)),
 		managed.WithRecorder(event.NewAPIRecorder(mgr.GetEventRecorderFor(name))),
 		managed.WithPollInterval(o.PollInterval),
+		managed.WithInitializers(&externalName{kube: mgr.GetClient()}),
 	}
 
 	r := managed.NewReconciler(mgr,
@@ -90,12 +139,24 @@ func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.E
 	// No credentials are required for local KIND clusters.
 	provider := kindcluster.NewProvider()
 
-	return &external{provider: provider}, nil
+	// Clusters are created within the policy, quotas, and host capacity of
+	// their ProviderConfig, so it must exist and allow their namespace,
+	// unless the cluster is being deleted.
+	pc, err := providerConfig(ctx, c.kube, cr)
+	if err != nil && !meta.WasDeleted(cr) {
+		return nil, err
+	}
+
+	return &external{provider: provider, kube: c.kube, pc: pc}, nil
 }
 
 // external implements managed.ExternalClient for namespaced KIND clusters.
 type external struct {
 	provider *kindcluster.Provider
+	kube     client.Client
+
+	// pc is the ProviderConfig of the cluster.
+	pc *v1beta1.ProviderConfig
 }
 
 // Observe checks whether the KIND cluster already exists and observes its state.
@@ -107,6 +168,15 @@ func (e *external) Observe(ctx context.Context, mg resource.Managed) (managed.Ex
 
 	clusterName := getClusterName(cr)
 
+	// Tenants in different namespaces must not share a KIND cluster.
+	conflict, err := e.nameConflict(ctx, clusterName)
+	if err != nil {
+		return managed.ExternalObservation{}, err
+	}
+	if conflict != "" {
+		return e.conflicting(cr, conflict)
+	}
+
 	// List all local KIND clusters to check if ours exists.
 	clusters, err := e.provider.List()
 	if err != nil {
@@ -125,29 +195,49 @@ func (e *external) Observe(ctx context.Context, mg resource.Managed) (managed.Ex
 		return managed.ExternalObservation{ResourceExists: false}, nil
 	}
 
-	// Cluster exists - get the kubeconfig.
-	kubeconfig, err := e.provider.KubeConfig(clusterName, false)
+	// Observe node states.
+	nodes, err := e.provider.ListNodes(clusterName)
 	if err != nil {
-		return managed.ExternalObservation{}, errors.Wrap(err, errGetNSKubeConfig)
+		return managed.ExternalObservation{}, errors.Wrap(err, errGetNSNodes)
 	}
 
-	// Parse the API server endpoint from the kubeconfig.
-	if kubeconf, parseErr := clientcmd.Load([]byte(kubeconfig)); parseErr == nil {
-		for _, clusterInfo := range kubeconf.Clusters {
-			endpoint := clusterInfo.Server
-			cr.Status.AtProvider.APIServerEndpoint = &endpoint
-			break
+	// An existing cluster is only adopted if it belongs to this namespace,
+	// or if it records no owner and no other Cluster claims it.
+	owner := kindnode.Owner(nodes)
+	claimed := false
+	if owner == "" {
+		if claimed, err = e.claimed(ctx, cr, clusterName); err != nil {
+			return managed.ExternalObservation{}, err
 		}
 	}
+	if conflict := ownerConflict(cr, clusterName, owner, claimed); conflict != "" {
+		return e.conflicting(cr, conflict)
+	}
 
-	// Observe node states.
-	nodes, err := e.provider.ListNodes(clusterName)
+	// A cluster that is being deleted is deleted whether or not the Secrets,
+	// ConfigMaps, and other objects it references still exist, so they are
+	// not resolved.
+	if meta.WasDeleted(cr) {
+		return managed.ExternalObservation{ResourceExists: true}, nil
+	}
+
+	// Clusters created before owners were recorded are adopted by recording
+	// this namespace, so that no other namespace adopts them later.
+	if owner == "" {
+		if err := kindnode.SetOwner(nodes, cr.GetNamespace()); err != nil {
+			return managed.ExternalObservation{}, errors.Wrap(err, errRecordNSOwner)
+		}
+	}
+
+	cfg, err := e.nodeConfig(ctx, cr)
 	if err != nil {
-		return managed.ExternalObservation{}, errors.Wrap(err, errGetNSNodes)
+		return managed.ExternalObservation{}, errors.Wrap(err, errResolveNSNodeConfig)
 	}
+	limits := e.nodeResources(cr)
 
 	nodeObs := make([]clusterv1alpha1.NodeObservation, 0, len(nodes))
 	allReady := len(nodes) > 0
+	upToDate := true
 
 	for _, n := range nodes {
 		role, roleErr := n.Role()
@@ -159,6 +249,7 @@ func (e *external) Observe(ctx context.Context, mg resource.Managed) (managed.Ex
 			IPAddress:   ipv4,
 			IPv6Address: ipv6,
 			Image:       nodeImage(ctx, n.String()),
+			Networks:    nodeNetworks(ctx, n.String()),
 		}
 
 		if roleErr == nil && ipErr == nil {
@@ -168,10 +259,98 @@ func (e *external) Observe(ctx context.Context, mg resource.Managed) (managed.Ex
 			allReady = false
 		}
 
+		// Nodes whose resource limits changed need the current limits.
+		var limited bool
+		obs.Resources, limited = noderesources.Observe(ctx, n.String(), limits[n.String()])
+		if !limited {
+			upToDate = false
+		}
+
 		nodeObs = append(nodeObs, obs)
 	}
 
+	// Nodes that joined after creation, or that predate a change to the
+	// trusted CAs, registries, or mounted ConfigMaps and Secrets, need the
+	// current configuration.
+	if !kindnode.UpToDateAll(nodes, cfg) {
+		upToDate = false
+	}
+
+	// The cluster is not ready until it has been bootstrapped.
+	steps, err := e.bootstrapSteps(ctx, cr)
+	if err != nil {
+		return managed.ExternalObservation{}, errors.Wrap(err, errResolveNSBootstrap)
+	}
+	if !bootstrap.Applied(&cr.Status.AtProvider, steps) {
+		allReady = false
+		upToDate = false
+	}
+
+	pool, err := e.loadBalancerPool(ctx, cr)
+	if err != nil {
+		return managed.ExternalObservation{}, errors.Wrap(err, errResolveNSLoadBalancer)
+	}
+	lbs, lbUpToDate := kindnode.LoadBalancers(nodes, pool)
+	upToDate = upToDate && lbUpToDate
+
+	// Cluster exists - get the kubeconfigs.
+	conn, published, err := e.connectionDetails(ctx, cr, nodes, false)
+	if err != nil {
+		return managed.ExternalObservation{}, err
+	}
+	upToDate = upToDate && published
+
+	// Certificates that are due are renewed by Update, if enabled.
+	due, err := certificates.Observe(cr, &cr.Status.AtProvider, cr.Spec.ForProvider.CertificateRotation, conn)
+	if err != nil {
+		return managed.ExternalObservation{}, errors.Wrap(err, errObserveNSCertificates)
+	}
+	upToDate = upToDate && !(due && certificates.Automatic(cr.Spec.ForProvider.CertificateRotation))
+
+	if cr.Spec.ForProvider.ArgoCD != nil {
+		registered, err := e.argoCD(ctx, cr, conn, false)
+		if err != nil {
+			return managed.ExternalObservation{}, errors.Wrap(err, errPublishNSArgoCD)
+		}
+		upToDate = upToDate && registered
+	}
+
+	// ProviderConfigs are published once the cluster is ready.
+	if cr.Spec.ForProvider.PublishProviderConfigs != nil && allReady {
+		published, err := e.providerConfigs(ctx, cr, false)
+		if err != nil {
+			return managed.ExternalObservation{}, errors.Wrap(err, errPublishNSProviderConfigs)
+		}
+		upToDate = upToDate && published
+	}
+
+	secrets, err := kubeconfigSecrets(cr)
+	if err != nil {
+		return managed.ExternalObservation{}, errors.Wrap(err, errPublishNSKubeconfigSecrets)
+	}
+	secretsUpToDate, err := kubeconfigsecrets.UpToDate(ctx, e.kube, cr, secrets, conn["kubeconfig"])
+	if err != nil {
+		return managed.ExternalObservation{}, errors.Wrap(err, errPublishNSKubeconfigSecrets)
+	}
+	upToDate = upToDate && secretsUpToDate
+
+	grantsUpToDate, err := e.accessGrantsUpToDate(ctx, cr, conn["kubeconfig"])
+	if err != nil {
+		return managed.ExternalObservation{}, errors.Wrap(err, errSyncNSAccessGrants)
+	}
+	upToDate = upToDate && grantsUpToDate
+
+	// Parse the API server endpoint from the external kubeconfig.
+	if kubeconf, parseErr := clientcmd.Load(conn["kubeconfig-external"]); parseErr == nil {
+		for _, clusterInfo := range kubeconf.Clusters {
+			endpoint := clusterInfo.Server
+			cr.Status.AtProvider.APIServerEndpoint = &endpoint
+			break
+		}
+	}
+
 	cr.Status.AtProvider.Nodes = nodeObs
+	cr.Status.AtProvider.LoadBalancers = loadBalancerObservations(lbs)
 	cr.Status.AtProvider.Ready = allReady
 
 	if allReady {
@@ -181,11 +360,9 @@ func (e *external) Observe(ctx context.Context, mg resource.Managed) (managed.Ex
 	}
 
 	return managed.ExternalObservation{
-		ResourceExists:   true,
-		ResourceUpToDate: true,
-		ConnectionDetails: managed.ConnectionDetails{
-			"kubeconfig": []byte(kubeconfig),
-		},
+		ResourceExists:    true,
+		ResourceUpToDate:  upToDate,
+		ConnectionDetails: conn,
 	}, nil
 }
 
@@ -201,6 +378,40 @@ func (e *external) Create(ctx context.Context, mg resource.Managed) (managed.Ext
 	clusterName := getClusterName(cr)
 	meta.SetExternalName(cr, clusterName)
 
+	// Resolve the node configuration before creating the cluster so that a
+	// bad reference fails fast instead of leaving a half-configured cluster.
+	cfg, err := e.nodeConfig(ctx, cr)
+	if err != nil {
+		return managed.ExternalCreation{}, errors.Wrap(err, errResolveNSNodeConfig)
+	}
+	limits := e.nodeResources(cr)
+	if _, err := e.bootstrapSteps(ctx, cr); err != nil {
+		return managed.ExternalCreation{}, errors.Wrap(err, errResolveNSBootstrap)
+	}
+
+	network, err := e.dockerNetwork(ctx, cr)
+	if err != nil {
+		return managed.ExternalCreation{}, errors.Wrap(err, errResolveNSNetwork)
+	}
+
+	// Clusters that request more of the Docker host than the policy of the
+	// ProviderConfig allows are not created.
+	if violations := policy.Check(policy.Namespaced(e.pc.Spec.Policy), cr.Spec.ForProvider, network); violations != "" {
+		cr.SetConditions(clusterv1alpha1.PolicyViolation(violations))
+		return managed.ExternalCreation{}, errors.Errorf(errNSPolicyViolation, violations)
+	}
+
+	// Clusters created on a host that lacks the capacity for them fail half
+	// way, so creation waits until the host has it.
+	short, err := capacity.Check(ctx, e.pc.Spec.HostCapacity, cr.Spec.ForProvider)
+	if err != nil {
+		return managed.ExternalCreation{}, errors.Wrap(err, errCheckNSCapacity)
+	}
+	if short != "" {
+		cr.SetConditions(clusterv1alpha1.WaitingForCapacity(short))
+		return managed.ExternalCreation{}, errors.Errorf(errNoNSCapacity, short)
+	}
+
 	// Build the KIND cluster configuration from the spec.
 	kindConfig := buildKindConfig(cr.Spec.ForProvider)
 
@@ -221,21 +432,90 @@ func (e *external) Create(ctx context.Context, mg resource.Managed) (managed.Ext
 		opts = append(opts, kindcluster.CreateWithWaitForReady(wait))
 	}
 
-	if err := e.provider.Create(clusterName, opts...); err != nil {
+	// Clusters that would exceed the quotas of the ProviderConfig are not
+	// created. Their usage is reserved until they are counted as created,
+	// so that clusters created concurrently cannot exceed the quotas.
+	if q := e.pc.Spec.Quotas; q != nil {
+		exceeded, err := quota.Admit(ctx, e.kube, q, e.pc.GetName(), cr.GetUID(), cr.GetNamespace(), cr.Spec.ForProvider)
+		if err != nil {
+			return managed.ExternalCreation{}, errors.Wrap(err, errCheckNSQuotas)
+		}
+		if exceeded != "" {
+			cr.SetConditions(clusterv1alpha1.QuotaExceeded(exceeded))
+			return managed.ExternalCreation{}, errors.Errorf(errNSQuotaExceeded, exceeded)
+		}
+	}
+
+	// KIND reads the network from the environment, so creations on
+	// different networks take turns until it has read it.
+	err = kindnetwork.Create(ctx, network, kindConfig, func(p *kindcluster.Provider) error {
+		return p.Create(clusterName, opts...)
+	})
+	if err != nil {
+		quota.Release(e.pc.GetName(), cr.GetUID())
 		return managed.ExternalCreation{}, errors.Wrap(err, errCreateNSCluster)
 	}
 
-	// Retrieve the kubeconfig immediately after creation.
-	kubeconfig, err := e.provider.KubeConfig(clusterName, false)
+	// Record the namespace that owns the cluster, so that no other
+	// namespace adopts it.
+	owned, err := e.provider.ListNodes(clusterName)
 	if err != nil {
-		return managed.ExternalCreation{}, errors.Wrap(err, errGetNSKubeConfig)
+		return managed.ExternalCreation{}, errors.Wrap(err, errGetNSNodes)
+	}
+	if err := kindnode.SetOwner(owned, cr.GetNamespace()); err != nil {
+		// A cluster that records no owner is never adopted, so it is
+		// deleted to be created again.
+		_ = e.provider.Delete(clusterName, os.DevNull)
+		return managed.ExternalCreation{}, errors.Wrap(err, errRecordNSOwner)
 	}
 
-	return managed.ExternalCreation{
-		ConnectionDetails: managed.ConnectionDetails{
-			"kubeconfig": []byte(kubeconfig),
-		},
-	}, nil
+	// KIND has no resource controls, so the limits are applied to the
+	// node containers once they run.
+	if len(limits) > 0 {
+		nodes, err := e.provider.ListNodes(clusterName)
+		if err != nil {
+			return managed.ExternalCreation{}, errors.Wrap(err, errGetNSNodes)
+		}
+		if err := noderesources.Limit(ctx, nodes, limits); err != nil {
+			return managed.ExternalCreation{}, errors.Wrap(err, errLimitNSNodeResources)
+		}
+	}
+
+	if !cfg.Empty() {
+		nodes, err := e.provider.ListNodes(clusterName)
+		if err != nil {
+			return managed.ExternalCreation{}, errors.Wrap(err, errGetNSNodes)
+		}
+		if err := kindnode.ApplyAll(nodes, cfg); err != nil {
+			return managed.ExternalCreation{}, errors.Wrap(err, errApplyNSNodeConfig)
+		}
+	}
+
+	// Retrieve the kubeconfigs immediately after creation.
+	nodes, err := e.provider.ListNodes(clusterName)
+	if err != nil {
+		return managed.ExternalCreation{}, errors.Wrap(err, errGetNSNodes)
+	}
+	conn, _, err := e.connectionDetails(ctx, cr, nodes, true)
+	if err != nil {
+		return managed.ExternalCreation{}, err
+	}
+
+	if err := e.syncAccessGrants(ctx, cr, nodes, conn["kubeconfig"]); err != nil {
+		return managed.ExternalCreation{}, errors.Wrap(err, errSyncNSAccessGrants)
+	}
+
+	if err := e.syncKubeconfigSecrets(ctx, cr, conn["kubeconfig"]); err != nil {
+		return managed.ExternalCreation{}, errors.Wrap(err, errPublishNSKubeconfigSecrets)
+	}
+
+	if cr.Spec.ForProvider.ArgoCD != nil {
+		if _, err := e.argoCD(ctx, cr, conn, true); err != nil {
+			return managed.ExternalCreation{}, errors.Wrap(err, errPublishNSArgoCD)
+		}
+	}
+
+	return managed.ExternalCreation{ConnectionDetails: conn}, nil
 }
 
 // Disconnect is a no-op.
@@ -243,9 +523,96 @@ func (e *external) Disconnect(_ context.Context) error {
 	return nil
 }
 
-// Update is a no-op because KIND clusters are immutable after creation.
+// Update applies the trusted CAs and registry configuration to nodes that
+// are missing them, assigns addresses to LoadBalancer Services, and syncs the
+// access grants. Everything else is immutable after creation.
 func (e *external) Update(ctx context.Context, mg resource.Managed) (managed.ExternalUpdate, error) {
-	return managed.ExternalUpdate{}, nil
+	cr, ok := mg.(*namespacedclusterv1alpha1.Cluster)
+	if !ok {
+		return managed.ExternalUpdate{}, errors.New(errNotNamespacedCluster)
+	}
+
+	cfg, err := e.nodeConfig(ctx, cr)
+	if err != nil {
+		return managed.ExternalUpdate{}, errors.Wrap(err, errResolveNSNodeConfig)
+	}
+
+	nodes, err := e.provider.ListNodes(getClusterName(cr))
+	if err != nil {
+		return managed.ExternalUpdate{}, errors.Wrap(err, errGetNSNodes)
+	}
+
+	if err := kindnode.ApplyAll(nodes, cfg); err != nil {
+		return managed.ExternalUpdate{}, errors.Wrap(err, errApplyNSNodeConfig)
+	}
+
+	limits := e.nodeResources(cr)
+	if err := noderesources.Limit(ctx, nodes, limits); err != nil {
+		return managed.ExternalUpdate{}, errors.Wrap(err, errLimitNSNodeResources)
+	}
+
+	pool, err := e.loadBalancerPool(ctx, cr)
+	if err != nil {
+		return managed.ExternalUpdate{}, errors.Wrap(err, errResolveNSLoadBalancer)
+	}
+	if err := kindnode.SyncLoadBalancers(nodes, pool); err != nil {
+		return managed.ExternalUpdate{}, errors.Wrap(err, errSyncNSLoadBalancers)
+	}
+
+	// Steps that fail are recorded in status and retried on the next
+	// reconcile.
+	steps, err := e.bootstrapSteps(ctx, cr)
+	if err != nil {
+		return managed.ExternalUpdate{}, errors.Wrap(err, errResolveNSBootstrap)
+	}
+	if err := bootstrap.Apply(&cr.Status.AtProvider, nodes, steps); err != nil {
+		return managed.ExternalUpdate{}, errors.Wrap(err, errNSBootstrap)
+	}
+
+	conn, _, err := e.connectionDetails(ctx, cr, nodes, true)
+	if err != nil {
+		return managed.ExternalUpdate{}, err
+	}
+
+	// Renewal rewrites the admin kubeconfig the connection details are read
+	// from, so they are read again afterwards.
+	due, err := certificates.Observe(cr, &cr.Status.AtProvider, cr.Spec.ForProvider.CertificateRotation, conn)
+	if err != nil {
+		return managed.ExternalUpdate{}, errors.Wrap(err, errObserveNSCertificates)
+	}
+	if due && certificates.Automatic(cr.Spec.ForProvider.CertificateRotation) {
+		if err := kindnode.RenewCertificates(nodes); err != nil {
+			return managed.ExternalUpdate{}, errors.Wrap(err, errRenewNSCertificates)
+		}
+		if conn, _, err = e.connectionDetails(ctx, cr, nodes, true); err != nil {
+			return managed.ExternalUpdate{}, err
+		}
+		if _, err := certificates.Observe(cr, &cr.Status.AtProvider, cr.Spec.ForProvider.CertificateRotation, conn); err != nil {
+			return managed.ExternalUpdate{}, errors.Wrap(err, errObserveNSCertificates)
+		}
+	}
+
+	if err := e.syncAccessGrants(ctx, cr, nodes, conn["kubeconfig"]); err != nil {
+		return managed.ExternalUpdate{}, errors.Wrap(err, errSyncNSAccessGrants)
+	}
+
+	if err := e.syncKubeconfigSecrets(ctx, cr, conn["kubeconfig"]); err != nil {
+		return managed.ExternalUpdate{}, errors.Wrap(err, errPublishNSKubeconfigSecrets)
+	}
+
+	if cr.Spec.ForProvider.ArgoCD != nil {
+		if _, err := e.argoCD(ctx, cr, conn, true); err != nil {
+			return managed.ExternalUpdate{}, errors.Wrap(err, errPublishNSArgoCD)
+		}
+	}
+
+	if cr.Spec.ForProvider.PublishProviderConfigs != nil && cr.Status.AtProvider.Ready {
+		if _, err := e.providerConfigs(ctx, cr, true); err != nil {
+			return managed.ExternalUpdate{}, errors.Wrap(err, errPublishNSProviderConfigs)
+		}
+	}
+
+	return managed.ExternalUpdate{ConnectionDetails: conn}, nil
 }
 
 // Delete removes the KIND cluster.
@@ -259,6 +626,35 @@ func (e *external) Delete(ctx context.Context, mg resource.Managed) (managed.Ext
 
 	clusterName := getClusterName(cr)
 
+	if cr.Spec.ForProvider.APIServerService != nil {
+		namespace, name, err := apiServerServiceName(cr)
+		if err != nil {
+			return managed.ExternalDelete{}, errors.Wrap(err, errDeleteNSAPIServer)
+		}
+		if err := apiservice.Delete(ctx, e.kube, namespace, name, clusterName); err != nil {
+			return managed.ExternalDelete{}, errors.Wrap(err, errDeleteNSAPIServer)
+		}
+	}
+
+	if cr.Spec.ForProvider.ArgoCD != nil {
+		namespace, name := argoCDSecretName(cr)
+		if err := argocd.Delete(ctx, e.kube, namespace, name, clusterName); err != nil {
+			return managed.ExternalDelete{}, errors.Wrap(err, errDeleteNSArgoCD)
+		}
+	}
+
+	if cr.Spec.ForProvider.PublishProviderConfigs != nil {
+		for _, pc := range publishedProviderConfigs(cr) {
+			if err := pc.Delete(ctx, e.kube, clusterName); err != nil {
+				return managed.ExternalDelete{}, errors.Wrap(err, errDeleteNSProviderConfigs)
+			}
+		}
+	}
+
+	if err := kubeconfigsecrets.Sync(ctx, e.kube, cr, namespacedclusterv1alpha1.ClusterGroupVersionKind, nil, nil); err != nil {
+		return managed.ExternalDelete{}, errors.Wrap(err, errDeleteNSKubeconfigSecrets)
+	}
+
 	// Pass os.DevNull so KIND does not attempt to remove the cluster entry from
 	// the default ~/.kube/config (which would also not exist there anyway since
 	// Create wrote to /dev/null).
@@ -269,6 +665,199 @@ func (e *external) Delete(ctx context.Context, mg resource.Managed) (managed.Ext
 	return managed.ExternalDelete{}, nil
 }
 
+// nodeConfig resolves the provider-managed node configuration of the
+// cluster. References are always resolved in the cluster's own namespace.
+func (e *external) nodeConfig(ctx context.Context, cr *namespacedclusterv1alpha1.Cluster) (kindnode.Config, error) {
+	r := sources.Resolver{Client: e.kube, Namespace: cr.GetNamespace()}
+	return r.NodeConfig(ctx, getClusterName(cr), cr.Spec.ForProvider)
+}
+
+// nodeResources returns the resource limits of each node of the cluster.
+func (e *external) nodeResources(cr *namespacedclusterv1alpha1.Cluster) map[string]docker.Resources {
+	r := sources.Resolver{Client: e.kube, Namespace: cr.GetNamespace()}
+	return r.NodeResources(e.pc, getClusterName(cr), cr.Spec.ForProvider.Nodes)
+}
+
+// providerConfig returns the ProviderConfig of the cluster. Namespaced
+// Clusters must name one, so that tenants cannot fall back to one their
+// namespace is not allowed to use.
+func providerConfig(ctx context.Context, kube client.Reader, cr *namespacedclusterv1alpha1.Cluster) (*v1beta1.ProviderConfig, error) {
+	ref := cr.Spec.ProviderConfigReference
+	if ref == nil {
+		return nil, errors.New(errNoNSProviderConfig)
+	}
+	r := sources.Resolver{Client: kube, Namespace: cr.GetNamespace()}
+	return r.ProviderConfig(ctx, ref.Kind, ref.Name)
+}
+
+// dockerNetwork resolves the Docker network the cluster nodes are created
+// on.
+func (e *external) dockerNetwork(ctx context.Context, cr *namespacedclusterv1alpha1.Cluster) (string, error) {
+	r := sources.Resolver{Client: e.kube, Namespace: cr.GetNamespace()}
+	return r.DockerNetwork(ctx, cr.Spec.ForProvider)
+}
+
+// loadBalancerPool resolves the address pool assigned to LoadBalancer
+// Services, or nil if load balancing is disabled.
+func (e *external) loadBalancerPool(ctx context.Context, cr *namespacedclusterv1alpha1.Cluster) (*kindnode.AddressPool, error) {
+	r := sources.Resolver{Client: e.kube, Namespace: cr.GetNamespace()}
+	return r.LoadBalancerPool(ctx, cr.Spec.ForProvider)
+}
+
+// bootstrapSteps resolves the manifests of the cluster's bootstrap steps.
+func (e *external) bootstrapSteps(ctx context.Context, cr *namespacedclusterv1alpha1.Cluster) ([]kindnode.BootstrapStep, error) {
+	r := sources.Resolver{Client: e.kube, Namespace: cr.GetNamespace()}
+	return r.BootstrapSteps(ctx, cr.Spec.ForProvider.Bootstrap)
+}
+
+// connectionDetails returns the kubeconfigs of the cluster: the external one
+// for the Docker host, the internal one for the Docker network and, with an
+// apiServerService, one for the management cluster. The one selected by
+// primaryKubeconfig is also published as kubeconfig. It reports whether the
+// API server Service and the ServiceAccount token are up to date, creating,
+// updating, or deleting them first when apply is true.
+func (e *external) connectionDetails(ctx context.Context, cr *namespacedclusterv1alpha1.Cluster, all []nodes.Node, apply bool) (managed.ConnectionDetails, bool, error) {
+	clusterName := getClusterName(cr)
+	external, err := e.provider.KubeConfig(clusterName, false)
+	if err != nil {
+		return nil, false, errors.Wrap(err, errGetNSKubeConfig)
+	}
+	internal, err := e.provider.KubeConfig(clusterName, true)
+	if err != nil {
+		return nil, false, errors.Wrap(err, errGetNSKubeConfig)
+	}
+	conn := managed.ConnectionDetails{
+		"kubeconfig-external": []byte(external),
+		"kubeconfig-internal": []byte(internal),
+	}
+
+	upToDate := true
+	if cr.Spec.ForProvider.APIServerService != nil {
+		kc, published, err := e.apiServerService(ctx, cr, all, []byte(internal), apply)
+		if err != nil {
+			return nil, false, errors.Wrap(err, errPublishNSAPIServer)
+		}
+		conn["kubeconfig-service"] = kc
+		upToDate = published
+	}
+
+	primary := "external"
+	if p := cr.Spec.ForProvider.PrimaryKubeconfig; p != nil {
+		primary = *p
+	}
+	kc, ok := conn["kubeconfig-"+primary]
+	if !ok {
+		return nil, false, errors.Errorf(errPrimaryNSKubeConfig, primary)
+	}
+	conn["kubeconfig"] = kc
+
+	// Tools that take individual fields rather than a kubeconfig get the
+	// ones of the primary kubeconfig, and a long-lived token.
+	if err := kubeconfigDetails(conn, kc); err != nil {
+		return nil, false, errors.Wrap(err, errParseNSKubeConfig)
+	}
+	conn["clusterName"] = []byte(clusterName)
+	token, role, err := kindnode.ServiceAccountToken(all)
+	if err != nil {
+		return nil, false, errors.Wrap(err, errGetNSToken)
+	}
+	want := ""
+	if t := cr.Spec.ForProvider.ServiceAccountToken; t != nil {
+		want = t.ClusterRole
+	}
+	if apply && role != want {
+		if want == "" {
+			err = kindnode.DeleteServiceAccountToken(all)
+		} else {
+			err = kindnode.ApplyServiceAccountToken(all, want)
+		}
+		if err != nil {
+			return nil, false, errors.Wrap(err, errGetNSToken)
+		}
+		// The token controller populates the new token asynchronously.
+		token, role = "", want
+	}
+	if token != "" && want != "" {
+		conn["token"] = []byte(token)
+	}
+	return conn, upToDate && role == want, nil
+}
+
+// kubeconfigDetails adds the endpoint, CA certificate, and client
+// credentials of the kubeconfig's current context to the connection details.
+func kubeconfigDetails(conn managed.ConnectionDetails, kubeconfig []byte) error {
+	cfg, err := clientcmd.Load(kubeconfig)
+	if err != nil {
+		return err
+	}
+	current, ok := cfg.Contexts[cfg.CurrentContext]
+	if !ok {
+		return errors.Errorf(errNoNSContext, cfg.CurrentContext)
+	}
+	if c, ok := cfg.Clusters[current.Cluster]; ok {
+		conn["endpoint"] = []byte(c.Server)
+		conn["clusterCA"] = c.CertificateAuthorityData
+	}
+	if a, ok := cfg.AuthInfos[current.AuthInfo]; ok {
+		conn["clientCert"] = a.ClientCertificateData
+		conn["clientKey"] = a.ClientKeyData
+	}
+	return nil
+}
+
+// apiServerService returns a kubeconfig that reaches the API server through
+// the Service that publishes it in the management cluster, and whether the
+// Service points at the API server. When apply is true the Service is
+// created or updated first.
+func (e *external) apiServerService(ctx context.Context, cr *namespacedclusterv1alpha1.Cluster, all []nodes.Node, internal []byte, apply bool) ([]byte, bool, error) {
+	namespace, name, err := apiServerServiceName(cr)
+	if err != nil {
+		return nil, false, err
+	}
+	target, err := apiservice.Target(all)
+	if err != nil {
+		return nil, false, err
+	}
+	if apply {
+		if err := apiservice.Apply(ctx, e.kube, namespace, name, getClusterName(cr), target); err != nil {
+			return nil, false, err
+		}
+	}
+	kc, err := apiservice.KubeConfig(internal, namespace, name)
+	if err != nil {
+		return nil, false, err
+	}
+	return kc, apiservice.UpToDate(ctx, e.kube, namespace, name, target), nil
+}
+
+// apiServerServiceName returns the namespace and name of the Service that
+// publishes the API server. The Service is always created in the cluster's
+// own namespace.
+func apiServerServiceName(cr *namespacedclusterv1alpha1.Cluster) (string, string, error) {
+	name := getClusterName(cr)
+	if s := cr.Spec.ForProvider.APIServerService; s.Name != nil {
+		name = *s.Name
+	}
+	return cr.GetNamespace(), name, nil
+}
+
+// loadBalancerObservations converts the LoadBalancer Services into their
+// observed state.
+func loadBalancerObservations(lbs []kindnode.LoadBalancerService) []clusterv1alpha1.LoadBalancerObservation {
+	if len(lbs) == 0 {
+		return nil
+	}
+	out := make([]clusterv1alpha1.LoadBalancerObservation, 0, len(lbs))
+	for _, lb := range lbs {
+		obs := clusterv1alpha1.LoadBalancerObservation{Namespace: lb.Namespace, Name: lb.Name}
+		if lb.IP.IsValid() {
+			obs.IP = lb.IP.String()
+		}
+		out = append(out, obs)
+	}
+	return out
+}
+
 // nodeImage returns the container image for a KIND node by inspecting the
 // Docker container.
 func nodeImage(ctx context.Context, containerName string) string {
@@ -280,13 +869,24 @@ func nodeImage(ctx context.Context, containerName string) string {
 	return strings.TrimSpace(string(out))
 }
 
+// nodeNetworks returns the Docker networks a KIND node is attached to.
+func nodeNetworks(ctx context.Context, containerName string) []string {
+	out, err := exec.CommandContext(ctx, "docker", "inspect",
+		"--format={{range $name, $_ := .NetworkSettings.Networks}}{{$name}} {{end}}", containerName).Output()
+	if err != nil {
+		return nil
+	}
+	return strings.Fields(string(out))
+}
+
 // getClusterName returns the external name of the cluster, falling back to
-// the managed resource name if no external name has been set.
+// the namespace-prefixed managed resource name if no external name has been
+// set.
 func getClusterName(cr *namespacedclusterv1alpha1.Cluster) string {
 	if name := meta.GetExternalName(cr); name != "" {
 		return name
 	}
-	return cr.GetName()
+	return kindClusterName(cr.GetNamespace(), cr.GetName())
 }
 
 // buildKindConfig converts the ClusterParameters into a KIND v1alpha4 cluster
@@ -309,6 +909,11 @@ func buildKindConfig(params clusterv1alpha1.ClusterParameters) *v1alpha4.Cluster
 		}
 
 		for _, m := range node.ExtraMounts {
+			// ConfigMap and Secret mounts are copied into the node by
+			// the provider once it was created.
+			if m.HostPath == "" {
+				continue
+			}
 			mount := v1alpha4.Mount{
 				HostPath:      m.HostPath,
 				ContainerPath: m.ContainerPath,
@@ -380,3 +985,125 @@ func buildKindConfig(params clusterv1alpha1.ClusterParameters) *v1alpha4.Cluster
 
 	return cfg
 }
+
+// argoCD registers the cluster with Argo CD and reports whether its cluster
+// Secret is up to date. When apply is true the Secret is written first. A
+// cluster that authenticates with a token is not registered until the token
+// has been issued.
+func (e *external) argoCD(ctx context.Context, cr *namespacedclusterv1alpha1.Cluster, conn managed.ConnectionDetails, apply bool) (bool, error) {
+	a := cr.Spec.ForProvider.ArgoCD
+	token := ""
+	if a.Credentials == "token" {
+		if cr.Spec.ForProvider.ServiceAccountToken == nil {
+			return false, errors.New(errNSArgoCDToken)
+		}
+		token = string(conn["token"])
+		if token == "" {
+			return false, nil
+		}
+	}
+
+	clusterName := getClusterName(cr)
+	name := clusterName
+	if a.ClusterName != nil {
+		name = *a.ClusterName
+	}
+	c, err := argocd.FromKubeConfig(conn["kubeconfig"], name, token)
+	if err != nil {
+		return false, err
+	}
+	c.Labels = a.Labels
+
+	namespace, secretName := argoCDSecretName(cr)
+	if apply {
+		return true, argocd.Apply(ctx, e.kube, namespace, secretName, clusterName, c)
+	}
+	return argocd.UpToDate(ctx, e.kube, namespace, secretName, clusterName, c)
+}
+
+// argoCDSecretName returns the namespace and name of the Argo CD cluster
+// Secret. The Secret is always written to the Cluster's own namespace.
+func argoCDSecretName(cr *namespacedclusterv1alpha1.Cluster) (string, string) {
+	name := getClusterName(cr)
+	if n := cr.Spec.ForProvider.ArgoCD.SecretName; n != nil {
+		name = *n
+	}
+	return cr.GetNamespace(), name
+}
+
+// providerConfigs publishes the ProviderConfigs of the cluster and reports
+// whether they are up to date. When apply is true they are created, updated,
+// or deleted first.
+func (e *external) providerConfigs(ctx context.Context, cr *namespacedclusterv1alpha1.Cluster, apply bool) (bool, error) {
+	if cr.GetWriteConnectionSecretToReference() == nil {
+		return false, errors.New(errNSProviderConfigsSecret)
+	}
+	clusterName := getClusterName(cr)
+	upToDate := true
+	for _, pc := range publishedProviderConfigs(cr) {
+		if apply {
+			if err := pc.Sync(ctx, e.kube, clusterName); err != nil {
+				return false, err
+			}
+			continue
+		}
+		ok, err := pc.UpToDate(ctx, e.kube, clusterName)
+		if err != nil {
+			return false, err
+		}
+		upToDate = upToDate && ok
+	}
+	return upToDate, nil
+}
+
+// publishedProviderConfigs returns the ProviderConfigs publishProviderConfigs
+// configures, including the ones it disables.
+func publishedProviderConfigs(cr *namespacedclusterv1alpha1.Cluster) []providerconfigs.ProviderConfig {
+	p := cr.Spec.ForProvider.PublishProviderConfigs
+	name := getClusterName(cr)
+	if p.Name != nil {
+		name = *p.Name
+	}
+	ref := cr.GetWriteConnectionSecretToReference()
+	secretNamespace, secretName := cr.GetNamespace(), ""
+	if ref != nil {
+		secretName = ref.Name
+	}
+
+	pc := func(gvk schema.GroupVersionKind, publish *bool) providerconfigs.ProviderConfig {
+		return providerconfigs.ProviderConfig{
+			GroupVersionKind: gvk,
+			Namespace:        cr.GetNamespace(),
+			Name:             name,
+			SecretNamespace:  secretNamespace,
+			SecretName:       secretName,
+			SecretKey:        "kubeconfig",
+			Publish:          publish == nil || *publish,
+		}
+	}
+	return []providerconfigs.ProviderConfig{
+		pc(providerconfigs.KubernetesNamespaced, p.Kubernetes),
+		pc(providerconfigs.HelmNamespaced, p.Helm),
+	}
+}
+
+// syncKubeconfigSecrets writes the kubeconfig Secrets of the cluster and
+// deletes the ones of removed formats.
+func (e *external) syncKubeconfigSecrets(ctx context.Context, cr *namespacedclusterv1alpha1.Cluster, kubeconfig []byte) error {
+	secrets, err := kubeconfigSecrets(cr)
+	if err != nil {
+		return err
+	}
+	return kubeconfigsecrets.Sync(ctx, e.kube, cr, namespacedclusterv1alpha1.ClusterGroupVersionKind, secrets, kubeconfig)
+}
+
+// kubeconfigSecrets returns the kubeconfig Secrets kubeconfigSecretFormats
+// configures.
+func kubeconfigSecrets(cr *namespacedclusterv1alpha1.Cluster) ([]kubeconfigsecrets.Secret, error) {
+	clusterName := getClusterName(cr)
+	out := make([]kubeconfigsecrets.Secret, 0, len(cr.Spec.ForProvider.KubeconfigSecretFormats))
+	for _, f := range cr.Spec.ForProvider.KubeconfigSecretFormats {
+		out = append(out, kubeconfigsecrets.New(f.Format, cr.GetNamespace(), f.Name, clusterName))
+	}
+	return out, nil
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+package namespacedcluster
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+
+	clusterv1alpha1 "github.com/humoflife/provider-kind/apis/cluster/v1alpha1"
+	namespacedclusterv1alpha1 "github.com/humoflife/provider-kind/apis/namespacedcluster/v1alpha1"
+)
+
+const (
+	// maxClusterName is the longest KIND cluster name that KIND does not
+	// warn about. Node container names and hostnames append the node role
+	// to it, so longer names may not work on some systems.
+	maxClusterName = 50
+
+	// hashLength is the number of hex digits of the hash that makes names
+	// unique.
+	hashLength = 8
+)
+
+const (
+	errUpdateExternalName     = "cannot set the KIND cluster name"
+	errListClusterResources   = "cannot list Clusters"
+	errListNSClusterResources = "cannot list namespaced Clusters"
+	errNSNameConflict         = "cannot use KIND cluster name: %s"
+)
+
+// kindClusterName returns the default KIND cluster name of the managed
+// resource: its namespace and name, suffixed with a hash of both. Joining
+// them alone is ambiguous, since namespace "a-b" and name "c" would get the
+// same cluster as namespace "a" and name "b-c", so tenants in different
+// namespaces always get different clusters through the hash. Names that are
+// too long are truncated before the hash.
+func kindClusterName(namespace, name string) string {
+	h := sha256.Sum256([]byte(namespace + "/" + name))
+	prefix := namespace + "-" + name
+	if len(prefix) > maxClusterName-hashLength-1 {
+		prefix = strings.TrimRight(prefix[:maxClusterName-hashLength-1], "-.")
+	}
+	return prefix + "-" + hex.EncodeToString(h[:])[:hashLength]
+}
+
+// externalName sets the external name of new managed resources to their
+// namespace-prefixed KIND cluster name. It replaces the default initializer,
+// which would use the bare resource name.
+type externalName struct {
+	kube client.Client
+}
+
+// Initialize sets the external name if it is not set.
+func (a *externalName) Initialize(ctx context.Context, mg resource.Managed) error {
+	if meta.GetExternalName(mg) != "" {
+		return nil
+	}
+	meta.SetExternalName(mg, kindClusterName(mg.GetNamespace(), mg.GetName()))
+	return errors.Wrap(a.kube.Update(ctx, mg), errUpdateExternalName)
+}
+
+// nameConflict returns why the KIND cluster name of the managed resource
+// belongs to a cluster-scoped Cluster, or an empty string if it does not.
+// Cluster-scoped Clusters record no owner on their nodes, so every name they
+// use is theirs.
+func (e *external) nameConflict(ctx context.Context, name string) (string, error) {
+	cl := &clusterv1alpha1.ClusterList{}
+	if err := e.kube.List(ctx, cl); err != nil {
+		return "", errors.Wrap(err, errListClusterResources)
+	}
+	for i := range cl.Items {
+		c := &cl.Items[i]
+		if clusterScopedName(c) == name {
+			return fmt.Sprintf("KIND cluster %q belongs to cluster-scoped Cluster %q", name, c.GetName()), nil
+		}
+	}
+	return "", nil
+}
+
+// ownerConflict returns why an existing KIND cluster may not be adopted by
+// the managed resource, or an empty string if it may. A cluster is adopted
+// if it records the namespace of the managed resource as its owner. One that
+// records no owner was created before owners were recorded, or outside the
+// provider. It is adopted only by a managed resource that records it as its
+// external name, and only if no other managed resource claims it.
+func ownerConflict(cr *namespacedclusterv1alpha1.Cluster, name, owner string, claimed bool) string {
+	switch {
+	case owner == cr.GetNamespace():
+		return ""
+	case owner != "":
+		return fmt.Sprintf("KIND cluster %q belongs to namespace %q", name, owner)
+	case meta.GetExternalName(cr) != name:
+		return fmt.Sprintf("KIND cluster %q exists and records no owning namespace", name)
+	case claimed:
+		return fmt.Sprintf("KIND cluster %q records no owning namespace and is claimed by another Cluster", name)
+	}
+	return ""
+}
+
+// claimed reports whether a namespaced Cluster other than the managed
+// resource uses the KIND cluster name.
+func (e *external) claimed(ctx context.Context, cr *namespacedclusterv1alpha1.Cluster, name string) (bool, error) {
+	l := &namespacedclusterv1alpha1.ClusterList{}
+	if err := e.kube.List(ctx, l); err != nil {
+		return false, errors.Wrap(err, errListNSClusterResources)
+	}
+	for i := range l.Items {
+		c := &l.Items[i]
+		if c.GetUID() != cr.GetUID() && getClusterName(c) == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// clusterScopedName returns the KIND cluster name of a cluster-scoped
+// Cluster.
+func clusterScopedName(c *clusterv1alpha1.Cluster) string {
+	if name := meta.GetExternalName(c); name != "" {
+		return name
+	}
+	return c.GetName()
+}
+
+// conflicting reports a KIND cluster name that belongs to another namespace
+// or Cluster. A managed resource that is being deleted observes no cluster,
+// so that it is deleted without deleting the cluster that is not its own.
+func (e *external) conflicting(cr *namespacedclusterv1alpha1.Cluster, conflict string) (managed.ExternalObservation, error) {
+	if meta.WasDeleted(cr) {
+		return managed.ExternalObservation{ResourceExists: false}, nil
+	}
+	cr.SetConditions(clusterv1alpha1.NameConflict(conflict))
+	return managed.ExternalObservation{}, errors.Errorf(errNSNameConflict, conflict)
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+package namespacedcluster
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/test"
+
+	namespacedclusterv1alpha1 "github.com/humoflife/provider-kind/apis/namespacedcluster/v1alpha1"
+)
+
+func TestKindClusterName(t *testing.T) {
+	type args struct {
+		namespace string
+		name      string
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   string
+	}{
+		"Short": {
+			reason: "A short name should be the namespace and name, suffixed with their hash.",
+			args:   args{namespace: "my-team", name: "my-cluster"},
+			want:   "my-team-my-cluster-51eda384",
+		},
+		"AmbiguousA": {
+			reason: "Namespaces and names that join to the same string should get different hashes.",
+			args:   args{namespace: "a-b", name: "c"},
+			want:   "a-b-c-4e84717d",
+		},
+		"AmbiguousB": {
+			reason: "Namespaces and names that join to the same string should get different hashes.",
+			args:   args{namespace: "a", name: "b-c"},
+			want:   "a-b-c-b88f83c8",
+		},
+		"TooLong": {
+			reason: "A name that is too long should be truncated before the hash.",
+			args:   args{namespace: "team-" + strings.Repeat("x", 30), name: "cluster-with-a-long-name"},
+			want:   "team-" + strings.Repeat("x", 30) + "-clust-989e03cb",
+		},
+		"TruncatedAtSeparator": {
+			reason: "A truncated name should not end in a separator before the hash.",
+			args:   args{namespace: "t", name: strings.Repeat("x", 38) + "-yyyy"},
+			want:   "t-" + strings.Repeat("x", 38) + "-7ac6ab08",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := kindClusterName(tc.args.namespace, tc.args.name)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nkindClusterName(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if len(got) > maxClusterName {
+				t.Errorf("\n%s\nkindClusterName(...): %q is longer than %d characters", tc.reason, got, maxClusterName)
+			}
+		})
+	}
+}
+
+func TestOwnerConflict(t *testing.T) {
+	// A Cluster that claims to have created its KIND cluster, which tenants
+	// can forge, should not change whether it is adopted.
+	cr := &namespacedclusterv1alpha1.Cluster{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "c"}}
+	meta.SetExternalCreateSucceeded(cr, metav1.Now().Time)
+	meta.SetExternalName(cr, "shared")
+
+	type args struct {
+		name    string
+		owner   string
+		claimed bool
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   string
+	}{
+		"SameNamespace": {
+			reason: "A KIND cluster owned by the namespace of the Cluster should be adopted.",
+			args:   args{name: "shared", owner: "team-a"},
+		},
+		"OtherNamespace": {
+			reason: "A KIND cluster owned by another namespace should not be adopted.",
+			args:   args{name: "shared", owner: "team-b"},
+			want:   `KIND cluster "shared" belongs to namespace "team-b"`,
+		},
+		"NoOwner": {
+			reason: "A KIND cluster that records no owner should be adopted by the only Cluster whose external name it is.",
+			args:   args{name: "shared"},
+		},
+		"NoOwnerClaimed": {
+			reason: "A KIND cluster that records no owner should not be adopted if another Cluster claims it.",
+			args:   args{name: "shared", claimed: true},
+			want:   `KIND cluster "shared" records no owning namespace and is claimed by another Cluster`,
+		},
+		"NoOwnerOtherName": {
+			reason: "A KIND cluster that records no owner should not be adopted by a Cluster whose external name it is not.",
+			args:   args{name: "other"},
+			want:   `KIND cluster "other" exists and records no owning namespace`,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := ownerConflict(cr, tc.args.name, tc.args.owner, tc.args.claimed)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nownerConflict(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestClaimed(t *testing.T) {
+	cluster := func(ns, name, uid, externalName string) namespacedclusterv1alpha1.Cluster {
+		c := namespacedclusterv1alpha1.Cluster{ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name, UID: types.UID(uid)}}
+		meta.SetExternalName(&c, externalName)
+		return c
+	}
+	cr := cluster("team-a", "c", "a", "shared")
+
+	cases := map[string]struct {
+		reason string
+		others []namespacedclusterv1alpha1.Cluster
+		want   bool
+	}{
+		"Unclaimed": {
+			reason: "A KIND cluster name that only the Cluster itself uses should not be claimed.",
+			others: []namespacedclusterv1alpha1.Cluster{cr, cluster("team-b", "c", "b", "other")},
+		},
+		"Claimed": {
+			reason: "A KIND cluster name that a Cluster in another namespace uses should be claimed.",
+			others: []namespacedclusterv1alpha1.Cluster{cr, cluster("team-b", "c", "b", "shared")},
+			want:   true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			e := &external{kube: &test.MockClient{MockList: func(_ context.Context, obj client.ObjectList, _ ...client.ListOption) error {
+				obj.(*namespacedclusterv1alpha1.ClusterList).Items = tc.others
+				return nil
+			}}}
+			got, err := e.claimed(context.Background(), &cr, "shared")
+			if err != nil {
+				t.Fatalf("\n%s\nclaimed(...): %v", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nclaimed(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,280 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+// Package network implements the Crossplane managed reconciler for Docker
+// networks used by KIND clusters.
+package network
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/v2/apis/common/v1"
+	xpcontroller "github.com/crossplane/crossplane-runtime/v2/pkg/controller"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/event"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/ratelimiter"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+
+	networkv1alpha1 "github.com/humoflife/provider-kind/apis/network/v1alpha1"
+	"github.com/humoflife/provider-kind/apis/v1beta1"
+	"github.com/humoflife/provider-kind/internal/docker"
+)
+
+const (
+	errNotNetwork     = "managed resource is not a Network custom resource"
+	errTrackUsage     = "cannot track ProviderConfig usage"
+	errInspectNetwork = "cannot inspect Docker network"
+	errCreateNetwork  = "cannot create Docker network"
+	errDeleteNetwork  = "cannot delete Docker network"
+	errNetworkInUse   = "cannot recreate Docker network %q: %d containers are attached"
+	errNotOwned       = "Docker network %q exists and does not belong to this Network"
+)
+
+const (
+	// LabelNetwork is set on Docker networks to the name of the Network
+	// that owns them.
+	LabelNetwork = "kind.crossplane.io/network"
+
+	// labelConfigHash records the configuration a network was created
+	// with, so that changes can be detected.
+	labelConfigHash = "kind.crossplane.io/config-hash"
+)
+
+// Setup adds a controller that reconciles Network managed resources.
+func Setup(mgr ctrl.Manager, o xpcontroller.Options) error {
+	name := managed.ControllerName(networkv1alpha1.NetworkGroupVersionKind.String())
+
+	reconcilerOpts := []managed.ReconcilerOption{
+		managed.WithExternalConnecter(&connector{kube: mgr.GetClient()}),
+		managed.WithLogger(o.Logger.WithValues("controller", name)),
+		managed.WithRecorder(event.NewAPIRecorder(mgr.GetEventRecorderFor(name))),
+		managed.WithPollInterval(o.PollInterval),
+	}
+
+	r := managed.NewReconciler(mgr,
+		resource.ManagedKind(networkv1alpha1.NetworkGroupVersionKind),
+		reconcilerOpts...,
+	)
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(name).
+		WithOptions(o.ForControllerRuntime()).
+		For(&networkv1alpha1.Network{}).
+		Complete(ratelimiter.NewReconciler(name, r, o.GlobalRateLimiter))
+}
+
+// connector creates an external client for each reconcile.
+type connector struct {
+	kube client.Client
+}
+
+func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.ExternalClient, error) {
+	cr, ok := mg.(*networkv1alpha1.Network)
+	if !ok {
+		return nil, errors.New(errNotNetwork)
+	}
+
+	tracker := resource.NewLegacyProviderConfigUsageTracker(c.kube, &v1beta1.ProviderConfigUsage{})
+	if err := tracker.Track(ctx, cr); err != nil {
+		return nil, errors.Wrap(err, errTrackUsage)
+	}
+
+	return &external{}, nil
+}
+
+// external implements managed.ExternalClient for Docker networks.
+type external struct{}
+
+// Observe inspects the Docker network.
+func (e *external) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
+	cr, ok := mg.(*networkv1alpha1.Network)
+	if !ok {
+		return managed.ExternalObservation{}, errors.New(errNotNetwork)
+	}
+
+	n, err := docker.InspectNetwork(ctx, GetNetworkName(cr))
+	if err != nil {
+		return managed.ExternalObservation{}, errors.Wrap(err, errInspectNetwork)
+	}
+	if n == nil {
+		return managed.ExternalObservation{ResourceExists: false}, nil
+	}
+
+	// Networks that were not created for this Network, such as KIND's own
+	// kind network, are never adopted, so they are not recreated or removed
+	// either.
+	if !owned(n, cr) {
+		if meta.WasDeleted(cr) {
+			return managed.ExternalObservation{ResourceExists: false}, nil
+		}
+		return managed.ExternalObservation{}, errors.Errorf(errNotOwned, n.Name)
+	}
+
+	obs := networkv1alpha1.NetworkObservation{
+		ID:         n.ID,
+		Name:       n.Name,
+		Containers: len(n.Containers),
+	}
+	for _, c := range n.IPAM.Config {
+		obs.Subnets = append(obs.Subnets, c.Subnet)
+	}
+	cr.Status.AtProvider = obs
+	cr.SetConditions(xpv1.Available())
+
+	return managed.ExternalObservation{
+		ResourceExists:   true,
+		ResourceUpToDate: n.Labels[labelConfigHash] == configHash(createArgs(cr)),
+	}, nil
+}
+
+// Create creates the Docker network.
+func (e *external) Create(ctx context.Context, mg resource.Managed) (managed.ExternalCreation, error) {
+	cr, ok := mg.(*networkv1alpha1.Network)
+	if !ok {
+		return managed.ExternalCreation{}, errors.New(errNotNetwork)
+	}
+
+	cr.SetConditions(xpv1.Creating())
+
+	name := GetNetworkName(cr)
+	meta.SetExternalName(cr, name)
+
+	return managed.ExternalCreation{}, create(ctx, cr)
+}
+
+// Update recreates the Docker network with the desired configuration. Docker
+// cannot remove a network with attached containers, so the clusters on it
+// must be deleted first.
+func (e *external) Update(ctx context.Context, mg resource.Managed) (managed.ExternalUpdate, error) {
+	cr, ok := mg.(*networkv1alpha1.Network)
+	if !ok {
+		return managed.ExternalUpdate{}, errors.New(errNotNetwork)
+	}
+
+	name := GetNetworkName(cr)
+	n, err := docker.InspectNetwork(ctx, name)
+	if err != nil {
+		return managed.ExternalUpdate{}, errors.Wrap(err, errInspectNetwork)
+	}
+	if n != nil {
+		if !owned(n, cr) {
+			return managed.ExternalUpdate{}, errors.Errorf(errNotOwned, name)
+		}
+		if len(n.Containers) > 0 {
+			return managed.ExternalUpdate{}, errors.Errorf(errNetworkInUse, name, len(n.Containers))
+		}
+		if err := docker.RemoveNetwork(ctx, name); err != nil {
+			return managed.ExternalUpdate{}, errors.Wrap(err, errDeleteNetwork)
+		}
+	}
+
+	return managed.ExternalUpdate{}, create(ctx, cr)
+}
+
+// Delete removes the Docker network. This fails while containers, such as
+// cluster nodes, are attached to it.
+func (e *external) Delete(ctx context.Context, mg resource.Managed) (managed.ExternalDelete, error) {
+	cr, ok := mg.(*networkv1alpha1.Network)
+	if !ok {
+		return managed.ExternalDelete{}, errors.New(errNotNetwork)
+	}
+
+	cr.SetConditions(xpv1.Deleting())
+
+	name := GetNetworkName(cr)
+	n, err := docker.InspectNetwork(ctx, name)
+	if err != nil {
+		return managed.ExternalDelete{}, errors.Wrap(err, errInspectNetwork)
+	}
+	if n == nil || !owned(n, cr) {
+		return managed.ExternalDelete{}, nil
+	}
+	if err := docker.RemoveNetwork(ctx, name); err != nil {
+		return managed.ExternalDelete{}, errors.Wrap(err, errDeleteNetwork)
+	}
+	return managed.ExternalDelete{}, nil
+}
+
+// Disconnect is a no-op because the Docker CLI holds no persistent connection.
+func (e *external) Disconnect(_ context.Context) error {
+	return nil
+}
+
+// create creates the Docker network.
+func create(ctx context.Context, cr *networkv1alpha1.Network) error {
+	args := createArgs(cr)
+	full := []string{"network", "create",
+		"--label", LabelNetwork + "=" + cr.GetName(),
+		"--label", labelConfigHash + "=" + configHash(args),
+	}
+	full = append(full, args...)
+	full = append(full, GetNetworkName(cr))
+	if _, err := docker.Run(ctx, full...); err != nil {
+		return errors.Wrap(err, errCreateNetwork)
+	}
+	return nil
+}
+
+// createArgs returns the docker network create arguments derived from the
+// Network spec. They match the options KIND uses for its own network.
+func createArgs(cr *networkv1alpha1.Network) []string {
+	p := cr.Spec.ForProvider
+	args := []string{"--driver=bridge", "--opt=com.docker.network.bridge.enable_ip_masquerade=true"}
+
+	if p.Subnet != nil {
+		args = append(args, "--subnet="+*p.Subnet)
+		if p.Gateway != nil {
+			args = append(args, "--gateway="+*p.Gateway)
+		}
+	}
+	if p.IPv6 != nil && *p.IPv6 {
+		args = append(args, "--ipv6")
+		if p.IPv6Subnet != nil {
+			args = append(args, "--subnet="+*p.IPv6Subnet)
+		}
+	}
+	if p.MTU != nil {
+		args = append(args, fmt.Sprintf("--opt=com.docker.network.driver.mtu=%d", *p.MTU))
+	}
+
+	keys := make([]string, 0, len(p.Labels))
+	for k := range p.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		args = append(args, "--label="+k+"="+p.Labels[k])
+	}
+	return args
+}
+
+// configHash returns a digest of the docker network create arguments.
+func configHash(args []string) string {
+	h := sha256.Sum256([]byte(strings.Join(args, "\x00")))
+	return hex.EncodeToString(h[:])
+}
+
+// GetNetworkName returns the external name of the network, falling back to
+// the managed resource name if no external name has been set.
+func GetNetworkName(cr *networkv1alpha1.Network) string {
+	if name := meta.GetExternalName(cr); name != "" {
+		return name
+	}
+	return cr.GetName()
+}
+
+// owned reports whether the Docker network was created for the Network.
+func owned(n *docker.Network, cr *networkv1alpha1.Network) bool {
+	return n.Labels[LabelNetwork] == cr.GetName()
+}
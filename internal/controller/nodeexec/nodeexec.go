@@ -0,0 +1,272 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+// Package nodeexec implements the Crossplane managed reconciler for scripts
+// run inside KIND cluster nodes.
+package nodeexec
+
+import (
+	"context"
+	"slices"
+	"sort"
+
+	"github.com/pkg/errors"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	kindcluster "sigs.k8s.io/kind/pkg/cluster"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/v2/apis/common/v1"
+	xpcontroller "github.com/crossplane/crossplane-runtime/v2/pkg/controller"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/event"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/ratelimiter"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+
+	nodeexecv1alpha1 "github.com/humoflife/provider-kind/apis/nodeexec/v1alpha1"
+	"github.com/humoflife/provider-kind/apis/v1beta1"
+	"github.com/humoflife/provider-kind/internal/kindnode"
+	"github.com/humoflife/provider-kind/internal/sources"
+)
+
+const (
+	errNotNodeExec = "managed resource is not a NodeExec custom resource"
+	errTrackUsage  = "cannot track ProviderConfig usage"
+	errGetNodes    = "cannot list KIND cluster nodes"
+	errNoNodes     = "no nodes of KIND cluster %q match the node selector"
+)
+
+// Setup adds a controller that reconciles NodeExec managed resources.
+func Setup(mgr ctrl.Manager, o xpcontroller.Options) error {
+	name := managed.ControllerName(nodeexecv1alpha1.NodeExecGroupVersionKind.String())
+
+	reconcilerOpts := []managed.ReconcilerOption{
+		managed.WithExternalConnecter(&connector{kube: mgr.GetClient()}),
+		managed.WithLogger(o.Logger.WithValues("controller", name)),
+		managed.WithRecorder(event.NewAPIRecorder(mgr.GetEventRecorderFor(name))),
+		managed.WithPollInterval(o.PollInterval),
+	}
+
+	r := managed.NewReconciler(mgr,
+		resource.ManagedKind(nodeexecv1alpha1.NodeExecGroupVersionKind),
+		reconcilerOpts...,
+	)
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(name).
+		WithOptions(o.ForControllerRuntime()).
+		For(&nodeexecv1alpha1.NodeExec{}).
+		Complete(ratelimiter.NewReconciler(name, r, o.GlobalRateLimiter))
+}
+
+// connector creates a KIND cluster provider for each reconcile.
+type connector struct {
+	kube client.Client
+}
+
+func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.ExternalClient, error) {
+	cr, ok := mg.(*nodeexecv1alpha1.NodeExec)
+	if !ok {
+		return nil, errors.New(errNotNodeExec)
+	}
+
+	tracker := resource.NewLegacyProviderConfigUsageTracker(c.kube, &v1beta1.ProviderConfigUsage{})
+	if err := tracker.Track(ctx, cr); err != nil {
+		return nil, errors.Wrap(err, errTrackUsage)
+	}
+
+	return &external{provider: kindcluster.NewProvider(), kube: c.kube}, nil
+}
+
+// external implements managed.ExternalClient for NodeExecs.
+type external struct {
+	provider *kindcluster.Provider
+	kube     client.Client
+}
+
+// Observe checks that the current script ran successfully on every selected
+// node. Nodes that were added or recreated since have not run it.
+func (e *external) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
+	cr, ok := mg.(*nodeexecv1alpha1.NodeExec)
+	if !ok {
+		return managed.ExternalObservation{}, errors.New(errNotNodeExec)
+	}
+
+	clusterName, err := e.clusterName(ctx, cr)
+	if err != nil {
+		return managed.ExternalObservation{}, err
+	}
+	cr.Status.AtProvider.ClusterName = clusterName
+
+	targets, err := e.nodes(cr, clusterName)
+	if err != nil {
+		return managed.ExternalObservation{}, err
+	}
+
+	ran := []string{}
+	for _, n := range targets {
+		if kindnode.HasRunScript(n, cr.GetName(), cr.Spec.ForProvider.Script) {
+			ran = append(ran, n.String())
+		}
+	}
+	cr.Status.AtProvider.Nodes = ran
+
+	upToDate := len(ran) == len(targets)
+	if upToDate {
+		cr.SetConditions(xpv1.Available())
+	} else {
+		cr.SetConditions(xpv1.Unavailable())
+	}
+
+	// The script exists once it ran anywhere, or failed to, so that failures
+	// are retried by updates. A deleted script exists until it was forgotten.
+	exists := len(ran) > 0 || (len(cr.Status.AtProvider.Results) > 0 && !meta.WasDeleted(cr))
+
+	return managed.ExternalObservation{
+		ResourceExists:   exists,
+		ResourceUpToDate: upToDate,
+	}, nil
+}
+
+// Create runs the script on the selected nodes.
+func (e *external) Create(ctx context.Context, mg resource.Managed) (managed.ExternalCreation, error) {
+	cr, ok := mg.(*nodeexecv1alpha1.NodeExec)
+	if !ok {
+		return managed.ExternalCreation{}, errors.New(errNotNodeExec)
+	}
+
+	cr.SetConditions(xpv1.Creating())
+
+	return managed.ExternalCreation{}, e.run(ctx, cr)
+}
+
+// Update runs the script on selected nodes that have not run it, or all of
+// them if it changed.
+func (e *external) Update(ctx context.Context, mg resource.Managed) (managed.ExternalUpdate, error) {
+	cr, ok := mg.(*nodeexecv1alpha1.NodeExec)
+	if !ok {
+		return managed.ExternalUpdate{}, errors.New(errNotNodeExec)
+	}
+
+	return managed.ExternalUpdate{}, e.run(ctx, cr)
+}
+
+// Delete forgets that the script ran on the nodes, so a NodeExec of the same
+// name runs again. The effects of the script are not undone. Nothing is
+// forgotten if the cluster no longer exists.
+func (e *external) Delete(ctx context.Context, mg resource.Managed) (managed.ExternalDelete, error) {
+	cr, ok := mg.(*nodeexecv1alpha1.NodeExec)
+	if !ok {
+		return managed.ExternalDelete{}, errors.New(errNotNodeExec)
+	}
+
+	cr.SetConditions(xpv1.Deleting())
+
+	clusterName, err := e.clusterName(ctx, cr)
+	if kerrors.IsNotFound(errors.Cause(err)) {
+		return managed.ExternalDelete{}, nil
+	}
+	if err != nil {
+		return managed.ExternalDelete{}, err
+	}
+
+	all, err := e.provider.ListNodes(clusterName)
+	if err != nil {
+		return managed.ExternalDelete{}, errors.Wrap(err, errGetNodes)
+	}
+
+	for _, n := range kindnode.Select(all, selector(cr)) {
+		if err := kindnode.ForgetScript(n, cr.GetName()); err != nil {
+			return managed.ExternalDelete{}, err
+		}
+	}
+	return managed.ExternalDelete{}, nil
+}
+
+// Disconnect is a no-op because the KIND provider uses the local Docker daemon
+// and holds no persistent connection that needs to be cleaned up.
+func (e *external) Disconnect(_ context.Context) error {
+	return nil
+}
+
+// run runs the script on every selected node that has not run it, and
+// records the results. Every node is tried; the first failure is returned.
+func (e *external) run(ctx context.Context, cr *nodeexecv1alpha1.NodeExec) error {
+	clusterName, err := e.clusterName(ctx, cr)
+	if err != nil {
+		return err
+	}
+	targets, err := e.nodes(cr, clusterName)
+	if err != nil {
+		return err
+	}
+
+	results := map[string]nodeexecv1alpha1.NodeExecResult{}
+	for _, r := range cr.Status.AtProvider.Results {
+		results[r.Node] = r
+	}
+
+	var failed error
+	names := make([]string, 0, len(targets))
+	for _, n := range targets {
+		names = append(names, n.String())
+		if kindnode.HasRunScript(n, cr.GetName(), cr.Spec.ForProvider.Script) {
+			continue
+		}
+		r, err := kindnode.RunScript(n, cr.GetName(), cr.Spec.ForProvider.Script)
+		now := metav1.Now()
+		results[n.String()] = nodeexecv1alpha1.NodeExecResult{
+			Node:     n.String(),
+			ExitCode: int32(r.ExitCode),
+			Stdout:   r.Stdout,
+			Stderr:   r.Stderr,
+			RunTime:  &now,
+		}
+		if err != nil && failed == nil {
+			failed = err
+		}
+	}
+
+	// Results of nodes that were removed or are no longer selected are
+	// dropped.
+	out := make([]nodeexecv1alpha1.NodeExecResult, 0, len(results))
+	for node, r := range results {
+		if slices.Contains(names, node) {
+			out = append(out, r)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Node < out[j].Node })
+	cr.Status.AtProvider.Results = out
+
+	return failed
+}
+
+// clusterName returns the KIND cluster name of the referenced Cluster.
+func (e *external) clusterName(ctx context.Context, cr *nodeexecv1alpha1.NodeExec) (string, error) {
+	return sources.Resolver{Client: e.kube}.ClusterName(ctx, cr.Spec.ForProvider.ClusterRef.Name)
+}
+
+// nodes returns the selected nodes of the cluster.
+func (e *external) nodes(cr *nodeexecv1alpha1.NodeExec, clusterName string) ([]nodes.Node, error) {
+	all, err := e.provider.ListNodes(clusterName)
+	if err != nil {
+		return nil, errors.Wrap(err, errGetNodes)
+	}
+	targets := kindnode.Select(all, selector(cr))
+	if len(targets) == 0 {
+		return nil, errors.Errorf(errNoNodes, clusterName)
+	}
+	return targets, nil
+}
+
+// selector returns the node selector of the NodeExec.
+func selector(cr *nodeexecv1alpha1.NodeExec) kindnode.Selector {
+	if sel := cr.Spec.ForProvider.NodeSelector; sel != nil {
+		return kindnode.Selector{Roles: sel.Roles, Names: sel.Names}
+	}
+	return kindnode.Selector{}
+}
@@ -0,0 +1,404 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+// Package nodeimagecache implements the Crossplane managed reconciler for
+// KIND node images cached on the Docker host.
+package nodeimagecache
+
+import (
+	"context"
+	"slices"
+
+	"github.com/pkg/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/kind/pkg/apis/config/defaults"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/v2/apis/common/v1"
+	xpcontroller "github.com/crossplane/crossplane-runtime/v2/pkg/controller"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/event"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/ratelimiter"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+
+	clusterv1alpha1 "github.com/humoflife/provider-kind/apis/cluster/v1alpha1"
+	namespacedclusterv1alpha1 "github.com/humoflife/provider-kind/apis/namespacedcluster/v1alpha1"
+	nodeimagecachev1alpha1 "github.com/humoflife/provider-kind/apis/nodeimagecache/v1alpha1"
+	"github.com/humoflife/provider-kind/apis/v1beta1"
+	"github.com/humoflife/provider-kind/internal/docker"
+)
+
+const (
+	errNotNodeImageCache = "managed resource is not a NodeImageCache custom resource"
+	errTrackUsage        = "cannot track ProviderConfig usage"
+	errInspectImage      = "cannot inspect image"
+	errRemoveImage       = "cannot remove image %q"
+	errListClusters      = "cannot list Clusters"
+)
+
+// Setup adds a controller that reconciles NodeImageCache managed resources.
+func Setup(mgr ctrl.Manager, o xpcontroller.Options) error {
+	name := managed.ControllerName(nodeimagecachev1alpha1.NodeImageCacheGroupVersionKind.String())
+
+	reconcilerOpts := []managed.ReconcilerOption{
+		managed.WithExternalConnecter(&connector{kube: mgr.GetClient(), pulls: newPulls()}),
+		managed.WithLogger(o.Logger.WithValues("controller", name)),
+		managed.WithRecorder(event.NewAPIRecorder(mgr.GetEventRecorderFor(name))),
+		managed.WithPollInterval(o.PollInterval),
+	}
+
+	r := managed.NewReconciler(mgr,
+		resource.ManagedKind(nodeimagecachev1alpha1.NodeImageCacheGroupVersionKind),
+		reconcilerOpts...,
+	)
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(name).
+		WithOptions(o.ForControllerRuntime()).
+		For(&nodeimagecachev1alpha1.NodeImageCache{}).
+		Complete(ratelimiter.NewReconciler(name, r, o.GlobalRateLimiter))
+}
+
+// connector creates an external client for each reconcile. Pulls outlive a
+// reconcile, so they are tracked by the connector.
+type connector struct {
+	kube  client.Client
+	pulls *pulls
+}
+
+func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.ExternalClient, error) {
+	cr, ok := mg.(*nodeimagecachev1alpha1.NodeImageCache)
+	if !ok {
+		return nil, errors.New(errNotNodeImageCache)
+	}
+
+	tracker := resource.NewLegacyProviderConfigUsageTracker(c.kube, &v1beta1.ProviderConfigUsage{})
+	if err := tracker.Track(ctx, cr); err != nil {
+		return nil, errors.Wrap(err, errTrackUsage)
+	}
+
+	return &external{kube: c.kube, pulls: c.pulls}, nil
+}
+
+// external implements managed.ExternalClient for node image caches.
+type external struct {
+	kube  client.Client
+	pulls *pulls
+}
+
+// Observe reports the pull state and size of every image.
+func (e *external) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
+	cr, ok := mg.(*nodeimagecachev1alpha1.NodeImageCache)
+	if !ok {
+		return managed.ExternalObservation{}, errors.New(errNotNodeImageCache)
+	}
+
+	// Images are only removed on deletion if they are garbage-collected,
+	// so the cache exists as long as there is something left to collect.
+	if meta.WasDeleted(cr) {
+		collectable, err := e.collectable(ctx, cr)
+		if err != nil {
+			return managed.ExternalObservation{}, err
+		}
+		return managed.ExternalObservation{ResourceExists: len(collectable) > 0}, nil
+	}
+
+	p := cr.Spec.ForProvider
+	obs := nodeimagecachev1alpha1.NodeImageCacheObservation{}
+	exists := false
+	allPulled := true
+
+	for _, ref := range p.Images {
+		img, err := docker.InspectImage(ctx, ref)
+		if err != nil {
+			return managed.ExternalObservation{}, errors.Wrap(err, errInspectImage)
+		}
+		o := nodeimagecachev1alpha1.CachedImageObservation{Image: ref, State: nodeimagecachev1alpha1.ImagePending}
+		switch {
+		case img != nil:
+			o.State = nodeimagecachev1alpha1.ImagePulled
+			o.ID = img.ID
+			o.SizeBytes = img.Size
+			obs.TotalSizeBytes += img.Size
+			e.pulls.forget(ref)
+			exists = true
+		default:
+			if s, ok := e.pulls.get(ref); ok {
+				o.State, o.Progress, o.Message = s.state, s.progress, s.message
+				exists = exists || s.state == nodeimagecachev1alpha1.ImagePulling
+			}
+			allPulled = false
+		}
+		obs.Images = append(obs.Images, o)
+	}
+
+	// Images dropped from the spec stay in the status until they are gone
+	// from the Docker host, so garbage collection can find them.
+	unreferenced, err := e.unreferenced(ctx, cr)
+	if err != nil {
+		return managed.ExternalObservation{}, err
+	}
+	collect := false
+	for _, u := range unreferenced {
+		obs.Images = append(obs.Images, u.observation)
+		obs.TotalSizeBytes += u.observation.SizeBytes
+		collect = collect || u.collectable
+	}
+	cr.Status.AtProvider = obs
+
+	if allPulled {
+		cr.SetConditions(xpv1.Available())
+	} else {
+		cr.SetConditions(xpv1.Unavailable())
+	}
+
+	return managed.ExternalObservation{
+		ResourceExists:   exists,
+		ResourceUpToDate: allPulled && !collect,
+	}, nil
+}
+
+// Create starts pulling the images.
+func (e *external) Create(ctx context.Context, mg resource.Managed) (managed.ExternalCreation, error) {
+	cr, ok := mg.(*nodeimagecachev1alpha1.NodeImageCache)
+	if !ok {
+		return managed.ExternalCreation{}, errors.New(errNotNodeImageCache)
+	}
+
+	cr.SetConditions(xpv1.Creating())
+
+	return managed.ExternalCreation{}, e.pull(ctx, cr)
+}
+
+// Update starts pulling missing images and garbage-collects images that
+// were removed from the spec.
+func (e *external) Update(ctx context.Context, mg resource.Managed) (managed.ExternalUpdate, error) {
+	cr, ok := mg.(*nodeimagecachev1alpha1.NodeImageCache)
+	if !ok {
+		return managed.ExternalUpdate{}, errors.New(errNotNodeImageCache)
+	}
+
+	if err := e.pull(ctx, cr); err != nil {
+		return managed.ExternalUpdate{}, err
+	}
+
+	unreferenced, err := e.unreferenced(ctx, cr)
+	if err != nil {
+		return managed.ExternalUpdate{}, err
+	}
+	for _, u := range unreferenced {
+		if !u.collectable {
+			continue
+		}
+		if err := removeImage(ctx, u.observation.Image); err != nil {
+			return managed.ExternalUpdate{}, err
+		}
+	}
+	return managed.ExternalUpdate{}, nil
+}
+
+// Delete garbage-collects every cached image that neither a Cluster nor a
+// container uses, if garbage collection is enabled. Otherwise the images are
+// left in place.
+func (e *external) Delete(ctx context.Context, mg resource.Managed) (managed.ExternalDelete, error) {
+	cr, ok := mg.(*nodeimagecachev1alpha1.NodeImageCache)
+	if !ok {
+		return managed.ExternalDelete{}, errors.New(errNotNodeImageCache)
+	}
+
+	cr.SetConditions(xpv1.Deleting())
+
+	collectable, err := e.collectable(ctx, cr)
+	if err != nil {
+		return managed.ExternalDelete{}, err
+	}
+	for _, ref := range collectable {
+		if err := removeImage(ctx, ref); err != nil {
+			return managed.ExternalDelete{}, err
+		}
+	}
+	return managed.ExternalDelete{}, nil
+}
+
+// Disconnect is a no-op because the Docker CLI holds no persistent connection.
+func (e *external) Disconnect(_ context.Context) error {
+	return nil
+}
+
+// collectable returns every cached image that garbage collection would
+// remove when the cache is deleted. It is empty unless garbage collection is
+// enabled.
+func (e *external) collectable(ctx context.Context, cr *nodeimagecachev1alpha1.NodeImageCache) ([]string, error) {
+	if !garbageCollect(cr) {
+		return nil, nil
+	}
+	referenced, err := e.referencedImages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, o := range cr.Status.AtProvider.Images {
+		ok, err := unused(ctx, o.Image, referenced)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out = append(out, o.Image)
+		}
+	}
+	return out, nil
+}
+
+// unused reports whether the image is present, and used by neither a
+// Cluster nor a container.
+func unused(ctx context.Context, ref string, referenced map[string]bool) (bool, error) {
+	img, err := docker.InspectImage(ctx, ref)
+	if err != nil {
+		return false, errors.Wrap(err, errInspectImage)
+	}
+	if img == nil || referenced[img.ID] {
+		return false, nil
+	}
+	inUse, err := docker.ImageInUse(ctx, img.ID)
+	if err != nil {
+		return false, errors.Wrap(err, errInspectImage)
+	}
+	return !inUse, nil
+}
+
+// pull starts a background pull of every image missing from the Docker
+// host. Pulls run detached from the reconcile so that large node images do
+// not block it; Observe reports their progress.
+func (e *external) pull(ctx context.Context, cr *nodeimagecachev1alpha1.NodeImageCache) error {
+	for _, ref := range cr.Spec.ForProvider.Images {
+		img, err := docker.InspectImage(ctx, ref)
+		if err != nil {
+			return errors.Wrap(err, errInspectImage)
+		}
+		if img == nil {
+			e.pulls.start(ref)
+		}
+	}
+	return nil
+}
+
+// unreferencedImage is a cached image that was removed from the spec.
+type unreferencedImage struct {
+	observation nodeimagecachev1alpha1.CachedImageObservation
+
+	// collectable is true if garbage collection is enabled and neither a
+	// Cluster nor a container uses the image.
+	collectable bool
+}
+
+// unreferenced returns the previously cached images that are no longer in
+// the spec but still present on the Docker host.
+func (e *external) unreferenced(ctx context.Context, cr *nodeimagecachev1alpha1.NodeImageCache) ([]unreferencedImage, error) {
+	var referenced map[string]bool
+	var out []unreferencedImage
+
+	for _, prev := range cr.Status.AtProvider.Images {
+		if slices.Contains(cr.Spec.ForProvider.Images, prev.Image) {
+			continue
+		}
+		img, err := docker.InspectImage(ctx, prev.Image)
+		if err != nil {
+			return nil, errors.Wrap(err, errInspectImage)
+		}
+		if img == nil {
+			continue
+		}
+		u := unreferencedImage{observation: nodeimagecachev1alpha1.CachedImageObservation{
+			Image:     prev.Image,
+			State:     nodeimagecachev1alpha1.ImageUnreferenced,
+			ID:        img.ID,
+			SizeBytes: img.Size,
+		}}
+		if garbageCollect(cr) {
+			if referenced == nil {
+				if referenced, err = e.referencedImages(ctx); err != nil {
+					return nil, err
+				}
+			}
+			if u.collectable, err = unused(ctx, prev.Image, referenced); err != nil {
+				return nil, err
+			}
+		}
+		out = append(out, u)
+	}
+	return out, nil
+}
+
+// referencedImages returns the Docker image IDs of the node images used by
+// any Cluster, cluster-scoped or namespaced.
+func (e *external) referencedImages(ctx context.Context) (map[string]bool, error) {
+	var params []clusterv1alpha1.ClusterParameters
+	var nodes []clusterv1alpha1.NodeObservation
+
+	cl := &clusterv1alpha1.ClusterList{}
+	if err := e.kube.List(ctx, cl); err != nil {
+		return nil, errors.Wrap(err, errListClusters)
+	}
+	for _, c := range cl.Items {
+		params = append(params, c.Spec.ForProvider)
+		nodes = append(nodes, c.Status.AtProvider.Nodes...)
+	}
+
+	nl := &namespacedclusterv1alpha1.ClusterList{}
+	if err := e.kube.List(ctx, nl); err != nil {
+		return nil, errors.Wrap(err, errListClusters)
+	}
+	for _, c := range nl.Items {
+		params = append(params, c.Spec.ForProvider)
+		nodes = append(nodes, c.Status.AtProvider.Nodes...)
+	}
+
+	refs := map[string]bool{}
+	for _, p := range params {
+		if len(p.Nodes) == 0 {
+			refs[defaults.Image] = true
+		}
+		for _, n := range p.Nodes {
+			if n.Image != nil {
+				refs[*n.Image] = true
+			} else {
+				refs[defaults.Image] = true
+			}
+		}
+	}
+	for _, n := range nodes {
+		if n.Image != "" {
+			refs[n.Image] = true
+		}
+	}
+
+	ids := map[string]bool{}
+	for ref := range refs {
+		id, err := docker.ImageID(ctx, ref)
+		if err != nil {
+			return nil, errors.Wrap(err, errInspectImage)
+		}
+		if id != "" {
+			ids[id] = true
+		}
+	}
+	return ids, nil
+}
+
+// removeImage removes the image from the Docker host. Images still used by
+// a container, such as a node of a Cluster not managed by this provider,
+// are kept.
+func removeImage(ctx context.Context, ref string) error {
+	err := docker.RemoveImage(ctx, ref)
+	if docker.IsConflict(err) {
+		return nil
+	}
+	return errors.Wrapf(err, errRemoveImage, ref)
+}
+
+// garbageCollect reports whether unreferenced images should be removed.
+func garbageCollect(cr *nodeimagecachev1alpha1.NodeImageCache) bool {
+	return cr.Spec.ForProvider.GarbageCollect != nil && *cr.Spec.ForProvider.GarbageCollect
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+package nodeimagecache
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	nodeimagecachev1alpha1 "github.com/humoflife/provider-kind/apis/nodeimagecache/v1alpha1"
+	"github.com/humoflife/provider-kind/internal/docker"
+)
+
+// layerLine matches the per-layer lines of docker pull output, for example
+// "a2abf6c4d29d: Pull complete".
+var layerLine = regexp.MustCompile(`^([0-9a-f]{12}): (.+)$`)
+
+// pullStatus is the state of a background image pull.
+type pullStatus struct {
+	state    string
+	progress string
+	message  string
+}
+
+// pull tracks a single background image pull.
+type pull struct {
+	layers map[string]bool
+	failed bool
+	err    string
+}
+
+// pulls tracks background image pulls by image reference.
+type pulls struct {
+	mu sync.Mutex
+	m  map[string]*pull
+}
+
+func newPulls() *pulls {
+	return &pulls{m: map[string]*pull{}}
+}
+
+// start pulls the image in the background, unless a pull of it is already
+// running. A failed pull is retried.
+func (p *pulls) start(ref string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if cur, ok := p.m[ref]; ok && !cur.failed {
+		return
+	}
+	cur := &pull{layers: map[string]bool{}}
+	p.m[ref] = cur
+
+	go func() {
+		err := docker.PullImage(context.Background(), ref, func(line string) {
+			m := layerLine.FindStringSubmatch(line)
+			if m == nil {
+				return
+			}
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			cur.layers[m[1]] = m[2] == "Pull complete" || m[2] == "Already exists"
+		})
+		if err == nil {
+			return
+		}
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		cur.failed = true
+		cur.err = strings.TrimSpace(err.Error())
+	}()
+}
+
+// get returns the status of the pull of the image, if one was started.
+func (p *pulls) get(ref string) (pullStatus, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	cur, ok := p.m[ref]
+	if !ok {
+		return pullStatus{}, false
+	}
+	if cur.failed {
+		return pullStatus{state: nodeimagecachev1alpha1.ImageFailed, message: cur.err}, true
+	}
+	done := 0
+	for _, complete := range cur.layers {
+		if complete {
+			done++
+		}
+	}
+	s := pullStatus{state: nodeimagecachev1alpha1.ImagePulling}
+	if len(cur.layers) > 0 {
+		s.progress = fmt.Sprintf("%d/%d layers", done, len(cur.layers))
+	}
+	return s, true
+}
+
+// forget stops tracking the pull of the image once it is present.
+func (p *pulls) forget(ref string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if cur, ok := p.m[ref]; ok && !cur.failed {
+		delete(p.m, ref)
+	}
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+package providerconfig
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	xpcontroller "github.com/crossplane/crossplane-runtime/v2/pkg/controller"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/reconciler/providerconfig"
+
+	clusterv1alpha1 "github.com/humoflife/provider-kind/apis/cluster/v1alpha1"
+	namespacedclusterv1alpha1 "github.com/humoflife/provider-kind/apis/namespacedcluster/v1alpha1"
+	"github.com/humoflife/provider-kind/apis/v1beta1"
+	"github.com/humoflife/provider-kind/internal/quota"
+)
+
+const (
+	errGetProviderConfig = "cannot get ProviderConfig"
+	errCountUsage        = "cannot count ProviderConfig usage"
+	errUpdateStatus      = "cannot update ProviderConfig status"
+)
+
+// SetupUsage adds a controller that reports the clusters and nodes of the
+// Clusters that use each ProviderConfig, which its quotas limit.
+func SetupUsage(mgr ctrl.Manager, o xpcontroller.Options) error {
+	name := providerconfig.ControllerName(v1beta1.ProviderConfigGroupKind) + "/usage"
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(name).
+		WithOptions(o.ForControllerRuntime()).
+		For(&v1beta1.ProviderConfig{}).
+		Watches(&clusterv1alpha1.Cluster{}, handler.EnqueueRequestsFromMapFunc(clusterProviderConfig)).
+		Watches(&namespacedclusterv1alpha1.Cluster{}, handler.EnqueueRequestsFromMapFunc(namespacedClusterProviderConfig)).
+		Complete(&usageReconciler{kube: mgr.GetClient()})
+}
+
+// usageReconciler reports the quota usage of ProviderConfigs.
+type usageReconciler struct {
+	kube client.Client
+}
+
+func (r *usageReconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	pc := &v1beta1.ProviderConfig{}
+	if err := r.kube.Get(ctx, req.NamespacedName, pc); err != nil {
+		return reconcile.Result{}, errors.Wrap(client.IgnoreNotFound(err), errGetProviderConfig)
+	}
+
+	u, err := quota.Count(ctx, r.kube, pc.GetName(), "")
+	if err != nil {
+		return reconcile.Result{}, errors.Wrap(err, errCountUsage)
+	}
+	usage := &v1beta1.QuotaUsage{Clusters: u.Clusters, Nodes: u.Nodes}
+	if len(u.Namespaces) > 0 {
+		usage.Namespaces = u.Namespaces
+	}
+	if equality.Semantic.DeepEqual(pc.Status.Usage, usage) {
+		return reconcile.Result{}, nil
+	}
+
+	pc.Status.Usage = usage
+	return reconcile.Result{}, errors.Wrap(r.kube.Status().Update(ctx, pc), errUpdateStatus)
+}
+
+// clusterProviderConfig maps a cluster-scoped Cluster to its ProviderConfig.
+// Like quota.Count, it maps a Cluster that references none to the default.
+func clusterProviderConfig(_ context.Context, o client.Object) []reconcile.Request {
+	c, ok := o.(*clusterv1alpha1.Cluster)
+	if !ok {
+		return nil
+	}
+	name := quota.DefaultProviderConfig
+	if ref := c.GetProviderConfigReference(); ref != nil {
+		name = ref.Name
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: name}}}
+}
+
+// namespacedClusterProviderConfig maps a namespaced Cluster to its
+// ProviderConfig, or to the default if it references none.
+func namespacedClusterProviderConfig(_ context.Context, o client.Object) []reconcile.Request {
+	c, ok := o.(*namespacedclusterv1alpha1.Cluster)
+	if !ok {
+		return nil
+	}
+	name := quota.DefaultProviderConfig
+	if ref := c.Spec.ProviderConfigReference; ref != nil {
+		name = ref.Name
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: name}}}
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+package providerconfig
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/v2/apis/common/v1"
+
+	clusterv1alpha1 "github.com/humoflife/provider-kind/apis/cluster/v1alpha1"
+	namespacedclusterv1alpha1 "github.com/humoflife/provider-kind/apis/namespacedcluster/v1alpha1"
+	"github.com/humoflife/provider-kind/apis/v1beta1"
+	"github.com/humoflife/provider-kind/internal/quota"
+)
+
+func TestProviderConfigMaps(t *testing.T) {
+	request := func(name string) []reconcile.Request {
+		return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: name}}}
+	}
+
+	withRef := &clusterv1alpha1.Cluster{}
+	withRef.SetProviderConfigReference(&xpv1.Reference{Name: "ci"})
+	withNSRef := &namespacedclusterv1alpha1.Cluster{}
+	withNSRef.SetProviderConfigReference(&xpv1.ProviderConfigReference{Kind: v1beta1.ProviderConfigKind, Name: "ci"})
+
+	cases := map[string]struct {
+		reason string
+		fn     func(context.Context, client.Object) []reconcile.Request
+		o      client.Object
+		want   []reconcile.Request
+	}{
+		"ClusterWithReference": {
+			reason: "A cluster-scoped Cluster should map to the ProviderConfig it references.",
+			fn:     clusterProviderConfig,
+			o:      withRef,
+			want:   request("ci"),
+		},
+		"ClusterWithoutReference": {
+			reason: "A cluster-scoped Cluster that references no ProviderConfig should map to the default, which quota.Count counts it against.",
+			fn:     clusterProviderConfig,
+			o:      &clusterv1alpha1.Cluster{},
+			want:   request(quota.DefaultProviderConfig),
+		},
+		"NamespacedClusterWithReference": {
+			reason: "A namespaced Cluster should map to the ProviderConfig it references.",
+			fn:     namespacedClusterProviderConfig,
+			o:      withNSRef,
+			want:   request("ci"),
+		},
+		"NamespacedClusterWithoutReference": {
+			reason: "A namespaced Cluster that references no ProviderConfig should map to the default, which quota.Count counts it against.",
+			fn:     namespacedClusterProviderConfig,
+			o:      &namespacedclusterv1alpha1.Cluster{},
+			want:   request(quota.DefaultProviderConfig),
+		},
+		"OtherObject": {
+			reason: "Objects that are not Clusters should map to nothing.",
+			fn:     clusterProviderConfig,
+			o:      &namespacedclusterv1alpha1.Cluster{},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := tc.fn(context.Background(), tc.o)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nmap(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
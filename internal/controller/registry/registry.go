@@ -0,0 +1,463 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+// Package registry implements the Crossplane managed reconciler for local
+// image registries used by KIND clusters.
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/v2/apis/common/v1"
+	xpcontroller "github.com/crossplane/crossplane-runtime/v2/pkg/controller"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/event"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/ratelimiter"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+
+	registryv1alpha1 "github.com/humoflife/provider-kind/apis/registry/v1alpha1"
+	"github.com/humoflife/provider-kind/apis/v1beta1"
+	"github.com/humoflife/provider-kind/internal/docker"
+	"github.com/humoflife/provider-kind/internal/sources"
+)
+
+const (
+	errNotRegistry       = "managed resource is not a Registry custom resource"
+	errTrackUsage        = "cannot track ProviderConfig usage"
+	errInspectRegistry   = "cannot inspect registry container"
+	errCreateRegistry    = "cannot create registry container"
+	errDeleteRegistry    = "cannot delete registry container"
+	errConnectNetwork    = "cannot attach registry container to Docker network"
+	errCheckNetwork      = "cannot check Docker network"
+	errGetCredentials    = "cannot get pull-through cache credentials"
+	errParseRemoteURL    = "cannot parse pull-through cache remote URL"
+	errMissingCredential = "Secret %s/%s must contain username and password keys"
+	errWriteConfig       = "cannot write registry configuration"
+	errStartRegistry     = "cannot start registry container"
+	errNotOwned          = "container %q exists and does not belong to this Registry"
+	errStorageVolume     = "storageVolume %q is not the name of a Docker volume"
+)
+
+const (
+	// LabelRegistry is set on registry containers to the name of the
+	// Registry that owns them.
+	LabelRegistry = "kind.crossplane.io/registry"
+
+	// labelConfigHash records the configuration a registry container was
+	// created with, so that changes can be detected.
+	labelConfigHash = "kind.crossplane.io/config-hash"
+
+	// ContainerPort is the port the registry listens on inside its
+	// container.
+	ContainerPort = 5000
+
+	// configFile is the registry configuration written into containers of
+	// pull-through caches with credentials, so that the password is not
+	// passed in the environment where docker inspect shows it. /etc exists
+	// in every image, and docker cp needs the directory to exist.
+	configFile = "/etc/provider-kind-registry.yml"
+
+	defaultImage         = "registry:2"
+	defaultNetwork       = "kind"
+	defaultListenAddress = "127.0.0.1"
+)
+
+// volumeName matches the names Docker allows for volumes. Host paths never
+// match, since they contain a slash.
+var volumeName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]+$`)
+
+// Setup adds a controller that reconciles Registry managed resources.
+func Setup(mgr ctrl.Manager, o xpcontroller.Options) error {
+	name := managed.ControllerName(registryv1alpha1.RegistryGroupVersionKind.String())
+
+	reconcilerOpts := []managed.ReconcilerOption{
+		managed.WithExternalConnecter(&connector{kube: mgr.GetClient()}),
+		managed.WithLogger(o.Logger.WithValues("controller", name)),
+		managed.WithRecorder(event.NewAPIRecorder(mgr.GetEventRecorderFor(name))),
+		managed.WithPollInterval(o.PollInterval),
+	}
+
+	r := managed.NewReconciler(mgr,
+		resource.ManagedKind(registryv1alpha1.RegistryGroupVersionKind),
+		reconcilerOpts...,
+	)
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(name).
+		WithOptions(o.ForControllerRuntime()).
+		For(&registryv1alpha1.Registry{}).
+		Complete(ratelimiter.NewReconciler(name, r, o.GlobalRateLimiter))
+}
+
+// connector creates an external client for each reconcile.
+type connector struct {
+	kube client.Client
+}
+
+func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.ExternalClient, error) {
+	cr, ok := mg.(*registryv1alpha1.Registry)
+	if !ok {
+		return nil, errors.New(errNotRegistry)
+	}
+
+	tracker := resource.NewLegacyProviderConfigUsageTracker(c.kube, &v1beta1.ProviderConfigUsage{})
+	if err := tracker.Track(ctx, cr); err != nil {
+		return nil, errors.Wrap(err, errTrackUsage)
+	}
+
+	return &external{kube: c.kube}, nil
+}
+
+// external implements managed.ExternalClient for registry containers.
+type external struct {
+	kube client.Client
+}
+
+// Observe inspects the registry container.
+func (e *external) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
+	cr, ok := mg.(*registryv1alpha1.Registry)
+	if !ok {
+		return managed.ExternalObservation{}, errors.New(errNotRegistry)
+	}
+
+	name := GetContainerName(cr)
+	c, err := docker.InspectContainer(ctx, name)
+	if err != nil {
+		return managed.ExternalObservation{}, errors.Wrap(err, errInspectRegistry)
+	}
+	if c == nil {
+		return managed.ExternalObservation{ResourceExists: false}, nil
+	}
+
+	// Containers that were not created for this Registry are never adopted,
+	// so they are not recreated or removed either.
+	if !owned(c, cr) {
+		if meta.WasDeleted(cr) {
+			return managed.ExternalObservation{ResourceExists: false}, nil
+		}
+		return managed.ExternalObservation{}, errors.Errorf(errNotOwned, name)
+	}
+
+	spec, err := e.runSpec(ctx, cr)
+	if err != nil {
+		return managed.ExternalObservation{}, err
+	}
+
+	p := cr.Spec.ForProvider
+	obs := registryv1alpha1.RegistryObservation{
+		ContainerID:     c.ID,
+		ContainerName:   c.Name,
+		Status:          c.State.Status,
+		NetworkEndpoint: fmt.Sprintf("%s:%d", c.Name, ContainerPort),
+	}
+	if p.HostPort != nil {
+		obs.HostEndpoint = fmt.Sprintf("localhost:%d", *p.HostPort)
+	}
+	if p.PullThroughCache != nil {
+		if u, err := url.Parse(p.PullThroughCache.RemoteURL); err == nil {
+			obs.UpstreamHost = u.Host
+		}
+	}
+	cr.Status.AtProvider = obs
+
+	if c.State.Running {
+		cr.SetConditions(xpv1.Available())
+	} else {
+		cr.SetConditions(xpv1.Unavailable())
+	}
+
+	upToDate := c.Config.Labels[labelConfigHash] == spec.hash()
+
+	// The KIND network only exists once the first cluster has been created,
+	// so attach the registry as soon as it appears.
+	network := networkName(cr)
+	if _, attached := c.NetworkSettings.Networks[network]; !attached {
+		exists, err := docker.NetworkExists(ctx, network)
+		if err != nil {
+			return managed.ExternalObservation{}, errors.Wrap(err, errCheckNetwork)
+		}
+		if exists {
+			upToDate = false
+		}
+	}
+
+	return managed.ExternalObservation{
+		ResourceExists:   true,
+		ResourceUpToDate: upToDate,
+	}, nil
+}
+
+// Create starts the registry container.
+func (e *external) Create(ctx context.Context, mg resource.Managed) (managed.ExternalCreation, error) {
+	cr, ok := mg.(*registryv1alpha1.Registry)
+	if !ok {
+		return managed.ExternalCreation{}, errors.New(errNotRegistry)
+	}
+
+	cr.SetConditions(xpv1.Creating())
+
+	name := GetContainerName(cr)
+	meta.SetExternalName(cr, name)
+
+	return managed.ExternalCreation{}, e.run(ctx, cr)
+}
+
+// Update recreates the registry container when its configuration changed,
+// and attaches it to the Docker network when it is missing from it. Data in
+// a storage volume survives recreation.
+func (e *external) Update(ctx context.Context, mg resource.Managed) (managed.ExternalUpdate, error) {
+	cr, ok := mg.(*registryv1alpha1.Registry)
+	if !ok {
+		return managed.ExternalUpdate{}, errors.New(errNotRegistry)
+	}
+
+	name := GetContainerName(cr)
+	c, err := docker.InspectContainer(ctx, name)
+	if err != nil {
+		return managed.ExternalUpdate{}, errors.Wrap(err, errInspectRegistry)
+	}
+	if c != nil && !owned(c, cr) {
+		return managed.ExternalUpdate{}, errors.Errorf(errNotOwned, name)
+	}
+
+	spec, err := e.runSpec(ctx, cr)
+	if err != nil {
+		return managed.ExternalUpdate{}, err
+	}
+
+	if c == nil || c.Config.Labels[labelConfigHash] != spec.hash() {
+		if err := docker.RemoveContainer(ctx, name); err != nil {
+			return managed.ExternalUpdate{}, errors.Wrap(err, errDeleteRegistry)
+		}
+		return managed.ExternalUpdate{}, e.run(ctx, cr)
+	}
+
+	return managed.ExternalUpdate{}, connectNetwork(ctx, cr)
+}
+
+// Delete removes the registry container. The storage volume, if any, is
+// kept.
+func (e *external) Delete(ctx context.Context, mg resource.Managed) (managed.ExternalDelete, error) {
+	cr, ok := mg.(*registryv1alpha1.Registry)
+	if !ok {
+		return managed.ExternalDelete{}, errors.New(errNotRegistry)
+	}
+
+	cr.SetConditions(xpv1.Deleting())
+
+	name := GetContainerName(cr)
+	c, err := docker.InspectContainer(ctx, name)
+	if err != nil {
+		return managed.ExternalDelete{}, errors.Wrap(err, errInspectRegistry)
+	}
+	if c == nil || !owned(c, cr) {
+		return managed.ExternalDelete{}, nil
+	}
+	if err := docker.RemoveContainer(ctx, name); err != nil {
+		return managed.ExternalDelete{}, errors.Wrap(err, errDeleteRegistry)
+	}
+	return managed.ExternalDelete{}, nil
+}
+
+// Disconnect is a no-op because the Docker CLI holds no persistent connection.
+func (e *external) Disconnect(_ context.Context) error {
+	return nil
+}
+
+// run starts the registry container and attaches it to the Docker network.
+// The configuration file, if any, is written before the container starts.
+func (e *external) run(ctx context.Context, cr *registryv1alpha1.Registry) error {
+	spec, err := e.runSpec(ctx, cr)
+	if err != nil {
+		return err
+	}
+
+	name := GetContainerName(cr)
+	full := []string{"create", "--restart=always",
+		"--name", name,
+		"--label", LabelRegistry + "=" + cr.GetName(),
+		"--label", labelConfigHash + "=" + spec.hash(),
+	}
+	full = append(full, spec.args...)
+	if _, err := docker.Run(ctx, full...); err != nil {
+		return errors.Wrap(err, errCreateRegistry)
+	}
+
+	if spec.config != nil {
+		if err := docker.CopyFile(ctx, name, configFile, spec.config, 0o600); err != nil {
+			// A container without its configuration would start without
+			// credentials, so it is removed and created again later.
+			_ = docker.RemoveContainer(ctx, name)
+			return errors.Wrap(err, errWriteConfig)
+		}
+	}
+	if _, err := docker.Run(ctx, "start", name); err != nil {
+		return errors.Wrap(err, errStartRegistry)
+	}
+
+	return connectNetwork(ctx, cr)
+}
+
+// runSpec describes how the registry container of a Registry is run.
+type runSpec struct {
+	// args are the docker create arguments, including the image and
+	// command.
+	args []string
+
+	// config is the configuration file written into the container, or nil
+	// if the image's own configuration is used.
+	config []byte
+
+	// credentials identifies the version of the pull-through cache
+	// credentials in the configuration file, if any.
+	credentials string
+}
+
+// hash returns a digest of the run specification. The configuration file
+// holds the pull-through cache password, which anyone who can inspect the
+// container could guess against a digest of it, so the version of the
+// credentials Secret stands in for the file.
+func (s runSpec) hash() string {
+	h := sha256.Sum256([]byte(strings.Join(s.args, "\x00") + "\x00" + s.credentials))
+	return hex.EncodeToString(h[:])
+}
+
+// runSpec returns how the registry container is run, derived from the
+// Registry spec.
+func (e *external) runSpec(ctx context.Context, cr *registryv1alpha1.Registry) (runSpec, error) {
+	p := cr.Spec.ForProvider
+	var spec runSpec
+
+	if p.HostPort != nil {
+		addr := defaultListenAddress
+		if p.ListenAddress != nil {
+			addr = *p.ListenAddress
+		}
+		spec.args = append(spec.args, "--publish", fmt.Sprintf("%s:%d:%d", addr, *p.HostPort, ContainerPort))
+	}
+
+	if p.StorageVolume != nil {
+		// Docker mounts a host path given in place of a volume name, which
+		// would expose the host to the registry.
+		if !volumeName.MatchString(*p.StorageVolume) {
+			return runSpec{}, errors.Errorf(errStorageVolume, *p.StorageVolume)
+		}
+		spec.args = append(spec.args, "--volume", *p.StorageVolume+":/var/lib/registry")
+	}
+
+	if c := p.PullThroughCache; c != nil {
+		if _, err := url.Parse(c.RemoteURL); err != nil {
+			return runSpec{}, errors.Wrap(err, errParseRemoteURL)
+		}
+		spec.args = append(spec.args, "--env", "REGISTRY_PROXY_REMOTEURL="+c.RemoteURL)
+		if ref := c.CredentialsSecretRef; ref != nil {
+			s, err := sources.Resolver{Client: e.kube}.Secret(ctx, ref.Name, ref.Namespace)
+			if err != nil {
+				return runSpec{}, errors.Wrap(err, errGetCredentials)
+			}
+			user, pass := s.Data["username"], s.Data["password"]
+			if len(user) == 0 || len(pass) == 0 {
+				return runSpec{}, errors.Errorf(errMissingCredential, ref.Namespace, ref.Name)
+			}
+			spec.credentials = s.GetResourceVersion()
+			if spec.config, err = registryConfig(c.RemoteURL, string(user), string(pass)); err != nil {
+				return runSpec{}, errors.Wrap(err, errWriteConfig)
+			}
+		}
+	}
+
+	image := defaultImage
+	if p.Image != nil {
+		image = *p.Image
+	}
+	spec.args = append(spec.args, image)
+	if spec.config != nil {
+		// The registry image's entrypoint serves a configuration file passed
+		// as its command.
+		spec.args = append(spec.args, configFile)
+	}
+	return spec, nil
+}
+
+// registryConfig returns the configuration of a pull-through cache with
+// credentials. It is the registry image's default configuration with a
+// proxy section. JSON is valid YAML, which the registry reads.
+func registryConfig(remoteURL, username, password string) ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"version": "0.1",
+		"log":     map[string]any{"fields": map[string]any{"service": "registry"}},
+		"storage": map[string]any{
+			"cache":      map[string]any{"blobdescriptor": "inmemory"},
+			"filesystem": map[string]any{"rootdirectory": "/var/lib/registry"},
+		},
+		"http": map[string]any{
+			"addr":    fmt.Sprintf(":%d", ContainerPort),
+			"headers": map[string]any{"X-Content-Type-Options": []string{"nosniff"}},
+		},
+		"proxy": map[string]any{
+			"remoteurl": remoteURL,
+			"username":  username,
+			"password":  password,
+		},
+	})
+}
+
+// connectNetwork attaches the registry container to its Docker network if
+// the network exists and the container is not attached yet.
+func connectNetwork(ctx context.Context, cr *registryv1alpha1.Registry) error {
+	network := networkName(cr)
+	exists, err := docker.NetworkExists(ctx, network)
+	if err != nil {
+		return errors.Wrap(err, errCheckNetwork)
+	}
+	if !exists {
+		return nil
+	}
+
+	c, err := docker.InspectContainer(ctx, GetContainerName(cr))
+	if err != nil {
+		return errors.Wrap(err, errInspectRegistry)
+	}
+	if c == nil {
+		return nil
+	}
+	if _, attached := c.NetworkSettings.Networks[network]; attached {
+		return nil
+	}
+	return errors.Wrap(docker.ConnectNetwork(ctx, network, c.Name), errConnectNetwork)
+}
+
+// owned reports whether the container was created for the Registry.
+func owned(c *docker.Container, cr *registryv1alpha1.Registry) bool {
+	return c.Config.Labels[LabelRegistry] == cr.GetName()
+}
+
+// networkName returns the Docker network the registry is attached to.
+func networkName(cr *registryv1alpha1.Registry) string {
+	if cr.Spec.ForProvider.Network != nil {
+		return *cr.Spec.ForProvider.Network
+	}
+	return defaultNetwork
+}
+
+// GetContainerName returns the external name of the registry, falling back
+// to the managed resource name if no external name has been set.
+func GetContainerName(cr *registryv1alpha1.Registry) string {
+	if name := meta.GetExternalName(cr); name != "" {
+		return name
+	}
+	return cr.GetName()
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+package registry
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/v2/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/test"
+
+	registryv1alpha1 "github.com/humoflife/provider-kind/apis/registry/v1alpha1"
+)
+
+func TestRunSpec(t *testing.T) {
+	type want struct {
+		args []string
+		err  error
+	}
+
+	cases := map[string]struct {
+		reason string
+		params registryv1alpha1.RegistryParameters
+		want   want
+	}{
+		"Defaults": {
+			reason: "A Registry without parameters should run the default image unpublished.",
+			want:   want{args: []string{defaultImage}},
+		},
+		"NamedVolume": {
+			reason: "A named volume should be mounted as the registry storage.",
+			params: registryv1alpha1.RegistryParameters{StorageVolume: ptr.To("registry-data")},
+			want:   want{args: []string{"--volume", "registry-data:/var/lib/registry", defaultImage}},
+		},
+		"HostPath": {
+			reason: "A host path should not be mounted as the registry storage.",
+			params: registryv1alpha1.RegistryParameters{StorageVolume: ptr.To("/")},
+			want:   want{err: errors.Errorf(errStorageVolume, "/")},
+		},
+		"RelativeHostPath": {
+			reason: "A relative host path should not be mounted as the registry storage.",
+			params: registryv1alpha1.RegistryParameters{StorageVolume: ptr.To("./data")},
+			want:   want{err: errors.Errorf(errStorageVolume, "./data")},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cr := &registryv1alpha1.Registry{}
+			cr.Spec.ForProvider = tc.params
+			e := &external{}
+			spec, err := e.runSpec(context.Background(), cr)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nrunSpec(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.args, spec.args); diff != "" {
+				t.Errorf("\n%s\nrunSpec(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestRunSpecHash(t *testing.T) {
+	secret := func(password, resourceVersion string) *external {
+		return &external{kube: &test.MockClient{
+			MockGet: test.NewMockGetFn(nil, func(obj client.Object) error {
+				s := obj.(*corev1.Secret)
+				s.SetResourceVersion(resourceVersion)
+				s.Data = map[string][]byte{"username": []byte("user"), "password": []byte(password)}
+				return nil
+			}),
+		}}
+	}
+	cr := &registryv1alpha1.Registry{}
+	cr.Spec.ForProvider.PullThroughCache = &registryv1alpha1.PullThroughCache{
+		RemoteURL:            "https://registry-1.docker.io",
+		CredentialsSecretRef: &xpv1.SecretReference{Name: "hub", Namespace: "crossplane-system"},
+	}
+
+	hash := func(e *external) string {
+		spec, err := e.runSpec(context.Background(), cr)
+		if err != nil {
+			t.Fatalf("runSpec(...): %v", err)
+		}
+		return spec.hash()
+	}
+
+	cases := map[string]struct {
+		reason string
+		a, b   *external
+		equal  bool
+	}{
+		"SameVersion": {
+			reason: "The hash should not depend on the password, which must not be guessable from it.",
+			a:      secret("hunter2", "1"),
+			b:      secret("correct horse", "1"),
+			equal:  true,
+		},
+		"NewVersion": {
+			reason: "The hash should change with the version of the credentials Secret.",
+			a:      secret("hunter2", "1"),
+			b:      secret("hunter2", "2"),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.equal, hash(tc.a) == hash(tc.b)); diff != "" {
+				t.Errorf("\n%s\nhash(...) equal: -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
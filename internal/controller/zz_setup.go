@@ -9,8 +9,15 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 
 	"github.com/humoflife/provider-kind/internal/controller/cluster"
+	"github.com/humoflife/provider-kind/internal/controller/clusterpeering"
+	"github.com/humoflife/provider-kind/internal/controller/loadedimage"
+	"github.com/humoflife/provider-kind/internal/controller/manifest"
 	"github.com/humoflife/provider-kind/internal/controller/namespacedcluster"
+	"github.com/humoflife/provider-kind/internal/controller/network"
+	"github.com/humoflife/provider-kind/internal/controller/nodeexec"
+	"github.com/humoflife/provider-kind/internal/controller/nodeimagecache"
 	"github.com/humoflife/provider-kind/internal/controller/providerconfig"
+	"github.com/humoflife/provider-kind/internal/controller/registry"
 )
 
 // Setup creates all controllers with the supplied logger and adds them to
@@ -18,8 +25,16 @@ import (
 func Setup(mgr ctrl.Manager, o xpcontroller.Options) error {
 	for _, setup := range []func(ctrl.Manager, xpcontroller.Options) error{
 		cluster.Setup,
+		clusterpeering.Setup,
+		loadedimage.Setup,
+		manifest.Setup,
 		namespacedcluster.Setup,
+		network.Setup,
+		nodeexec.Setup,
+		nodeimagecache.Setup,
 		providerconfig.Setup,
+		providerconfig.SetupUsage,
+		registry.Setup,
 	} {
 		if err := setup(mgr, o); err != nil {
 			return err
@@ -0,0 +1,116 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+// Package docker runs Docker CLI commands against the host Docker daemon.
+// The provider image ships the docker binary for the KIND library, so the
+// CLI is used here too rather than a Docker API client.
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	errInspect       = "cannot inspect %s"
+	errDecodeInspect = "cannot decode docker inspect output"
+)
+
+// Container is the subset of docker inspect output used by the provider.
+type Container struct {
+	ID     string `json:"Id"`
+	Name   string `json:"Name"`
+	Config struct {
+		Image  string            `json:"Image"`
+		Labels map[string]string `json:"Labels"`
+	} `json:"Config"`
+	State struct {
+		Status  string `json:"Status"`
+		Running bool   `json:"Running"`
+	} `json:"State"`
+	HostConfig struct {
+		NanoCPUs  int64  `json:"NanoCpus"`
+		Memory    int64  `json:"Memory"`
+		PidsLimit *int64 `json:"PidsLimit"`
+	} `json:"HostConfig"`
+	NetworkSettings struct {
+		Networks map[string]struct {
+			IPAddress         string `json:"IPAddress"`
+			GlobalIPv6Address string `json:"GlobalIPv6Address"`
+		} `json:"Networks"`
+	} `json:"NetworkSettings"`
+}
+
+// Run runs docker with the supplied arguments and returns its standard
+// output. The returned error includes standard error.
+func Run(ctx context.Context, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, errors.Wrap(err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// IsNotFound reports whether err indicates that a Docker object does not
+// exist.
+func IsNotFound(err error) bool {
+	return err != nil && strings.Contains(strings.ToLower(err.Error()), "no such")
+}
+
+// InspectContainer returns the named container, or nil if it does not exist.
+func InspectContainer(ctx context.Context, name string) (*Container, error) {
+	out, err := Run(ctx, "container", "inspect", name)
+	if IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, errInspect, name)
+	}
+	var cs []Container
+	if err := json.Unmarshal(out, &cs); err != nil {
+		return nil, errors.Wrap(err, errDecodeInspect)
+	}
+	if len(cs) == 0 {
+		return nil, nil
+	}
+	c := cs[0]
+	c.Name = strings.TrimPrefix(c.Name, "/")
+	return &c, nil
+}
+
+// RemoveContainer forcibly removes the named container. It is not an error
+// if the container does not exist.
+func RemoveContainer(ctx context.Context, name string) error {
+	_, err := Run(ctx, "container", "rm", "--force", name)
+	if IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// NetworkExists reports whether the named network exists.
+func NetworkExists(ctx context.Context, name string) (bool, error) {
+	_, err := Run(ctx, "network", "inspect", name)
+	if IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrapf(err, errInspect, name)
+	}
+	return true, nil
+}
+
+// ConnectNetwork attaches the container to the network.
+func ConnectNetwork(ctx context.Context, network, container string) error {
+	_, err := Run(ctx, "network", "connect", network, container)
+	return err
+}
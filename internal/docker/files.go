@@ -0,0 +1,45 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"os/exec"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const errArchiveFile = "cannot archive file %s"
+
+// CopyFile writes content to the file in the container with the supplied
+// mode. The file is streamed to docker cp rather than bind mounted, so it
+// need not exist on the Docker host, and it does not show in docker inspect.
+func CopyFile(ctx context.Context, container, file string, content []byte, mode int64) error {
+	var archive bytes.Buffer
+	tw := tar.NewWriter(&archive)
+	hdr := &tar.Header{Name: path.Base(file), Mode: mode, Size: int64(len(content))}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return errors.Wrapf(err, errArchiveFile, file)
+	}
+	if _, err := tw.Write(content); err != nil {
+		return errors.Wrapf(err, errArchiveFile, file)
+	}
+	if err := tw.Close(); err != nil {
+		return errors.Wrapf(err, errArchiveFile, file)
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "docker", "cp", "-", container+":"+path.Dir(file))
+	cmd.Stdin = &archive
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return errors.Wrap(err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+package docker
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// labelKindCluster is the label KIND sets on node containers.
+	labelKindCluster = "io.x-k8s.kind.cluster"
+)
+
+const (
+	errInfo           = "cannot get Docker host info"
+	errListKindNodes  = "cannot list KIND node containers"
+	errProbeHost      = "cannot probe Docker host capacity"
+	errPullProbe      = "cannot pull probe image %q"
+	errParseProbe     = "cannot parse Docker host capacity"
+	errMissingMemInfo = "MemAvailable not found in /proc/meminfo"
+)
+
+// HostCapacity is the free capacity of the Docker host.
+type HostCapacity struct {
+	// FreeMemory is the memory available to new containers, in bytes.
+	FreeMemory int64
+
+	// FreeDisk is the free space of the filesystem of the Docker data
+	// root, in bytes.
+	FreeDisk int64
+
+	// Probed reports whether the capacity was read on the host. If it was
+	// not, the capacity is unknown.
+	Probed bool
+}
+
+// InspectHostCapacity returns the free capacity of the Docker host. The
+// Docker API does not report free memory or disk, so a container reads them.
+// It runs the image of a running KIND node or, failing that, the first of
+// the supplied images that is present on the host. Those are never pulled
+// for this, since node images are large. The probe image, if any, is used
+// and pulled if missing when none of them is present. Without an image the
+// capacity is not probed.
+func InspectHostCapacity(ctx context.Context, probe string, images ...string) (HostCapacity, error) {
+	root, err := Run(ctx, "info", "--format", "{{.DockerRootDir}}")
+	if err != nil {
+		return HostCapacity{}, errors.Wrap(err, errInfo)
+	}
+
+	image, err := probeImage(ctx, probe, images)
+	if err != nil || image == "" {
+		return HostCapacity{}, err
+	}
+
+	out, err := Run(ctx, "run", "--rm", "--pull=never", "--entrypoint", "sh",
+		"--volume", strings.TrimSpace(string(root))+":/data:ro", image,
+		"-c", "grep '^MemAvailable:' /proc/meminfo && df -Pk /data | tail -n 1")
+	if err != nil {
+		return HostCapacity{}, errors.Wrap(err, errProbeHost)
+	}
+	c, err := parseHostCapacity(out)
+	c.Probed = err == nil
+	return c, err
+}
+
+// probeImage returns an image present on the host that has sh, grep, and
+// df: that of a running KIND node, or the first of the supplied ones that is
+// present, or else the probe image, which is pulled if it is missing. It
+// returns an empty string if there is none.
+func probeImage(ctx context.Context, probe string, images []string) (string, error) {
+	out, err := Run(ctx, "container", "ls", "--filter", "label="+labelKindCluster, "--format", "{{.Image}}")
+	if err != nil {
+		return "", errors.Wrap(err, errListKindNodes)
+	}
+	if nodes := strings.Fields(string(out)); len(nodes) > 0 {
+		return nodes[0], nil
+	}
+	for _, image := range images {
+		img, err := InspectImage(ctx, image)
+		if err != nil {
+			return "", err
+		}
+		if img != nil {
+			return image, nil
+		}
+	}
+	if probe == "" {
+		return "", nil
+	}
+	img, err := InspectImage(ctx, probe)
+	if err != nil {
+		return "", err
+	}
+	if img == nil {
+		if err := PullImage(ctx, probe, func(string) {}); err != nil {
+			return "", errors.Wrapf(err, errPullProbe, probe)
+		}
+	}
+	return probe, nil
+}
+
+// KindNodes returns the number of running KIND node containers on the host,
+// of any cluster.
+func KindNodes(ctx context.Context) (int, error) {
+	out, err := Run(ctx, "container", "ls", "--quiet", "--filter", "label="+labelKindCluster)
+	if err != nil {
+		return 0, errors.Wrap(err, errListKindNodes)
+	}
+	return len(strings.Fields(string(out))), nil
+}
+
+// parseHostCapacity parses the MemAvailable line of /proc/meminfo and the
+// last line of df -Pk, both in KiB.
+func parseHostCapacity(out []byte) (HostCapacity, error) {
+	var c HostCapacity
+	memory := false
+	s := bufio.NewScanner(bytes.NewReader(out))
+	for s.Scan() {
+		f := strings.Fields(s.Text())
+		switch {
+		case len(f) >= 2 && f[0] == "MemAvailable:":
+			kib, err := strconv.ParseInt(f[1], 10, 64)
+			if err != nil {
+				return HostCapacity{}, errors.Wrap(err, errParseProbe)
+			}
+			c.FreeMemory = kib * 1024
+			memory = true
+		case len(f) >= 6:
+			kib, err := strconv.ParseInt(f[3], 10, 64)
+			if err != nil {
+				return HostCapacity{}, errors.Wrap(err, errParseProbe)
+			}
+			c.FreeDisk = kib * 1024
+		}
+	}
+	if !memory {
+		return HostCapacity{}, errors.New(errMissingMemInfo)
+	}
+	return c, nil
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+package docker
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	errOpenArchive     = "cannot open image archive %s"
+	errReadArchive     = "cannot read image archive %s"
+	errNoArchiveImages = "image archive %s contains no tagged images"
+
+	// archiveManifest lists the images of a docker save archive.
+	archiveManifest = "manifest.json"
+)
+
+// Image is the subset of docker image inspect output used by the provider.
+type Image struct {
+	ID       string   `json:"Id"`
+	RepoTags []string `json:"RepoTags"`
+	Size     int64    `json:"Size"`
+}
+
+// InspectImage returns the image, or nil if it does not exist.
+func InspectImage(ctx context.Context, ref string) (*Image, error) {
+	out, err := Run(ctx, "image", "inspect", ref)
+	if IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, errInspect, ref)
+	}
+	var is []Image
+	if err := json.Unmarshal(out, &is); err != nil {
+		return nil, errors.Wrap(err, errDecodeInspect)
+	}
+	if len(is) == 0 {
+		return nil, nil
+	}
+	return &is[0], nil
+}
+
+// PullImage pulls the image, calling progress with each line of pull
+// output.
+func PullImage(ctx context.Context, ref string, progress func(line string)) error {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "docker", "image", "pull", ref)
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	s := bufio.NewScanner(stdout)
+	for s.Scan() {
+		progress(s.Text())
+	}
+	if err := cmd.Wait(); err != nil {
+		return errors.Wrap(err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// RemoveImage removes the image. It is not an error if the image does not
+// exist.
+func RemoveImage(ctx context.Context, ref string) error {
+	_, err := Run(ctx, "image", "rm", ref)
+	if IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// ImageInUse reports whether any container, running or not, was created
+// from the image.
+func ImageInUse(ctx context.Context, id string) (bool, error) {
+	out, err := Run(ctx, "container", "ls", "--all", "--quiet", "--filter", "ancestor="+id)
+	if err != nil {
+		return false, err
+	}
+	return len(bytes.TrimSpace(out)) > 0, nil
+}
+
+// IsConflict reports whether err indicates that a Docker object is in use.
+func IsConflict(err error) bool {
+	return err != nil && strings.Contains(strings.ToLower(err.Error()), "conflict")
+}
+
+// ImageID returns the host Docker daemon ID of the image, or an empty string
+// if the image does not exist.
+func ImageID(ctx context.Context, ref string) (string, error) {
+	out, err := Run(ctx, "image", "inspect", "--format={{.Id}}", ref)
+	if IsNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.Wrapf(err, errInspect, ref)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// SaveImages writes the images to a tar archive at file.
+func SaveImages(ctx context.Context, file string, refs ...string) error {
+	_, err := Run(ctx, append([]string{"image", "save", "--output", file}, refs...)...)
+	return err
+}
+
+// ArchiveImages returns the image ID of every tagged image in a docker save
+// archive, keyed by tag. The image ID is the digest of the image config,
+// which is the ID containerd reports once the archive is imported.
+func ArchiveImages(file string) (map[string]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, errOpenArchive, file)
+	}
+	defer f.Close()
+
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, errors.Errorf(errNoArchiveImages, file)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, errReadArchive, file)
+		}
+		if path.Clean(hdr.Name) != archiveManifest {
+			continue
+		}
+		var manifest []struct {
+			Config   string   `json:"Config"`
+			RepoTags []string `json:"RepoTags"`
+		}
+		if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+			return nil, errors.Wrapf(err, errReadArchive, file)
+		}
+		images := map[string]string{}
+		for _, m := range manifest {
+			// Config is <hex>.json in legacy archives and
+			// blobs/sha256/<hex> in OCI layout archives.
+			id := "sha256:" + strings.TrimSuffix(path.Base(m.Config), ".json")
+			for _, tag := range m.RepoTags {
+				images[tag] = id
+			}
+		}
+		if len(images) == 0 {
+			return nil, errors.Errorf(errNoArchiveImages, file)
+		}
+		return images, nil
+	}
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+package docker
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// Network is the subset of docker network inspect output used by the
+// provider.
+type Network struct {
+	ID     string            `json:"Id"`
+	Name   string            `json:"Name"`
+	Labels map[string]string `json:"Labels"`
+	IPAM   struct {
+		Config []struct {
+			Subnet  string `json:"Subnet"`
+			Gateway string `json:"Gateway"`
+		} `json:"Config"`
+	} `json:"IPAM"`
+	Containers map[string]struct {
+		Name string `json:"Name"`
+	} `json:"Containers"`
+}
+
+// InspectNetwork returns the named network, or nil if it does not exist.
+func InspectNetwork(ctx context.Context, name string) (*Network, error) {
+	out, err := Run(ctx, "network", "inspect", name)
+	if IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, errInspect, name)
+	}
+	var ns []Network
+	if err := json.Unmarshal(out, &ns); err != nil {
+		return nil, errors.Wrap(err, errDecodeInspect)
+	}
+	if len(ns) == 0 {
+		return nil, nil
+	}
+	return &ns[0], nil
+}
+
+// RemoveNetwork removes the named network. It is not an error if the network
+// does not exist.
+func RemoveNetwork(ctx context.Context, name string) error {
+	_, err := Run(ctx, "network", "rm", name)
+	if IsNotFound(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+package docker
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	errUpdateResources = "cannot update resource limits of container %s"
+)
+
+// Resources are the resource limits of a container. Zero limits are not
+// enforced.
+type Resources struct {
+	NanoCPUs  int64
+	Memory    int64
+	PidsLimit int64
+}
+
+// Empty reports whether no limits are set.
+func (r Resources) Empty() bool {
+	return r == Resources{}
+}
+
+// Includes reports whether every limit set in want is in effect.
+func (r Resources) Includes(want Resources) bool {
+	return (want.NanoCPUs == 0 || r.NanoCPUs == want.NanoCPUs) &&
+		(want.Memory == 0 || r.Memory == want.Memory) &&
+		(want.PidsLimit == 0 || r.PidsLimit == want.PidsLimit)
+}
+
+// Resources returns the resource limits of the container.
+func (c *Container) Resources() Resources {
+	r := Resources{NanoCPUs: c.HostConfig.NanoCPUs, Memory: c.HostConfig.Memory}
+	if c.HostConfig.PidsLimit != nil && *c.HostConfig.PidsLimit > 0 {
+		r.PidsLimit = *c.HostConfig.PidsLimit
+	}
+	return r
+}
+
+// UpdateResources applies the limits that are set to the running container.
+// The memory limit includes swap, so the container gets none.
+func UpdateResources(ctx context.Context, name string, r Resources) error {
+	args := []string{"container", "update"}
+	if r.NanoCPUs != 0 {
+		args = append(args, "--cpus", strconv.FormatFloat(float64(r.NanoCPUs)/1e9, 'f', -1, 64))
+	}
+	if r.Memory != 0 {
+		m := strconv.FormatInt(r.Memory, 10)
+		args = append(args, "--memory", m, "--memory-swap", m)
+	}
+	if r.PidsLimit != 0 {
+		args = append(args, "--pids-limit", strconv.FormatInt(r.PidsLimit, 10))
+	}
+	_, err := Run(ctx, append(args, name)...)
+	return errors.Wrapf(err, errUpdateResources, name)
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+// Package kindnetwork selects the Docker network KIND creates cluster nodes
+// on. KIND offers no per-cluster option for this, only the process-wide
+// KIND_EXPERIMENTAL_DOCKER_NETWORK environment variable, which it reads once
+// while provisioning the nodes. Creations on different networks therefore
+// take turns, but only until KIND has read the variable.
+package kindnetwork
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+
+	"sigs.k8s.io/kind/pkg/apis/config/defaults"
+	"sigs.k8s.io/kind/pkg/apis/config/v1alpha4"
+	kindcluster "sigs.k8s.io/kind/pkg/cluster"
+	"sigs.k8s.io/kind/pkg/log"
+
+	"github.com/humoflife/provider-kind/internal/docker"
+)
+
+const (
+	// envNetwork is the environment variable KIND reads the network from.
+	envNetwork = "KIND_EXPERIMENTAL_DOCKER_NETWORK"
+
+	// readWarning is part of the warning KIND logs right after it read the
+	// network from the environment.
+	readWarning = "Overriding docker network"
+)
+
+var (
+	mu   sync.Mutex
+	cond = sync.NewCond(&mu)
+
+	// current is the network in use, and users the number of cluster
+	// creations that have yet to read it.
+	current string
+	users   int
+
+	// Default is the network clusters are created on when none is
+	// selected: the one the provider was started with, or "kind".
+	Default = defaultNetwork()
+)
+
+func defaultNetwork() string {
+	if n := os.Getenv(envNetwork); n != "" {
+		return n
+	}
+	return "kind"
+}
+
+// Create runs create, typically a KIND cluster creation with the supplied
+// configuration, with KIND creating nodes on the named network, or on the
+// default network if name is empty. Creations on the same network run
+// concurrently. Creations on different networks wait for each other only
+// until KIND has read the network, which it does before it creates the node
+// containers. The node images are pulled before that, so that pulls do not
+// make other creations wait.
+func Create(ctx context.Context, name string, cfg *v1alpha4.Cluster, create func(p *kindcluster.Provider) error) error {
+	if name == "" {
+		name = Default
+	}
+
+	// KIND pulls missing node images before it reads the network. Pulling
+	// them first leaves it nothing to pull while others wait. A failed pull
+	// is left for KIND to report.
+	for _, image := range images(cfg) {
+		if img, err := docker.InspectImage(ctx, image); err == nil && img == nil {
+			_ = docker.PullImage(ctx, image, func(string) {})
+		}
+	}
+
+	acquire(name)
+	var once sync.Once
+	read := func() { once.Do(release) }
+	defer read()
+
+	p := kindcluster.NewProvider(kindcluster.ProviderWithLogger(&signal{read: read}))
+	return create(p)
+}
+
+// acquire waits until no creation on another network has yet to read the
+// network, then sets it.
+func acquire(name string) {
+	mu.Lock()
+	defer mu.Unlock()
+	for users > 0 && current != name {
+		cond.Wait()
+	}
+	if users == 0 {
+		current = name
+		_ = os.Setenv(envNetwork, name)
+	}
+	users++
+}
+
+// release records that a creation read the network, or no longer will.
+func release() {
+	mu.Lock()
+	defer mu.Unlock()
+	users--
+	if users == 0 {
+		cond.Broadcast()
+	}
+}
+
+// images returns the node images of the cluster configuration.
+func images(cfg *v1alpha4.Cluster) []string {
+	if len(cfg.Nodes) == 0 {
+		return []string{defaults.Image}
+	}
+	var out []string
+	for _, n := range cfg.Nodes {
+		image := n.Image
+		if image == "" {
+			image = defaults.Image
+		}
+		out = append(out, image)
+	}
+	return out
+}
+
+// signal is a KIND logger that discards everything, and calls read when KIND
+// warns that it read the network from the environment.
+type signal struct {
+	read func()
+}
+
+func (s *signal) Warn(message string) {
+	if strings.Contains(message, readWarning) {
+		s.read()
+	}
+}
+
+func (s *signal) Warnf(format string, args ...any) {
+	if strings.Contains(format, readWarning) {
+		s.read()
+	}
+}
+
+func (s *signal) Error(string)               {}
+func (s *signal) Errorf(string, ...any)      {}
+func (s *signal) V(log.Level) log.InfoLogger { return log.NoopInfoLogger{} }
@@ -0,0 +1,168 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+package kindnode
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/exec"
+)
+
+// LabelAccessGrant is set on the objects created in the cluster for an access
+// grant, to the name of the grant.
+const LabelAccessGrant = "kind.crossplane.io/access-grant"
+
+const (
+	errApplyAccessGrant  = "cannot apply access grant %q from node %s"
+	errRevokeAccessGrant = "cannot revoke access grants from node %s"
+	errGrantToken        = "cannot get token of access grant %q from node %s"
+)
+
+// AccessGrant binds a ServiceAccount to a role.
+type AccessGrant struct {
+	// Name identifies the grant. It labels the objects created for it.
+	Name string
+
+	Namespace      string
+	ServiceAccount string
+
+	// RoleKind is Role or ClusterRole.
+	RoleKind string
+	RoleName string
+
+	// ClusterWide binds a ClusterRole with a ClusterRoleBinding.
+	ClusterWide bool
+}
+
+// ApplyAccessGrant creates the grant's namespace, ServiceAccount, token
+// Secret, and role binding, and returns the ServiceAccount token. The token
+// is empty until the token controller has populated the Secret.
+func ApplyAccessGrant(all []nodes.Node, g AccessGrant) (string, error) {
+	cp := controlPlane(all)
+	if cp == nil {
+		return "", errors.New(errNoControlPlane)
+	}
+
+	// A grant that switched between a RoleBinding and a ClusterRoleBinding,
+	// or whose binding was renamed, leaves the old binding behind.
+	if err := kubectl(cp, nil, "delete", "rolebindings,clusterrolebindings", "--all-namespaces",
+		"--selector="+LabelAccessGrant+"="+g.Name, "--field-selector=metadata.name!="+g.bindingName()).Run(); err != nil {
+		return "", errors.Wrapf(err, errApplyAccessGrant, g.Name, cp.String())
+	}
+
+	manifest, err := json.Marshal(g.objects())
+	if err != nil {
+		return "", errors.Wrapf(err, errApplyAccessGrant, g.Name, cp.String())
+	}
+	// Role references cannot change, so --force recreates changed bindings.
+	if err := kubectl(cp, nil, "apply", "--force", "-f", "-").SetStdin(bytes.NewReader(manifest)).Run(); err != nil {
+		return "", errors.Wrapf(err, errApplyAccessGrant, g.Name, cp.String())
+	}
+
+	var out bytes.Buffer
+	if err := kubectl(cp, &out, "--namespace="+g.Namespace, "get", "secret", g.tokenSecret(), "--ignore-not-found",
+		"--output=jsonpath={.data.token}").Run(); err != nil {
+		return "", errors.Wrapf(err, errGrantToken, g.Name, cp.String())
+	}
+	token, err := base64.StdEncoding.DecodeString(strings.TrimSpace(out.String()))
+	if err != nil {
+		return "", errors.Wrap(err, errDecodeToken)
+	}
+	return string(token), nil
+}
+
+// RevokeAccessGrants deletes the ServiceAccounts, token Secrets, and role
+// bindings of every grant not named in keep. Namespaces are left in place.
+func RevokeAccessGrants(all []nodes.Node, keep []string) error {
+	cp := controlPlane(all)
+	if cp == nil {
+		return errors.New(errNoControlPlane)
+	}
+	selector := LabelAccessGrant
+	if len(keep) > 0 {
+		selector += "," + LabelAccessGrant + " notin (" + strings.Join(keep, ",") + ")"
+	}
+	err := kubectl(cp, nil, "delete", "serviceaccounts,secrets,rolebindings,clusterrolebindings",
+		"--all-namespaces", "--selector="+selector).Run()
+	return errors.Wrapf(err, errRevokeAccessGrant, cp.String())
+}
+
+// bindingName returns the name of the grant's role binding. A
+// ClusterRoleBinding is named after the namespace and the ServiceAccount,
+// separated by colons, which neither name can contain.
+func (g AccessGrant) bindingName() string {
+	if g.ClusterWide {
+		return "provider-kind:" + g.Namespace + ":" + g.ServiceAccount
+	}
+	return "provider-kind-" + g.ServiceAccount
+}
+
+// tokenSecret returns the name of the Secret holding the grant's token.
+func (g AccessGrant) tokenSecret() string {
+	return g.ServiceAccount + "-token"
+}
+
+// objects returns the objects created for the grant as a List.
+func (g AccessGrant) objects() map[string]any {
+	labels := map[string]any{LabelAccessGrant: g.Name}
+	subject := map[string]any{"kind": "ServiceAccount", "name": g.ServiceAccount, "namespace": g.Namespace}
+	roleRef := map[string]any{"apiGroup": "rbac.authorization.k8s.io", "kind": g.RoleKind, "name": g.RoleName}
+
+	binding := map[string]any{
+		"apiVersion": "rbac.authorization.k8s.io/v1",
+		"kind":       "RoleBinding",
+		"metadata":   map[string]any{"name": g.bindingName(), "namespace": g.Namespace, "labels": labels},
+		"roleRef":    roleRef,
+		"subjects":   []any{subject},
+	}
+	if g.ClusterWide {
+		binding["kind"] = "ClusterRoleBinding"
+		binding["metadata"] = map[string]any{"name": g.bindingName(), "labels": labels}
+	}
+
+	return map[string]any{
+		"apiVersion": "v1",
+		"kind":       "List",
+		"items": []any{
+			map[string]any{
+				"apiVersion": "v1",
+				"kind":       "Namespace",
+				"metadata":   map[string]any{"name": g.Namespace},
+			},
+			map[string]any{
+				"apiVersion": "v1",
+				"kind":       "ServiceAccount",
+				"metadata":   map[string]any{"name": g.ServiceAccount, "namespace": g.Namespace, "labels": labels},
+			},
+			map[string]any{
+				"apiVersion": "v1",
+				"kind":       "Secret",
+				"type":       "kubernetes.io/service-account-token",
+				"metadata": map[string]any{
+					"name":        g.tokenSecret(),
+					"namespace":   g.Namespace,
+					"labels":      labels,
+					"annotations": map[string]any{"kubernetes.io/service-account.name": g.ServiceAccount},
+				},
+			},
+			binding,
+		},
+	}
+}
+
+// kubectl returns a kubectl command run with the admin kubeconfig on the
+// control plane node, writing its output to out if it is not nil.
+func kubectl(cp nodes.Node, out *bytes.Buffer, args ...string) exec.Cmd {
+	cmd := cp.Command("kubectl", append([]string{"--kubeconfig=" + adminKubeconfig}, args...)...)
+	if out != nil {
+		cmd.SetStdout(out)
+	}
+	return cmd
+}
@@ -0,0 +1,39 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+package kindnode
+
+import (
+	"github.com/pkg/errors"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+)
+
+const (
+	errBootstrapStep = "cannot apply bootstrap step %q from node %s: %s"
+
+	// fieldManager owns the fields of server-side applied objects.
+	fieldManager = "provider-kind"
+)
+
+// BootstrapStep is a set of YAML documents applied to a new cluster.
+type BootstrapStep struct {
+	Name     string
+	Manifest string
+}
+
+// Hash returns a digest of the step's manifest.
+func (s BootstrapStep) Hash() string {
+	return digest([]byte(s.Manifest))
+}
+
+// ApplyBootstrapStep server-side applies the step's manifest from a control
+// plane node. The provider takes ownership of fields other managers set.
+func ApplyBootstrapStep(all []nodes.Node, s BootstrapStep) error {
+	cp := controlPlane(all)
+	if cp == nil {
+		return errors.New(errNoControlPlane)
+	}
+	stderr, err := serverSideApply(cp, []byte(s.Manifest))
+	return errors.Wrapf(err, errBootstrapStep, s.Name, cp.String(), stderr)
+}
@@ -0,0 +1,40 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+package kindnode
+
+import (
+	"github.com/pkg/errors"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+)
+
+const (
+	errRenewCertificates = "cannot renew certificates on node %s"
+	errRestartControl    = "cannot restart the control plane on node %s"
+)
+
+// restartControlPlane stops the control plane containers that read the
+// renewed certificates. The kubelet restarts them from their static Pod
+// manifests.
+const restartControlPlane = "crictl ps --quiet --name 'kube-apiserver|kube-controller-manager|kube-scheduler' " +
+	"| xargs --no-run-if-empty crictl stop"
+
+// RenewCertificates renews the kubeadm-managed certificates and kubeconfigs
+// on every control plane node, including the admin kubeconfig the cluster
+// kubeconfig is read from, and restarts the control plane to load them. The
+// CA certificates are not renewed.
+func RenewCertificates(all []nodes.Node) error {
+	for _, n := range all {
+		if !isControlPlane(n) {
+			continue
+		}
+		if err := n.Command("kubeadm", "certs", "renew", "all").Run(); err != nil {
+			return errors.Wrapf(err, errRenewCertificates, n.String())
+		}
+		if err := n.Command("bash", "-c", restartControlPlane).Run(); err != nil {
+			return errors.Wrapf(err, errRestartControl, n.String())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+package kindnode
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/cluster/nodeutils"
+)
+
+const (
+	// execDir holds a marker per script that ran successfully on the node.
+	execDir = stateDir + "/exec"
+
+	// maxOutput is how much of a script's stdout and stderr is kept, from
+	// the end.
+	maxOutput = 4096
+)
+
+const (
+	errRunScript    = "cannot run script on node %s"
+	errScriptFailed = "script exited with code %d on node %s"
+	errRemoveMarker = "cannot remove marker file from node %s"
+)
+
+// ScriptResult is the outcome of running a script on a node.
+type ScriptResult struct {
+	ExitCode int
+	Stdout   string
+	Stderr   string
+}
+
+// HasRunScript reports whether the script ran successfully on the node under
+// the supplied name. A recreated node has not run it.
+func HasRunScript(n nodes.Node, name, script string) bool {
+	return readMarker(n, execMarker(name)) == digest([]byte(script))
+}
+
+// RunScript runs the script with bash inside the node, like kind's node
+// exec. A script that exits zero is recorded under the supplied name, so it
+// does not run again on the node until it changes. A script that exits
+// non-zero returns its result and an error.
+func RunScript(n nodes.Node, name, script string) (ScriptResult, error) {
+	var stdout, stderr bytes.Buffer
+	err := n.Command("bash", "-s").
+		SetStdin(strings.NewReader(script)).
+		SetStdout(&stdout).
+		SetStderr(&stderr).
+		Run()
+	r := ScriptResult{Stdout: tail(stdout.String()), Stderr: tail(stderr.String())}
+
+	var exit *exec.ExitError
+	switch {
+	case errors.As(errors.Cause(err), &exit):
+		r.ExitCode = exit.ExitCode()
+		return r, errors.Errorf(errScriptFailed, r.ExitCode, n.String())
+	case err != nil:
+		return r, errors.Wrapf(err, errRunScript, n.String())
+	}
+	if err := nodeutils.WriteFile(n, execMarker(name), digest([]byte(script))); err != nil {
+		return r, errors.Wrapf(err, errWriteMarker, n.String())
+	}
+	return r, nil
+}
+
+// ForgetScript removes the record of the named script from the node. Its
+// effects are not undone.
+func ForgetScript(n nodes.Node, name string) error {
+	return errors.Wrapf(n.Command("rm", "-f", execMarker(name)).Run(), errRemoveMarker, n.String())
+}
+
+// execMarker returns the marker file of the named script.
+func execMarker(name string) string {
+	return execDir + "/" + name + ".sha256"
+}
+
+// tail returns the last maxOutput bytes of s.
+func tail(s string) string {
+	if len(s) <= maxOutput {
+		return s
+	}
+	return s[len(s)-maxOutput:]
+}
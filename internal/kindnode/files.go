@@ -0,0 +1,108 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+package kindnode
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/cluster/nodeutils"
+)
+
+const (
+	filesMarker = stateDir + "/files.sha256"
+
+	// filesList lists the files installed by the provider, so that files
+	// that are no longer desired can be removed.
+	filesList = stateDir + "/files"
+)
+
+const (
+	errWriteFile        = "cannot write file %s to node %s"
+	errRemoveStaleFiles = "cannot remove stale files from node %s"
+)
+
+// File is a file copied into a node.
+type File struct {
+	Path    string
+	Content []byte
+
+	// Mode is the octal file mode, for example "0644".
+	Mode string
+}
+
+// Files is a set of files to copy into a node.
+type Files []File
+
+// NodeFiles are the files to copy into each node, by node name.
+type NodeFiles map[string]Files
+
+// Hash returns a digest of the files that identifies the installed set on a
+// node.
+func (f Files) Hash() string {
+	parts := make([][]byte, 0, 3*len(f))
+	for _, file := range f {
+		parts = append(parts, []byte(file.Path), []byte(file.Mode), file.Content)
+	}
+	return digest(parts...)
+}
+
+// HasFiles reports whether the node already has exactly the supplied files
+// installed.
+func HasFiles(n nodes.Node, files Files) bool {
+	installed := readMarker(n, filesMarker)
+	if len(files) == 0 && installed == "" {
+		return true
+	}
+	return installed == files.Hash()
+}
+
+// InstallFiles copies the supplied files into the node. Files previously
+// installed by the provider that are no longer desired are removed.
+func InstallFiles(n nodes.Node, files Files) error {
+	want := map[string]bool{}
+	for _, f := range files {
+		want[f.Path] = true
+	}
+	if installed := readMarker(n, filesList); installed != "" {
+		for _, p := range strings.Split(installed, "\n") {
+			if want[p] {
+				continue
+			}
+			if err := n.Command("rm", "-f", p).Run(); err != nil {
+				return errors.Wrapf(err, errRemoveStaleFiles, n.String())
+			}
+		}
+	}
+
+	paths := make([]string, 0, len(files))
+	for _, f := range files {
+		if err := nodeutils.WriteFile(n, f.Path, string(f.Content)); err != nil {
+			return errors.Wrapf(err, errWriteFile, f.Path, n.String())
+		}
+		if err := n.Command("chmod", f.Mode, f.Path).Run(); err != nil {
+			return errors.Wrapf(err, errWriteFile, f.Path, n.String())
+		}
+		paths = append(paths, f.Path)
+	}
+
+	if err := writeOrRemove(n, filesList, strings.Join(paths, "\n")); err != nil {
+		return errors.Wrapf(err, errWriteMarker, n.String())
+	}
+	if err := writeOrRemove(n, filesMarker, markerOf(files)); err != nil {
+		return errors.Wrapf(err, errWriteMarker, n.String())
+	}
+	return nil
+}
+
+// markerOf returns the marker content of the files, which is empty if there
+// are none.
+func markerOf(files Files) string {
+	if len(files) == 0 {
+		return ""
+	}
+	return files.Hash()
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+// Package kindnode configures KIND node containers in place. All changes are
+// made by executing commands inside the node containers, the same way KIND
+// itself configures nodes, so no host mounts are required.
+package kindnode
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/cluster/nodeutils"
+)
+
+const (
+	// stateDir holds provider-managed marker files inside each node.
+	stateDir = "/etc/provider-kind"
+
+	// certsDir is the containerd registry host configuration directory.
+	certsDir = "/etc/containerd/certs.d"
+)
+
+const (
+	errWriteMarker = "cannot write marker file to node %s"
+)
+
+// Config is the provider-managed configuration of a node.
+type Config struct {
+	// TrustedCAs are installed into the node trust store and trusted by
+	// containerd for every registry host.
+	TrustedCAs TrustedCAs
+
+	// Registries are rendered into containerd's registry host
+	// configuration.
+	Registries Registries
+
+	// Manifests are applied to the cluster from its control plane nodes.
+	Manifests Manifests
+
+	// Files are copied into the nodes they are listed for.
+	Files NodeFiles
+}
+
+// Empty reports whether the configuration requires no changes to nodes.
+func (c Config) Empty() bool {
+	return len(c.TrustedCAs) == 0 && c.Registries.empty() && len(c.Manifests) == 0 &&
+		len(c.Files) == 0
+}
+
+// UpToDate reports whether the node already has the supplied configuration.
+func UpToDate(n nodes.Node, c Config) bool {
+	return HasTrustedCAs(n, c.TrustedCAs) && HasRegistries(n, c.Registries, c.TrustedCAs) &&
+		HasManifests(n, c.Manifests) && HasFiles(n, c.Files[n.String()])
+}
+
+// Apply brings the node's provider-managed configuration in line with the
+// supplied one. Only the parts that differ are reapplied.
+func Apply(n nodes.Node, c Config) error {
+	if !HasTrustedCAs(n, c.TrustedCAs) {
+		if err := InstallTrustedCAs(n, c.TrustedCAs); err != nil {
+			return err
+		}
+	}
+	if !HasRegistries(n, c.Registries, c.TrustedCAs) {
+		if err := InstallRegistries(n, c.Registries, c.TrustedCAs); err != nil {
+			return err
+		}
+	}
+	if !HasManifests(n, c.Manifests) {
+		if err := InstallManifests(n, c.Manifests); err != nil {
+			return err
+		}
+	}
+	if files := c.Files[n.String()]; !HasFiles(n, files) {
+		if err := InstallFiles(n, files); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UpToDateAll reports whether every node that runs Kubernetes already has the
+// supplied configuration. The external load balancer of a cluster is
+// configured by KIND alone and has no shell to configure it with.
+func UpToDateAll(all []nodes.Node, c Config) bool {
+	for _, n := range Select(all, Selector{}) {
+		if !UpToDate(n, c) {
+			return false
+		}
+	}
+	return true
+}
+
+// ApplyAll applies the supplied configuration to every node that runs
+// Kubernetes, skipping the external load balancer of the cluster.
+func ApplyAll(all []nodes.Node, c Config) error {
+	for _, n := range Select(all, Selector{}) {
+		if err := Apply(n, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// digest returns a hex encoded SHA-256 digest of the supplied parts.
+func digest(parts ...[]byte) string {
+	h := sha256.New()
+	for _, p := range parts {
+		h.Write(p)
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// writeOrRemove writes content to file on the node, or removes the file if
+// content is empty.
+func writeOrRemove(n nodes.Node, file, content string) error {
+	if content == "" {
+		return n.Command("rm", "-f", file).Run()
+	}
+	return nodeutils.WriteFile(n, file, content)
+}
+
+// tomlStrings renders a TOML array of strings.
+func tomlStrings(s []string) string {
+	quoted := make([]string, 0, len(s))
+	for _, v := range s {
+		quoted = append(quoted, fmt.Sprintf("%q", v))
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+// readMarker returns the content of a marker file on the node, or an empty
+// string if it cannot be read.
+func readMarker(n nodes.Node, file string) string {
+	var out bytes.Buffer
+	if err := n.Command("cat", file).SetStdout(&out).Run(); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(out.String())
+}
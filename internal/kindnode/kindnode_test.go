@@ -0,0 +1,148 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+package kindnode
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/kind/pkg/cluster/constants"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/exec"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/test"
+)
+
+// fakeNode is a node whose files are kept in memory. Like the distroless
+// image KIND runs it from, the external load balancer fails every command.
+type fakeNode struct {
+	name     string
+	role     string
+	files    map[string]string
+	commands []string
+}
+
+func (n *fakeNode) String() string               { return n.name }
+func (n *fakeNode) Role() (string, error)        { return n.role, nil }
+func (n *fakeNode) IP() (string, string, error)  { return "", "", nil }
+func (n *fakeNode) SerialLogs(_ io.Writer) error { return nil }
+func (n *fakeNode) Command(name string, args ...string) exec.Cmd {
+	return &fakeCmd{node: n, args: append([]string{name}, args...)}
+}
+func (n *fakeNode) CommandContext(_ context.Context, name string, args ...string) exec.Cmd {
+	return n.Command(name, args...)
+}
+
+type fakeCmd struct {
+	node   *fakeNode
+	args   []string
+	stdin  io.Reader
+	stdout io.Writer
+}
+
+func (c *fakeCmd) SetEnv(...string) exec.Cmd      { return c }
+func (c *fakeCmd) SetStdin(r io.Reader) exec.Cmd  { c.stdin = r; return c }
+func (c *fakeCmd) SetStdout(w io.Writer) exec.Cmd { c.stdout = w; return c }
+func (c *fakeCmd) SetStderr(_ io.Writer) exec.Cmd { return c }
+
+func (c *fakeCmd) Run() error {
+	n := c.node
+	n.commands = append(n.commands, strings.Join(c.args, " "))
+	if n.role == constants.ExternalLoadBalancerNodeRoleValue {
+		return errors.Errorf("exec: %q: executable file not found in $PATH", c.args[0])
+	}
+	file := c.args[len(c.args)-1]
+	switch c.args[0] {
+	case "cat":
+		content, ok := n.files[file]
+		if !ok {
+			return errors.Errorf("cat: %s: No such file or directory", file)
+		}
+		if c.stdout != nil {
+			_, _ = io.WriteString(c.stdout, content)
+		}
+	case "cp":
+		b, err := io.ReadAll(c.stdin)
+		if err != nil {
+			return err
+		}
+		n.files[file] = string(b)
+	case "rm":
+		delete(n.files, file)
+	}
+	return nil
+}
+
+// haCluster returns the nodes of a cluster with two control plane nodes,
+// which KIND puts behind an external load balancer.
+func haCluster() (lb *fakeNode, all []nodes.Node) {
+	lb = &fakeNode{name: "ha-external-load-balancer", role: constants.ExternalLoadBalancerNodeRoleValue, files: map[string]string{}}
+	all = []nodes.Node{
+		lb,
+		&fakeNode{name: "ha-control-plane", role: constants.ControlPlaneNodeRoleValue, files: map[string]string{}},
+		&fakeNode{name: "ha-control-plane2", role: constants.ControlPlaneNodeRoleValue, files: map[string]string{}},
+	}
+	return lb, all
+}
+
+func TestApplyAll(t *testing.T) {
+	cfg := Config{TrustedCAs: TrustedCAs{[]byte("-----BEGIN CERTIFICATE-----")}}
+
+	type want struct {
+		err      error
+		upToDate bool
+	}
+
+	cases := map[string]struct {
+		reason string
+		apply  bool
+		want   want
+	}{
+		"NotApplied": {
+			reason: "Nodes should not be up to date before the configuration is applied.",
+		},
+		"HighAvailability": {
+			reason: "The configuration should be applied to every node but the external load balancer, and then be up to date.",
+			apply:  true,
+			want:   want{upToDate: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			lb, all := haCluster()
+			var err error
+			if tc.apply {
+				err = ApplyAll(all, cfg)
+			}
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nApplyAll(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.upToDate, UpToDateAll(all, cfg)); diff != "" {
+				t.Errorf("\n%s\nUpToDateAll(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff([]string(nil), lb.commands); diff != "" {
+				t.Errorf("\n%s\nexternal load balancer: -want commands, +got commands:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestSetOwner(t *testing.T) {
+	lb, all := haCluster()
+	if err := SetOwner(all, "team-a"); err != nil {
+		t.Fatalf("SetOwner(...): %v", err)
+	}
+	if diff := cmp.Diff("team-a", Owner(all)); diff != "" {
+		t.Errorf("Owner(...): -want, +got:\n%s", diff)
+	}
+	if diff := cmp.Diff([]string(nil), lb.commands); diff != "" {
+		t.Errorf("external load balancer: -want commands, +got commands:\n%s", diff)
+	}
+}
@@ -0,0 +1,414 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+package kindnode
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/kind/pkg/cluster/constants"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+)
+
+const (
+	loadBalancersMarker = stateDir + "/load-balancer-addresses"
+)
+
+const (
+	errParsePool        = "cannot parse address pool %q"
+	errPoolFamilies     = "address pool %q mixes IPv4 and IPv6 addresses"
+	errPoolOrder        = "address pool %q ends before it starts"
+	errPoolExhausted    = "address pool %s is exhausted: %d LoadBalancer Services are pending"
+	errNoControlPlane   = "cluster has no control plane node"
+	errListServices     = "cannot list Services from node %s"
+	errDecodeServices   = "cannot decode Services"
+	errPatchService     = "cannot set the address of Service %s/%s"
+	errAddAddress       = "cannot add address %s to node %s"
+	errRemoveAddress    = "cannot remove address %s from node %s"
+	errAddressInterface = "cannot find the interface of address %s on node %s"
+)
+
+// devRE matches the interface in ip route get output.
+var devRE = regexp.MustCompile(`\bdev (\S+)`)
+
+// AddressPool is an inclusive range of addresses assigned to LoadBalancer
+// Services.
+type AddressPool struct {
+	First netip.Addr
+	Last  netip.Addr
+}
+
+// ParseAddressPool parses a CIDR ("172.18.255.200/29") or an inclusive range
+// ("172.18.255.200-172.18.255.250").
+func ParseAddressPool(s string) (AddressPool, error) {
+	if first, last, ok := strings.Cut(s, "-"); ok {
+		f, err := netip.ParseAddr(strings.TrimSpace(first))
+		if err != nil {
+			return AddressPool{}, errors.Wrapf(err, errParsePool, s)
+		}
+		l, err := netip.ParseAddr(strings.TrimSpace(last))
+		if err != nil {
+			return AddressPool{}, errors.Wrapf(err, errParsePool, s)
+		}
+		if f.Is4() != l.Is4() {
+			return AddressPool{}, errors.Errorf(errPoolFamilies, s)
+		}
+		if l.Less(f) {
+			return AddressPool{}, errors.Errorf(errPoolOrder, s)
+		}
+		return AddressPool{First: f, Last: l}, nil
+	}
+
+	p, err := netip.ParsePrefix(strings.TrimSpace(s))
+	if err != nil {
+		return AddressPool{}, errors.Wrapf(err, errParsePool, s)
+	}
+	p = p.Masked()
+	last := p.Addr().As16()
+	bits := p.Bits()
+	if p.Addr().Is4() {
+		bits += 96
+	}
+	for i := bits; i < 128; i++ {
+		last[i/8] |= 1 << (7 - i%8)
+	}
+	l := netip.AddrFrom16(last)
+	if p.Addr().Is4() {
+		l = l.Unmap()
+	}
+	return AddressPool{First: p.Addr(), Last: l}, nil
+}
+
+// String renders the pool as an inclusive range.
+func (p AddressPool) String() string {
+	return fmt.Sprintf("%s-%s", p.First, p.Last)
+}
+
+// Contains reports whether the address is in the pool.
+func (p AddressPool) Contains(a netip.Addr) bool {
+	return a.IsValid() && a.Is4() == p.First.Is4() && !a.Less(p.First) && !p.Last.Less(a)
+}
+
+// Within reports whether the whole pool lies within the prefix.
+func (p AddressPool) Within(prefix netip.Prefix) bool {
+	return prefix.Contains(p.First) && prefix.Contains(p.Last)
+}
+
+// LoadBalancerService is a Service of type LoadBalancer and the address
+// assigned to it.
+type LoadBalancerService struct {
+	Namespace string
+	Name      string
+	IP        netip.Addr
+
+	// requested is the address set in spec.loadBalancerIP.
+	requested netip.Addr
+
+	// assigned is the address currently in the Service status.
+	assigned netip.Addr
+}
+
+// LoadBalancers returns the LoadBalancer Services of the cluster and the
+// addresses assigned to them from the pool, and reports whether the
+// addresses are published in the Service status and routed to a node. A nil
+// pool reports whether addresses from an earlier pool remain on the nodes.
+func LoadBalancers(all []nodes.Node, pool *AddressPool) ([]LoadBalancerService, bool) {
+	svcs, holder, err := planLoadBalancers(all, pool)
+	if err != nil {
+		return nil, false
+	}
+	want := make([]netip.Addr, 0, len(svcs))
+	for _, s := range svcs {
+		if !s.IP.IsValid() || s.IP != s.assigned {
+			return svcs, false
+		}
+		want = append(want, s.IP)
+	}
+	for _, n := range Select(all, Selector{}) {
+		if n == holder {
+			if !hasAddresses(n, want) {
+				return svcs, false
+			}
+			continue
+		}
+		if readMarker(n, loadBalancersMarker) != "" {
+			return svcs, false
+		}
+	}
+	return svcs, true
+}
+
+// SyncLoadBalancers assigns an address from the pool to every LoadBalancer
+// Service, publishes it in the Service status, and adds the addresses to a
+// single node, preferring workers. Traffic to an address reaches that node
+// over the Docker network, and kube-proxy forwards it to the Service. A nil
+// pool removes addresses from an earlier pool.
+func SyncLoadBalancers(all []nodes.Node, pool *AddressPool) error {
+	svcs, holder, err := planLoadBalancers(all, pool)
+	if err != nil {
+		return err
+	}
+
+	want := make([]netip.Addr, 0, len(svcs))
+	pending := 0
+	for _, s := range svcs {
+		if !s.IP.IsValid() {
+			pending++
+			continue
+		}
+		want = append(want, s.IP)
+		if s.IP != s.assigned {
+			if err := patchServiceStatus(controlPlane(all), s); err != nil {
+				return err
+			}
+		}
+	}
+
+	// The external load balancer of the cluster never holds addresses, and
+	// has no shell to remove them with.
+	for _, n := range Select(all, Selector{}) {
+		if n == holder {
+			continue
+		}
+		if err := installAddresses(n, nil); err != nil {
+			return err
+		}
+	}
+	if holder != nil {
+		if err := installAddresses(holder, want); err != nil {
+			return err
+		}
+	}
+
+	if pending > 0 {
+		return errors.Errorf(errPoolExhausted, pool, pending)
+	}
+	return nil
+}
+
+// planLoadBalancers lists the LoadBalancer Services and assigns each an
+// address. Addresses already assigned from the pool are kept, then
+// requested addresses are honoured, then the lowest free addresses are used.
+// It also selects the node that holds the addresses.
+func planLoadBalancers(all []nodes.Node, pool *AddressPool) ([]LoadBalancerService, nodes.Node, error) {
+	holder := loadBalancerNode(all)
+	if pool == nil {
+		return nil, nil, nil
+	}
+	cp := controlPlane(all)
+	if holder == nil || cp == nil {
+		return nil, nil, errors.New(errNoControlPlane)
+	}
+
+	svcs, err := listLoadBalancers(cp)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	used := map[netip.Addr]bool{}
+	for i := range svcs {
+		if a := svcs[i].assigned; pool.Contains(a) && !used[a] {
+			svcs[i].IP = a
+			used[a] = true
+		}
+	}
+	for i := range svcs {
+		if a := svcs[i].requested; !svcs[i].IP.IsValid() && pool.Contains(a) && !used[a] {
+			svcs[i].IP = a
+			used[a] = true
+		}
+	}
+	next := pool.First
+	for i := range svcs {
+		if svcs[i].IP.IsValid() {
+			continue
+		}
+		for pool.Contains(next) && used[next] {
+			next = next.Next()
+		}
+		if !pool.Contains(next) {
+			break
+		}
+		svcs[i].IP = next
+		used[next] = true
+	}
+	return svcs, holder, nil
+}
+
+// loadBalancerNode returns the node that holds the load balancer addresses:
+// the first worker by name, or the first control plane node if the cluster
+// has no workers.
+func loadBalancerNode(all []nodes.Node) nodes.Node {
+	var workers, controlPlanes []nodes.Node
+	for _, n := range all {
+		role, err := n.Role()
+		switch {
+		case err != nil:
+		case role == constants.WorkerNodeRoleValue:
+			workers = append(workers, n)
+		case role == constants.ControlPlaneNodeRoleValue:
+			controlPlanes = append(controlPlanes, n)
+		}
+	}
+	for _, candidates := range [][]nodes.Node{workers, controlPlanes} {
+		if len(candidates) > 0 {
+			sort.Slice(candidates, func(i, j int) bool { return candidates[i].String() < candidates[j].String() })
+			return candidates[0]
+		}
+	}
+	return nil
+}
+
+// controlPlane returns the first control plane node, or nil if there is
+// none.
+func controlPlane(all []nodes.Node) nodes.Node {
+	for _, n := range all {
+		if isControlPlane(n) {
+			return n
+		}
+	}
+	return nil
+}
+
+// listLoadBalancers lists the LoadBalancer Services using kubectl on the
+// control plane node, sorted by namespace and name.
+func listLoadBalancers(cp nodes.Node) ([]LoadBalancerService, error) {
+	var out bytes.Buffer
+	cmd := cp.Command("kubectl", "--kubeconfig="+adminKubeconfig, "get", "services",
+		"--all-namespaces", "--output=json").SetStdout(&out)
+	if err := cmd.Run(); err != nil {
+		return nil, errors.Wrapf(err, errListServices, cp.String())
+	}
+	list := &corev1.ServiceList{}
+	if err := json.Unmarshal(out.Bytes(), list); err != nil {
+		return nil, errors.Wrap(err, errDecodeServices)
+	}
+
+	svcs := make([]LoadBalancerService, 0, len(list.Items))
+	for _, s := range list.Items {
+		if s.Spec.Type != corev1.ServiceTypeLoadBalancer {
+			continue
+		}
+		lb := LoadBalancerService{Namespace: s.GetNamespace(), Name: s.GetName()}
+		lb.requested, _ = netip.ParseAddr(s.Spec.LoadBalancerIP)
+		if ingress := s.Status.LoadBalancer.Ingress; len(ingress) == 1 {
+			lb.assigned, _ = netip.ParseAddr(ingress[0].IP)
+		}
+		svcs = append(svcs, lb)
+	}
+	sort.Slice(svcs, func(i, j int) bool {
+		if svcs[i].Namespace != svcs[j].Namespace {
+			return svcs[i].Namespace < svcs[j].Namespace
+		}
+		return svcs[i].Name < svcs[j].Name
+	})
+	return svcs, nil
+}
+
+// patchServiceStatus publishes the Service's address in its status using
+// kubectl on the control plane node.
+func patchServiceStatus(cp nodes.Node, s LoadBalancerService) error {
+	status := fmt.Sprintf(`{"status":{"loadBalancer":{"ingress":[{"ip":%q}]}}}`, s.IP)
+	cmd := cp.Command("kubectl", "--kubeconfig="+adminKubeconfig, "--namespace="+s.Namespace,
+		"patch", "service", s.Name, "--subresource=status", "--type=merge", "--patch="+status)
+	if err := cmd.Run(); err != nil {
+		return errors.Wrapf(err, errPatchService, s.Namespace, s.Name)
+	}
+	return nil
+}
+
+// hasAddresses reports whether exactly the supplied addresses were added to
+// the node, and are still present.
+func hasAddresses(n nodes.Node, want []netip.Addr) bool {
+	if readMarker(n, loadBalancersMarker) != joinAddrs(want) {
+		return false
+	}
+	if len(want) == 0 {
+		return true
+	}
+	var out bytes.Buffer
+	if err := n.Command("ip", "-o", "addr", "show").SetStdout(&out).Run(); err != nil {
+		return false
+	}
+	for _, a := range want {
+		if !strings.Contains(out.String(), " "+hostPrefix(a).String()+" ") {
+			return false
+		}
+	}
+	return true
+}
+
+// installAddresses adds the addresses to the interface of the node that
+// reaches them, removing addresses it added earlier that are no longer
+// wanted.
+func installAddresses(n nodes.Node, want []netip.Addr) error {
+	installed := readMarker(n, loadBalancersMarker)
+	if installed == "" && len(want) == 0 {
+		// Nodes that never held addresses are left alone.
+		return nil
+	}
+
+	keep := map[netip.Addr]bool{}
+	for _, a := range want {
+		keep[a] = true
+	}
+	for _, s := range strings.Fields(installed) {
+		a, err := netip.ParseAddr(s)
+		if err != nil || keep[a] {
+			continue
+		}
+		var out bytes.Buffer
+		if err := n.Command("ip", "-o", "addr", "show", "to", hostPrefix(a).String()).SetStdout(&out).Run(); err != nil {
+			return errors.Wrapf(err, errRemoveAddress, a, n.String())
+		}
+		fields := strings.Fields(out.String())
+		if len(fields) < 2 {
+			continue
+		}
+		if err := n.Command("ip", "addr", "del", hostPrefix(a).String(), "dev", fields[1]).Run(); err != nil {
+			return errors.Wrapf(err, errRemoveAddress, a, n.String())
+		}
+	}
+
+	for _, a := range want {
+		var out bytes.Buffer
+		if err := n.Command("ip", "-o", "route", "get", a.String()).SetStdout(&out).Run(); err != nil {
+			return errors.Wrapf(err, errAddressInterface, a, n.String())
+		}
+		m := devRE.FindStringSubmatch(out.String())
+		if m == nil {
+			return errors.Errorf(errAddressInterface, a, n.String())
+		}
+		if err := n.Command("ip", "addr", "replace", hostPrefix(a).String(), "dev", m[1]).Run(); err != nil {
+			return errors.Wrapf(err, errAddAddress, a, n.String())
+		}
+	}
+
+	if err := writeOrRemove(n, loadBalancersMarker, joinAddrs(want)); err != nil {
+		return errors.Wrapf(err, errWriteMarker, n.String())
+	}
+	return nil
+}
+
+// hostPrefix returns the single address prefix of the address.
+func hostPrefix(a netip.Addr) netip.Prefix {
+	return netip.PrefixFrom(a, a.BitLen())
+}
+
+// joinAddrs renders addresses the way they are recorded in the marker.
+func joinAddrs(addrs []netip.Addr) string {
+	s := make([]string, 0, len(addrs))
+	for _, a := range addrs {
+		s = append(s, a.String())
+	}
+	return strings.Join(s, " ")
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+package kindnode
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/test"
+)
+
+func TestParseAddressPool(t *testing.T) {
+	_, errAddr := netip.ParseAddr("172.18.255")
+	_, errPrefix := netip.ParsePrefix("172.18.255.0")
+
+	type want struct {
+		pool AddressPool
+		err  error
+	}
+
+	cases := map[string]struct {
+		reason string
+		s      string
+		want   want
+	}{
+		"Range": {
+			reason: "An inclusive range should be parsed, ignoring spaces around its ends.",
+			s:      "172.18.255.200 - 172.18.255.250",
+			want: want{pool: AddressPool{
+				First: netip.MustParseAddr("172.18.255.200"),
+				Last:  netip.MustParseAddr("172.18.255.250"),
+			}},
+		},
+		"SingleAddressRange": {
+			reason: "A range may hold a single address.",
+			s:      "172.18.255.200-172.18.255.200",
+			want: want{pool: AddressPool{
+				First: netip.MustParseAddr("172.18.255.200"),
+				Last:  netip.MustParseAddr("172.18.255.200"),
+			}},
+		},
+		"CIDR": {
+			reason: "A CIDR should be masked and span all its addresses.",
+			s:      "172.18.255.203/29",
+			want: want{pool: AddressPool{
+				First: netip.MustParseAddr("172.18.255.200"),
+				Last:  netip.MustParseAddr("172.18.255.207"),
+			}},
+		},
+		"IPv6CIDR": {
+			reason: "An IPv6 CIDR should span all its addresses.",
+			s:      "fc00:f853:ccd:e793::100/120",
+			want: want{pool: AddressPool{
+				First: netip.MustParseAddr("fc00:f853:ccd:e793::100"),
+				Last:  netip.MustParseAddr("fc00:f853:ccd:e793::1ff"),
+			}},
+		},
+		"InvalidRangeAddress": {
+			reason: "A range with an invalid address should return an error.",
+			s:      "172.18.255-172.18.255.250",
+			want:   want{err: errors.Wrapf(errAddr, errParsePool, "172.18.255-172.18.255.250")},
+		},
+		"MixedFamilies": {
+			reason: "A range mixing IPv4 and IPv6 addresses should return an error.",
+			s:      "172.18.255.200-fc00::1",
+			want:   want{err: errors.Errorf(errPoolFamilies, "172.18.255.200-fc00::1")},
+		},
+		"Reversed": {
+			reason: "A range that ends before it starts should return an error.",
+			s:      "172.18.255.250-172.18.255.200",
+			want:   want{err: errors.Errorf(errPoolOrder, "172.18.255.250-172.18.255.200")},
+		},
+		"InvalidCIDR": {
+			reason: "Neither a range nor a CIDR should return an error.",
+			s:      "172.18.255.0",
+			want:   want{err: errors.Wrapf(errPrefix, errParsePool, "172.18.255.0")},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseAddressPool(tc.s)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nParseAddressPool(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.pool.String(), got.String()); diff != "" {
+				t.Errorf("\n%s\nParseAddressPool(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestSyncLoadBalancersWithoutPool(t *testing.T) {
+	type want struct {
+		err      error
+		commands map[string][]string
+	}
+
+	cases := map[string]struct {
+		reason  string
+		markers map[string]string
+		want    want
+	}{
+		"NoAddresses": {
+			reason: "Nodes that never held addresses should only be read, and the external load balancer not at all.",
+			want: want{commands: map[string][]string{
+				"ha-control-plane":  {"cat " + loadBalancersMarker},
+				"ha-control-plane2": {"cat " + loadBalancersMarker},
+			}},
+		},
+		"StaleAddresses": {
+			reason:  "Addresses from an earlier pool should be removed from the node that held them.",
+			markers: map[string]string{"ha-control-plane2": "172.18.255.200"},
+			want: want{commands: map[string][]string{
+				"ha-control-plane": {"cat " + loadBalancersMarker},
+				"ha-control-plane2": {
+					"cat " + loadBalancersMarker,
+					"ip -o addr show to 172.18.255.200/32",
+					"rm -f " + loadBalancersMarker,
+				},
+			}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, all := haCluster()
+			for _, n := range all {
+				if m, ok := tc.markers[n.String()]; ok {
+					n.(*fakeNode).files[loadBalancersMarker] = m
+				}
+			}
+			err := SyncLoadBalancers(all, nil)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nSyncLoadBalancers(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			got := map[string][]string{}
+			for _, n := range all {
+				if c := n.(*fakeNode).commands; len(c) > 0 {
+					got[n.String()] = c
+				}
+			}
+			if diff := cmp.Diff(tc.want.commands, got); diff != "" {
+				t.Errorf("\n%s\nSyncLoadBalancers(...): -want commands, +got commands:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+package kindnode
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/kind/pkg/cluster/constants"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/cluster/nodeutils"
+)
+
+const (
+	manifestsMarker = stateDir + "/manifests.sha256"
+
+	// adminKubeconfig is the cluster-admin kubeconfig kubeadm writes on
+	// every control plane node.
+	adminKubeconfig = "/etc/kubernetes/admin.conf"
+)
+
+const (
+	errApplyManifests = "cannot apply manifests from node %s"
+)
+
+// Manifests are YAML documents applied to the cluster API server. They are
+// applied from the control plane nodes with kubectl, so they work before
+// the cluster is reachable from the provider. Objects are never deleted when
+// they are removed from the list.
+type Manifests []string
+
+// hash returns a digest of the manifests.
+func (m Manifests) hash() string {
+	parts := make([][]byte, 0, len(m))
+	for _, doc := range m {
+		parts = append(parts, []byte(doc))
+	}
+	return digest(parts...)
+}
+
+// HasManifests reports whether the manifests were already applied from the
+// node. Worker nodes never apply manifests.
+func HasManifests(n nodes.Node, m Manifests) bool {
+	if !isControlPlane(n) {
+		return true
+	}
+	installed := readMarker(n, manifestsMarker)
+	if len(m) == 0 {
+		return installed == ""
+	}
+	return installed == m.hash()
+}
+
+// InstallManifests applies the manifests from a control plane node.
+func InstallManifests(n nodes.Node, m Manifests) error {
+	if !isControlPlane(n) {
+		return nil
+	}
+	if len(m) == 0 {
+		return errors.Wrapf(writeOrRemove(n, manifestsMarker, ""), errWriteMarker, n.String())
+	}
+	cmd := n.Command("kubectl", "--kubeconfig="+adminKubeconfig, "apply", "-f", "-").
+		SetStdin(strings.NewReader(strings.Join(m, "\n---\n")))
+	if err := cmd.Run(); err != nil {
+		return errors.Wrapf(err, errApplyManifests, n.String())
+	}
+	if err := nodeutils.WriteFile(n, manifestsMarker, m.hash()); err != nil {
+		return errors.Wrapf(err, errWriteMarker, n.String())
+	}
+	return nil
+}
+
+// isControlPlane reports whether the node runs the Kubernetes control plane.
+func isControlPlane(n nodes.Node) bool {
+	role, err := n.Role()
+	return err == nil && role == constants.ControlPlaneNodeRoleValue
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+package kindnode
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+)
+
+const (
+	errApplyObjects  = "cannot apply objects from node %s: %s"
+	errDiffObjects   = "cannot diff objects from node %s: %s"
+	errDeleteObjects = "cannot delete objects from node %s: %s"
+)
+
+// ApplyObjects server-side applies the manifest from a control plane node.
+func ApplyObjects(all []nodes.Node, manifest []byte) error {
+	cp := controlPlane(all)
+	if cp == nil {
+		return errors.New(errNoControlPlane)
+	}
+	stderr, err := serverSideApply(cp, manifest)
+	return errors.Wrapf(err, errApplyObjects, cp.String(), stderr)
+}
+
+// ObjectsDrifted reports whether server-side applying the manifest would
+// change the cluster, because objects are missing or differ from it.
+func ObjectsDrifted(all []nodes.Node, manifest []byte) (bool, error) {
+	cp := controlPlane(all)
+	if cp == nil {
+		return false, errors.New(errNoControlPlane)
+	}
+	var stderr bytes.Buffer
+	err := kubectl(cp, &bytes.Buffer{}, "diff", "--server-side", "--force-conflicts", "--field-manager="+fieldManager, "-f", "-").
+		SetStdin(bytes.NewReader(manifest)).
+		SetStderr(&stderr).
+		Run()
+	// kubectl diff exits with 1 when there are differences.
+	var exit *exec.ExitError
+	if errors.As(errors.Cause(err), &exit) && exit.ExitCode() == 1 {
+		return true, nil
+	}
+	return false, errors.Wrapf(err, errDiffObjects, cp.String(), strings.TrimSpace(stderr.String()))
+}
+
+// ObjectsExist reports whether any of the objects of the manifest exist.
+// Objects of kinds the cluster does not serve do not exist.
+func ObjectsExist(all []nodes.Node, manifest []byte) bool {
+	cp := controlPlane(all)
+	if cp == nil {
+		return false
+	}
+	var out bytes.Buffer
+	_ = kubectl(cp, &out, "get", "--ignore-not-found", "--output=name", "-f", "-").
+		SetStdin(bytes.NewReader(manifest)).
+		Run()
+	return strings.TrimSpace(out.String()) != ""
+}
+
+// DeleteObjects deletes the objects of the manifest from a control plane
+// node. Objects that do not exist are ignored.
+func DeleteObjects(all []nodes.Node, manifest []byte) error {
+	cp := controlPlane(all)
+	if cp == nil {
+		return errors.New(errNoControlPlane)
+	}
+	var stderr bytes.Buffer
+	err := kubectl(cp, nil, "delete", "--ignore-not-found", "--wait=false", "-f", "-").
+		SetStdin(bytes.NewReader(manifest)).
+		SetStderr(&stderr).
+		Run()
+	return errors.Wrapf(err, errDeleteObjects, cp.String(), strings.TrimSpace(stderr.String()))
+}
+
+// serverSideApply server-side applies the manifest from the control plane
+// node, taking ownership of fields other managers set. It returns what
+// kubectl wrote to stderr.
+func serverSideApply(cp nodes.Node, manifest []byte) (string, error) {
+	var stderr bytes.Buffer
+	err := kubectl(cp, nil, "apply", "--server-side", "--force-conflicts", "--field-manager="+fieldManager, "-f", "-").
+		SetStdin(bytes.NewReader(manifest)).
+		SetStderr(&stderr).
+		Run()
+	return strings.TrimSpace(stderr.String()), err
+}
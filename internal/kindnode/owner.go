@@ -0,0 +1,41 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+package kindnode
+
+import (
+	"github.com/pkg/errors"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+)
+
+// ownerMarker holds the namespace whose managed resource created the
+// cluster.
+const ownerMarker = stateDir + "/owner"
+
+const errWriteOwner = "cannot record the owner of node %s"
+
+// Owner returns the namespace recorded as the owner of the cluster, or an
+// empty string if none is recorded on any of its nodes.
+func Owner(all []nodes.Node) string {
+	for _, n := range Select(all, Selector{}) {
+		if owner := readMarker(n, ownerMarker); owner != "" {
+			return owner
+		}
+	}
+	return ""
+}
+
+// SetOwner records the namespace as the owner of the cluster on every node
+// that does not record it yet.
+func SetOwner(all []nodes.Node, namespace string) error {
+	for _, n := range Select(all, Selector{}) {
+		if readMarker(n, ownerMarker) == namespace {
+			continue
+		}
+		if err := writeOrRemove(n, ownerMarker, namespace); err != nil {
+			return errors.Wrapf(err, errWriteOwner, n.String())
+		}
+	}
+	return nil
+}
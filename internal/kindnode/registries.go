@@ -0,0 +1,230 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+package kindnode
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/cluster/nodeutils"
+)
+
+const (
+	registriesMarker   = stateDir + "/registries.sha256"
+	registriesHostList = stateDir + "/registries.hosts"
+	registryAuthMarker = stateDir + "/registry-auth.sha256"
+
+	// kubeletDockerConfig is read by the kubelet for image pull
+	// credentials, which it passes on to containerd.
+	kubeletDockerConfig = "/var/lib/kubelet/config.json"
+
+	dockerHubHost   = "docker.io"
+	dockerHubServer = "https://registry-1.docker.io"
+)
+
+const (
+	errClearHosts     = "cannot remove stale registry configuration from node %s"
+	errWriteRegistry  = "cannot write registry configuration to node %s"
+	errWriteAuth      = "cannot write registry credentials to node %s"
+	errRestartKubelet = "cannot restart kubelet on node %s"
+)
+
+// Registries is the resolved registry configuration of a cluster.
+type Registries struct {
+	// Hosts are the configured registry hosts.
+	Hosts []Registry
+
+	// Auth is a Docker config.json holding credentials for the registry
+	// hosts. It is made available to the kubelet, which supplies the
+	// credentials to containerd when pulling images.
+	Auth []byte
+}
+
+// Registry is the resolved configuration of a single registry host.
+type Registry struct {
+	Host    string
+	Server  string
+	TLS     TLS
+	Mirrors []Mirror
+}
+
+// Mirror is the resolved configuration of a registry mirror.
+type Mirror struct {
+	Endpoint     string
+	Capabilities []string
+	OverridePath bool
+	TLS          TLS
+}
+
+// TLS is the resolved TLS configuration of a registry endpoint.
+type TLS struct {
+	InsecureSkipVerify bool
+	CAs                [][]byte
+	ClientCert         []byte
+	ClientKey          []byte
+}
+
+// empty reports whether no registry configuration is desired.
+func (r Registries) empty() bool {
+	return len(r.Hosts) == 0 && len(r.Auth) == 0
+}
+
+// files renders the containerd host configuration files, keyed by their path
+// inside the node. Registry hosts get their own hosts.toml, so the trusted CA
+// paths are included in each of them as well.
+func (r Registries) files(trusted []string) map[string]string {
+	files := map[string]string{}
+	for _, reg := range r.Hosts {
+		dir := path.Join(certsDir, reg.Host)
+		var b strings.Builder
+
+		server := reg.Server
+		if server == "" {
+			server = defaultServer(reg.Host)
+		}
+		fmt.Fprintf(&b, "server = %q\n", server)
+		writeTLS(&b, files, "", reg.TLS, trusted, dir, "")
+
+		for i, m := range reg.Mirrors {
+			fmt.Fprintf(&b, "\n[host.%q]\n", m.Endpoint)
+			caps := m.Capabilities
+			if len(caps) == 0 {
+				caps = []string{"pull", "resolve"}
+			}
+			fmt.Fprintf(&b, "  capabilities = %s\n", tomlStrings(caps))
+			if m.OverridePath {
+				b.WriteString("  override_path = true\n")
+			}
+			writeTLS(&b, files, "  ", m.TLS, trusted, dir, fmt.Sprintf("mirror-%d-", i))
+		}
+		files[path.Join(dir, "hosts.toml")] = b.String()
+	}
+	return files
+}
+
+// writeTLS renders the TLS settings of an endpoint into b, adding any
+// certificate files it references to files.
+func writeTLS(b *strings.Builder, files map[string]string, indent string, t TLS, trusted []string, dir, prefix string) {
+	if t.InsecureSkipVerify {
+		fmt.Fprintf(b, "%sskip_verify = true\n", indent)
+	}
+	cas := append([]string{}, trusted...)
+	for i, pem := range t.CAs {
+		p := path.Join(dir, fmt.Sprintf("%sca-%d.crt", prefix, i))
+		files[p] = string(pem)
+		cas = append(cas, p)
+	}
+	if len(cas) > 0 {
+		fmt.Fprintf(b, "%sca = %s\n", indent, tomlStrings(cas))
+	}
+	if len(t.ClientCert) > 0 && len(t.ClientKey) > 0 {
+		crt := path.Join(dir, prefix+"client.crt")
+		key := path.Join(dir, prefix+"client.key")
+		files[crt] = string(t.ClientCert)
+		files[key] = string(t.ClientKey)
+		fmt.Fprintf(b, "%sclient = [%s]\n", indent, tomlStrings([]string{crt, key}))
+	}
+}
+
+// defaultServer returns the upstream URL of a registry host.
+func defaultServer(host string) string {
+	if host == dockerHubHost {
+		return dockerHubServer
+	}
+	return "https://" + host
+}
+
+// hash returns a digest of the rendered registry configuration.
+func (r Registries) hash(trusted []string) string {
+	files := r.files(trusted)
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	parts := make([][]byte, 0, 2*len(paths)+1)
+	for _, p := range paths {
+		parts = append(parts, []byte(p), []byte(files[p]))
+	}
+	parts = append(parts, r.Auth)
+	return digest(parts...)
+}
+
+// HasRegistries reports whether the node already has the supplied registry
+// configuration.
+func HasRegistries(n nodes.Node, r Registries, cas TrustedCAs) bool {
+	installed := readMarker(n, registriesMarker)
+	if r.empty() && installed == "" {
+		return true
+	}
+	return installed == r.hash(cas.Paths())
+}
+
+// InstallRegistries renders the supplied registry configuration into the
+// node. Host configuration previously written by the provider for registries
+// that are no longer desired is removed. The kubelet is restarted only when
+// the registry credentials change.
+func InstallRegistries(n nodes.Node, r Registries, cas TrustedCAs) error {
+	for _, host := range strings.Fields(readMarker(n, registriesHostList)) {
+		if err := n.Command("rm", "-rf", path.Join(certsDir, host)).Run(); err != nil {
+			return errors.Wrapf(err, errClearHosts, n.String())
+		}
+	}
+
+	files := r.files(cas.Paths())
+	for p, content := range files {
+		if err := writePrivateFile(n, p, content); err != nil {
+			return errors.Wrapf(err, errWriteRegistry, n.String())
+		}
+	}
+
+	hosts := make([]string, 0, len(r.Hosts))
+	for _, reg := range r.Hosts {
+		hosts = append(hosts, reg.Host)
+	}
+	if err := writeOrRemove(n, registriesHostList, strings.Join(hosts, "\n")); err != nil {
+		return errors.Wrapf(err, errWriteMarker, n.String())
+	}
+
+	authHash := ""
+	if len(r.Auth) > 0 {
+		authHash = digest(r.Auth)
+	}
+	if readMarker(n, registryAuthMarker) != authHash {
+		var err error
+		if len(r.Auth) > 0 {
+			err = writePrivateFile(n, kubeletDockerConfig, string(r.Auth))
+		} else {
+			err = n.Command("rm", "-f", kubeletDockerConfig).Run()
+		}
+		if err != nil {
+			return errors.Wrapf(err, errWriteAuth, n.String())
+		}
+		// The kubelet only reads its credentials file on start.
+		if err := n.Command("systemctl", "restart", "kubelet").Run(); err != nil {
+			return errors.Wrapf(err, errRestartKubelet, n.String())
+		}
+		if err := writeOrRemove(n, registryAuthMarker, authHash); err != nil {
+			return errors.Wrapf(err, errWriteMarker, n.String())
+		}
+	}
+
+	if err := nodeutils.WriteFile(n, registriesMarker, r.hash(cas.Paths())); err != nil {
+		return errors.Wrapf(err, errWriteMarker, n.String())
+	}
+	return nil
+}
+
+// writePrivateFile writes content to file on the node, readable only by root.
+func writePrivateFile(n nodes.Node, file, content string) error {
+	if err := nodeutils.WriteFile(n, file, content); err != nil {
+		return err
+	}
+	return n.Command("chmod", "0600", file).Run()
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+package kindnode
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRegistriesFiles(t *testing.T) {
+	type args struct {
+		r       Registries
+		trusted []string
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   map[string]string
+	}{
+		"NoHosts": {
+			reason: "No hosts.toml files should be rendered without registry hosts.",
+			args:   args{r: Registries{Auth: []byte("{}")}},
+			want:   map[string]string{},
+		},
+		"DockerHubDefaultServer": {
+			reason: "Docker Hub should default to its registry-1 upstream.",
+			args:   args{r: Registries{Hosts: []Registry{{Host: "docker.io"}}}},
+			want: map[string]string{
+				"/etc/containerd/certs.d/docker.io/hosts.toml": "server = \"https://registry-1.docker.io\"\n",
+			},
+		},
+		"OtherHostDefaultServer": {
+			reason: "Other hosts should default to HTTPS on the host itself.",
+			args:   args{r: Registries{Hosts: []Registry{{Host: "ghcr.io"}}}},
+			want: map[string]string{
+				"/etc/containerd/certs.d/ghcr.io/hosts.toml": "server = \"https://ghcr.io\"\n",
+			},
+		},
+		"TrustedCAs": {
+			reason: "Trusted CA paths should be listed for the host.",
+			args: args{
+				r:       Registries{Hosts: []Registry{{Host: "registry.local", Server: "http://registry.local:5000"}}},
+				trusted: []string{"/usr/local/share/ca-certificates/provider-kind-0.crt"},
+			},
+			want: map[string]string{
+				"/etc/containerd/certs.d/registry.local/hosts.toml": "server = \"http://registry.local:5000\"\n" +
+					"ca = [\"/usr/local/share/ca-certificates/provider-kind-0.crt\"]\n",
+			},
+		},
+		"HostTLS": {
+			reason: "Host CAs and client certificates should be written next to hosts.toml and referenced from it.",
+			args: args{r: Registries{Hosts: []Registry{{
+				Host: "registry.local",
+				TLS: TLS{
+					InsecureSkipVerify: true,
+					CAs:                [][]byte{[]byte("CA")},
+					ClientCert:         []byte("CERT"),
+					ClientKey:          []byte("KEY"),
+				},
+			}}}},
+			want: map[string]string{
+				"/etc/containerd/certs.d/registry.local/hosts.toml": "server = \"https://registry.local\"\n" +
+					"skip_verify = true\n" +
+					"ca = [\"/etc/containerd/certs.d/registry.local/ca-0.crt\"]\n" +
+					"client = [[\"/etc/containerd/certs.d/registry.local/client.crt\", \"/etc/containerd/certs.d/registry.local/client.key\"]]\n",
+				"/etc/containerd/certs.d/registry.local/ca-0.crt":   "CA",
+				"/etc/containerd/certs.d/registry.local/client.crt": "CERT",
+				"/etc/containerd/certs.d/registry.local/client.key": "KEY",
+			},
+		},
+		"ClientCertWithoutKey": {
+			reason: "A client certificate without a key should be ignored.",
+			args: args{r: Registries{Hosts: []Registry{{
+				Host: "registry.local",
+				TLS:  TLS{ClientCert: []byte("CERT")},
+			}}}},
+			want: map[string]string{
+				"/etc/containerd/certs.d/registry.local/hosts.toml": "server = \"https://registry.local\"\n",
+			},
+		},
+		"Mirrors": {
+			reason: "Mirrors should be rendered in order as host tables, defaulting to the pull and resolve capabilities.",
+			args: args{r: Registries{Hosts: []Registry{{
+				Host: "docker.io",
+				Mirrors: []Mirror{
+					{Endpoint: "http://mirror-a:5000"},
+					{
+						Endpoint:     "https://mirror-b",
+						Capabilities: []string{"pull"},
+						OverridePath: true,
+						TLS:          TLS{CAs: [][]byte{[]byte("MIRROR-CA")}},
+					},
+				},
+			}}}},
+			want: map[string]string{
+				"/etc/containerd/certs.d/docker.io/hosts.toml": "server = \"https://registry-1.docker.io\"\n" +
+					"\n[host.\"http://mirror-a:5000\"]\n" +
+					"  capabilities = [\"pull\", \"resolve\"]\n" +
+					"\n[host.\"https://mirror-b\"]\n" +
+					"  capabilities = [\"pull\"]\n" +
+					"  override_path = true\n" +
+					"  ca = [\"/etc/containerd/certs.d/docker.io/mirror-1-ca-0.crt\"]\n",
+				"/etc/containerd/certs.d/docker.io/mirror-1-ca-0.crt": "MIRROR-CA",
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := tc.args.r.files(tc.args.trusted)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nfiles(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+package kindnode
+
+import (
+	"bytes"
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+)
+
+const (
+	errAddRoute    = "cannot add route to %s via %s on node %s"
+	errRemoveRoute = "cannot remove route to %s on node %s"
+)
+
+// Route is a static route installed in a node's network namespace.
+type Route struct {
+	Dest netip.Prefix
+	Via  netip.Addr
+}
+
+// String renders the route as ip route arguments.
+func (r Route) String() string {
+	return fmt.Sprintf("%s via %s", r.Dest, r.Via)
+}
+
+// routesMarker returns the marker file listing the routes installed for the
+// named owner.
+func routesMarker(owner string) string {
+	return stateDir + "/routes-" + owner
+}
+
+// HasRoutes reports whether every route is present in the node's routing
+// table. Routes do not survive a node restart, so the routing table is
+// consulted rather than a marker.
+func HasRoutes(n nodes.Node, routes []Route) bool {
+	for _, r := range routes {
+		var out bytes.Buffer
+		if err := n.Command("ip", family(r.Dest), "route", "show", "exact", r.Dest.String()).SetStdout(&out).Run(); err != nil {
+			return false
+		}
+		if !strings.Contains(out.String(), "via "+r.Via.String()+" ") {
+			return false
+		}
+	}
+	return true
+}
+
+// HasAnyRoutes reports whether routes were installed for the named owner.
+func HasAnyRoutes(n nodes.Node, owner string) bool {
+	return readMarker(n, routesMarker(owner)) != ""
+}
+
+// InstallRoutes installs the routes on behalf of the named owner, replacing
+// any route to the same destination. Routes previously installed for the
+// owner that are no longer desired are removed.
+func InstallRoutes(n nodes.Node, owner string, routes []Route) error {
+	want := map[string]bool{}
+	for _, r := range routes {
+		want[r.Dest.String()] = true
+	}
+	if err := removeRoutes(n, owner, want); err != nil {
+		return err
+	}
+
+	lines := make([]string, 0, len(routes))
+	for _, r := range routes {
+		args := []string{family(r.Dest), "route", "replace", r.Dest.String(), "via", r.Via.String()}
+		if err := n.Command("ip", args...).Run(); err != nil {
+			return errors.Wrapf(err, errAddRoute, r.Dest, r.Via, n.String())
+		}
+		lines = append(lines, r.String())
+	}
+	if err := writeOrRemove(n, routesMarker(owner), strings.Join(lines, "\n")); err != nil {
+		return errors.Wrapf(err, errWriteMarker, n.String())
+	}
+	return nil
+}
+
+// RemoveRoutes removes every route installed for the named owner.
+func RemoveRoutes(n nodes.Node, owner string) error {
+	if err := removeRoutes(n, owner, nil); err != nil {
+		return err
+	}
+	return errors.Wrapf(writeOrRemove(n, routesMarker(owner), ""), errWriteMarker, n.String())
+}
+
+// removeRoutes removes the routes installed for the owner whose destination
+// is not in keep.
+func removeRoutes(n nodes.Node, owner string, keep map[string]bool) error {
+	for _, line := range strings.Split(readMarker(n, routesMarker(owner)), "\n") {
+		dest, _, _ := strings.Cut(line, " ")
+		p, err := netip.ParsePrefix(dest)
+		if err != nil || keep[dest] {
+			continue
+		}
+		var stderr bytes.Buffer
+		err = n.Command("ip", family(p), "route", "del", dest).SetStderr(&stderr).Run()
+		if err != nil && !strings.Contains(stderr.String(), "No such process") {
+			return errors.Wrapf(err, errRemoveRoute, dest, n.String())
+		}
+	}
+	return nil
+}
+
+// family returns the ip address family flag for the prefix.
+func family(p netip.Prefix) string {
+	if p.Addr().Is4() {
+		return "-4"
+	}
+	return "-6"
+}
+
+// CanReach reports whether a TCP connection from the node to the address
+// succeeds within two seconds.
+func CanReach(n nodes.Node, addr netip.AddrPort) bool {
+	script := fmt.Sprintf("timeout 2 bash -c '</dev/tcp/%s/%d'", addr.Addr(), addr.Port())
+	return n.Command("bash", "-c", script).Run() == nil
+}
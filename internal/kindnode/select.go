@@ -0,0 +1,36 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+package kindnode
+
+import (
+	"slices"
+
+	"sigs.k8s.io/kind/pkg/cluster/constants"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+)
+
+// Selector selects nodes of a cluster by role or by name.
+type Selector struct {
+	Roles []string
+	Names []string
+}
+
+// Select returns the nodes that have one of the roles or names of the
+// selector, or every node if the selector is empty. The external load
+// balancer of a cluster runs no Kubernetes, so it is never selected.
+func Select(all []nodes.Node, sel Selector) []nodes.Node {
+	out := make([]nodes.Node, 0, len(all))
+	for _, n := range all {
+		role, err := n.Role()
+		if err != nil || role == constants.ExternalLoadBalancerNodeRoleValue {
+			continue
+		}
+		if (len(sel.Roles) == 0 && len(sel.Names) == 0) ||
+			slices.Contains(sel.Roles, role) || slices.Contains(sel.Names, n.String()) {
+			out = append(out, n)
+		}
+	}
+	return out
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+package kindnode
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+)
+
+const (
+	// tokenAccount is the provider's ServiceAccount in kube-system, and the
+	// name of its ClusterRoleBinding.
+	tokenAccount = "provider-kind"
+
+	// tokenSecret is the Secret in kube-system that holds the token of the
+	// provider's ServiceAccount.
+	tokenSecret = "provider-kind-token"
+
+	tokenManifest = `apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: ` + tokenAccount + `
+  namespace: kube-system
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: ` + tokenAccount + `
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: %s
+subjects:
+  - kind: ServiceAccount
+    name: ` + tokenAccount + `
+    namespace: kube-system
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: ` + tokenSecret + `
+  namespace: kube-system
+  annotations:
+    kubernetes.io/service-account.name: ` + tokenAccount + `
+type: kubernetes.io/service-account-token
+`
+)
+
+const (
+	errReadToken   = "cannot read ServiceAccount token from node %s"
+	errCreateToken = "cannot create ServiceAccount token from node %s"
+	errDeleteToken = "cannot delete ServiceAccount token from node %s"
+	errDecodeToken = "cannot decode ServiceAccount token"
+)
+
+// ServiceAccountToken returns the long-lived token of the provider's
+// ServiceAccount and the ClusterRole it is bound to, without changing the
+// cluster. The role is empty if the ServiceAccount is not bound, and the
+// token is empty until the token controller has populated its Secret.
+func ServiceAccountToken(all []nodes.Node) (string, string, error) {
+	cp := controlPlane(all)
+	if cp == nil {
+		return "", "", errors.New(errNoControlPlane)
+	}
+
+	var role bytes.Buffer
+	if err := kubectl(cp, &role, "get", "clusterrolebinding", tokenAccount, "--ignore-not-found",
+		"--output=jsonpath={.roleRef.name}").Run(); err != nil {
+		return "", "", errors.Wrapf(err, errReadToken, cp.String())
+	}
+	var out bytes.Buffer
+	if err := kubectl(cp, &out, "--namespace=kube-system", "get", "secret", tokenSecret, "--ignore-not-found",
+		"--output=jsonpath={.data.token}").Run(); err != nil {
+		return "", "", errors.Wrapf(err, errReadToken, cp.String())
+	}
+
+	token, err := base64.StdEncoding.DecodeString(strings.TrimSpace(out.String()))
+	if err != nil {
+		return "", "", errors.Wrap(err, errDecodeToken)
+	}
+	return string(token), strings.TrimSpace(role.String()), nil
+}
+
+// ApplyServiceAccountToken creates the provider's ServiceAccount and its
+// token Secret, and binds it to the ClusterRole. A binding to another role is
+// replaced, since the role of a binding cannot be changed.
+func ApplyServiceAccountToken(all []nodes.Node, clusterRole string) error {
+	cp := controlPlane(all)
+	if cp == nil {
+		return errors.New(errNoControlPlane)
+	}
+
+	_, current, err := ServiceAccountToken(all)
+	if err != nil {
+		return err
+	}
+	if current != "" && current != clusterRole {
+		if err := kubectl(cp, nil, "delete", "clusterrolebinding", tokenAccount, "--ignore-not-found").Run(); err != nil {
+			return errors.Wrapf(err, errCreateToken, cp.String())
+		}
+	}
+
+	manifest := fmt.Sprintf(tokenManifest, clusterRole)
+	if err := kubectl(cp, nil, "apply", "-f", "-").SetStdin(strings.NewReader(manifest)).Run(); err != nil {
+		return errors.Wrapf(err, errCreateToken, cp.String())
+	}
+	return nil
+}
+
+// DeleteServiceAccountToken deletes the provider's ServiceAccount, its token
+// Secret, and its ClusterRoleBinding, if they exist.
+func DeleteServiceAccountToken(all []nodes.Node) error {
+	cp := controlPlane(all)
+	if cp == nil {
+		return errors.New(errNoControlPlane)
+	}
+	if err := kubectl(cp, nil, "delete", "clusterrolebinding", tokenAccount, "--ignore-not-found").Run(); err != nil {
+		return errors.Wrapf(err, errDeleteToken, cp.String())
+	}
+	if err := kubectl(cp, nil, "--namespace=kube-system", "delete", "secret/"+tokenSecret,
+		"serviceaccount/"+tokenAccount, "--ignore-not-found").Run(); err != nil {
+		return errors.Wrapf(err, errDeleteToken, cp.String())
+	}
+	return nil
+}
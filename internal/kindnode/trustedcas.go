@@ -0,0 +1,92 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+package kindnode
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/cluster/nodeutils"
+)
+
+const (
+	// caDir is the directory of the node's trust store that holds the CA
+	// certificates installed by the provider.
+	caDir = "/usr/local/share/ca-certificates/provider-kind"
+
+	trustedCAsMarker = stateDir + "/trusted-cas.sha256"
+)
+
+const (
+	errWriteCA    = "cannot write CA certificate to node %s"
+	errUpdateCAs  = "cannot update CA certificates on node %s"
+	errWriteHosts = "cannot write containerd hosts.toml to node %s"
+	errClearCAs   = "cannot remove stale CA certificates from node %s"
+)
+
+// TrustedCAs is a set of PEM-encoded CA certificates to install on nodes.
+type TrustedCAs [][]byte
+
+// Hash returns a digest of the certificates that identifies the installed
+// set on a node.
+func (c TrustedCAs) Hash() string {
+	return digest(c...)
+}
+
+// Paths returns the locations of the certificates inside a node.
+func (c TrustedCAs) Paths() []string {
+	paths := make([]string, 0, len(c))
+	for i := range c {
+		paths = append(paths, path.Join(caDir, fmt.Sprintf("ca-%d.crt", i)))
+	}
+	return paths
+}
+
+// HasTrustedCAs reports whether the node already has exactly the supplied
+// certificates installed.
+func HasTrustedCAs(n nodes.Node, cas TrustedCAs) bool {
+	installed := readMarker(n, trustedCAsMarker)
+	if len(cas) == 0 && installed == "" {
+		return true
+	}
+	return installed == cas.Hash()
+}
+
+// InstallTrustedCAs installs the supplied certificates into the node's trust
+// store and configures containerd to trust them for every registry host that
+// has no host-specific configuration. Certificates previously installed by
+// the provider that are no longer desired are removed.
+func InstallTrustedCAs(n nodes.Node, cas TrustedCAs) error {
+	if err := n.Command("rm", "-rf", caDir).Run(); err != nil {
+		return errors.Wrapf(err, errClearCAs, n.String())
+	}
+	paths := cas.Paths()
+	for i, pem := range cas {
+		if err := nodeutils.WriteFile(n, paths[i], string(pem)); err != nil {
+			return errors.Wrapf(err, errWriteCA, n.String())
+		}
+	}
+	if err := n.Command("update-ca-certificates").Run(); err != nil {
+		return errors.Wrapf(err, errUpdateCAs, n.String())
+	}
+	if err := writeOrRemove(n, path.Join(certsDir, "_default", "hosts.toml"), defaultHostsTOML(paths)); err != nil {
+		return errors.Wrapf(err, errWriteHosts, n.String())
+	}
+	if err := nodeutils.WriteFile(n, trustedCAsMarker, cas.Hash()); err != nil {
+		return errors.Wrapf(err, errWriteMarker, n.String())
+	}
+	return nil
+}
+
+// defaultHostsTOML renders the containerd hosts.toml used for registry hosts
+// without their own configuration.
+func defaultHostsTOML(caPaths []string) string {
+	if len(caPaths) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("ca = %s\n", tomlStrings(caPaths))
+}
@@ -0,0 +1,200 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+// Package kubeconfigsecrets writes the kubeconfig of a KIND cluster to
+// Secrets in the formats other tools read, next to the Crossplane connection
+// secret. The Secrets are controlled by the cluster's managed resource, so
+// they are garbage collected with it.
+package kubeconfigsecrets
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
+)
+
+// LabelFormat is set on the Secrets to their format.
+const LabelFormat = "kind.crossplane.io/kubeconfig-format"
+
+// Formats of kubeconfig Secrets.
+const (
+	// FormatFlux is read by Flux kubeConfig.secretRef, which defaults to the
+	// value key.
+	FormatFlux = "flux"
+
+	// FormatClusterAPI is the Cluster API <cluster>-kubeconfig Secret.
+	FormatClusterAPI = "clusterAPI"
+)
+
+const (
+	// labelClusterAPIClusterName is the Cluster API cluster label.
+	labelClusterAPIClusterName = "cluster.x-k8s.io/cluster-name"
+
+	// secretTypeClusterAPI is the type of Cluster API Secrets.
+	secretTypeClusterAPI corev1.SecretType = "cluster.x-k8s.io/secret"
+
+	// keyValue holds the kubeconfig in both formats.
+	keyValue = "value"
+)
+
+const (
+	errListSecrets  = "cannot list kubeconfig Secrets"
+	errGetSecret    = "cannot get kubeconfig Secret %s/%s"
+	errWriteSecret  = "cannot write kubeconfig Secret %s/%s"
+	errDeleteSecret = "cannot delete kubeconfig Secret %s/%s"
+	errSecretExists = "Secret %s/%s exists and does not belong to this Cluster"
+)
+
+// Secret is a kubeconfig Secret in one of the formats.
+type Secret struct {
+	Format    string
+	Namespace string
+	Name      string
+
+	// ClusterName labels Cluster API Secrets.
+	ClusterName string
+}
+
+// New returns the Secret of the format for a KIND cluster. The name, if not
+// nil, overrides the Secret name of a flux Secret, or the Cluster API
+// cluster name of a clusterAPI Secret.
+func New(format, namespace string, name *string, cluster string) Secret {
+	s := Secret{Format: format, Namespace: namespace}
+	switch format {
+	case FormatClusterAPI:
+		s.ClusterName = cluster
+		if name != nil {
+			s.ClusterName = *name
+		}
+		s.Name = s.ClusterName + "-kubeconfig"
+	default:
+		s.Name = cluster + "-flux-kubeconfig"
+		if name != nil {
+			s.Name = *name
+		}
+	}
+	return s
+}
+
+// UpToDate reports whether every Secret holds the kubeconfig and no Secrets
+// of removed formats remain.
+func UpToDate(ctx context.Context, kube client.Client, owner metav1.Object, want []Secret, kubeconfig []byte) (bool, error) {
+	owned, err := ownedSecrets(ctx, kube, owner)
+	if err != nil {
+		return false, err
+	}
+	if len(owned) != len(want) {
+		return false, nil
+	}
+	for _, w := range want {
+		s, ok := owned[types.NamespacedName{Namespace: w.Namespace, Name: w.Name}]
+		if !ok || s.Type != w.secretType() || !bytes.Equal(s.Data[keyValue], kubeconfig) {
+			return false, nil
+		}
+		for k, v := range w.labels() {
+			if s.GetLabels()[k] != v {
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
+
+// Sync writes the kubeconfig to every Secret, and deletes the Secrets of
+// removed formats. The Secrets are controlled by the owner, of the supplied
+// kind.
+func Sync(ctx context.Context, kube client.Client, owner metav1.Object, gvk schema.GroupVersionKind, want []Secret, kubeconfig []byte) error {
+	owned, err := ownedSecrets(ctx, kube, owner)
+	if err != nil {
+		return err
+	}
+	for _, w := range want {
+		if err := write(ctx, kube, owner, gvk, w, kubeconfig); err != nil {
+			return err
+		}
+		delete(owned, types.NamespacedName{Namespace: w.Namespace, Name: w.Name})
+	}
+	for nn, s := range owned {
+		if err := client.IgnoreNotFound(kube.Delete(ctx, s)); err != nil {
+			return errors.Wrapf(err, errDeleteSecret, nn.Namespace, nn.Name)
+		}
+	}
+	return nil
+}
+
+// ownedSecrets returns the kubeconfig Secrets controlled by the owner. A
+// cluster-scoped owner's Secrets may be in any namespace.
+func ownedSecrets(ctx context.Context, kube client.Client, owner metav1.Object) (map[types.NamespacedName]*corev1.Secret, error) {
+	l := &corev1.SecretList{}
+	if err := kube.List(ctx, l, client.InNamespace(owner.GetNamespace()), client.HasLabels{LabelFormat}); err != nil {
+		return nil, errors.Wrap(err, errListSecrets)
+	}
+	owned := map[types.NamespacedName]*corev1.Secret{}
+	for i := range l.Items {
+		if metav1.IsControlledBy(&l.Items[i], owner) {
+			owned[types.NamespacedName{Namespace: l.Items[i].GetNamespace(), Name: l.Items[i].GetName()}] = &l.Items[i]
+		}
+	}
+	return owned, nil
+}
+
+// write creates or updates a kubeconfig Secret.
+func write(ctx context.Context, kube client.Client, owner metav1.Object, gvk schema.GroupVersionKind, w Secret, kubeconfig []byte) error {
+	s := &corev1.Secret{}
+	err := kube.Get(ctx, types.NamespacedName{Namespace: w.Namespace, Name: w.Name}, s)
+	switch {
+	case kerrors.IsNotFound(err):
+		s = &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: w.Namespace, Name: w.Name}, Type: w.secretType()}
+	case err != nil:
+		return errors.Wrapf(err, errGetSecret, w.Namespace, w.Name)
+	case !metav1.IsControlledBy(s, owner):
+		return errors.Errorf(errSecretExists, w.Namespace, w.Name)
+	case s.Type != w.secretType():
+		// The type of a Secret cannot change, so a Secret that switched
+		// format is recreated.
+		if err := client.IgnoreNotFound(kube.Delete(ctx, s)); err != nil {
+			return errors.Wrapf(err, errDeleteSecret, w.Namespace, w.Name)
+		}
+		s = &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: w.Namespace, Name: w.Name}, Type: w.secretType()}
+	}
+
+	meta.AddLabels(s, w.labels())
+	if err := meta.AddControllerReference(s, meta.AsController(meta.TypedReferenceTo(owner, gvk))); err != nil {
+		return errors.Wrapf(err, errWriteSecret, w.Namespace, w.Name)
+	}
+	s.Data = map[string][]byte{keyValue: kubeconfig}
+
+	if s.GetResourceVersion() == "" {
+		err = kube.Create(ctx, s)
+	} else {
+		err = kube.Update(ctx, s)
+	}
+	return errors.Wrapf(err, errWriteSecret, w.Namespace, w.Name)
+}
+
+// labels returns the labels of the Secret.
+func (w Secret) labels() map[string]string {
+	l := map[string]string{LabelFormat: w.Format}
+	if w.Format == FormatClusterAPI {
+		l[labelClusterAPIClusterName] = w.ClusterName
+	}
+	return l
+}
+
+// secretType returns the type of the Secret.
+func (w Secret) secretType() corev1.SecretType {
+	if w.Format == FormatClusterAPI {
+		return secretTypeClusterAPI
+	}
+	return corev1.SecretTypeOpaque
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+// Package noderesources observes and applies the resource limits of KIND
+// node containers, for the cluster-scoped and the namespaced Cluster alike.
+package noderesources
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+
+	clusterv1alpha1 "github.com/humoflife/provider-kind/apis/cluster/v1alpha1"
+	"github.com/humoflife/provider-kind/internal/docker"
+)
+
+const (
+	errInspectNode = "cannot inspect node %s"
+)
+
+// Observe returns the resource limits in effect on the node, and
+// reports whether they include the desired ones.
+func Observe(ctx context.Context, node string, want docker.Resources) (*clusterv1alpha1.NodeResources, bool) {
+	c, err := docker.InspectContainer(ctx, node)
+	if err != nil || c == nil {
+		return nil, want.Empty()
+	}
+	have := c.Resources()
+	if have.Empty() {
+		return nil, have.Includes(want)
+	}
+	obs := &clusterv1alpha1.NodeResources{}
+	if have.NanoCPUs != 0 {
+		obs.CPUs = resource.NewMilliQuantity(have.NanoCPUs/1e6, resource.DecimalSI)
+	}
+	if have.Memory != 0 {
+		obs.Memory = resource.NewQuantity(have.Memory, resource.BinarySI)
+	}
+	if have.PidsLimit != 0 {
+		obs.PidsLimit = &have.PidsLimit
+	}
+	return obs, have.Includes(want)
+}
+
+// Limit applies the resource limits to the nodes that have any and
+// do not have them in effect already.
+func Limit(ctx context.Context, all []nodes.Node, want map[string]docker.Resources) error {
+	for _, n := range all {
+		w, ok := want[n.String()]
+		if !ok {
+			continue
+		}
+		c, err := docker.InspectContainer(ctx, n.String())
+		if err != nil {
+			return errors.Wrapf(err, errInspectNode, n.String())
+		}
+		if c != nil && c.Resources().Includes(w) {
+			continue
+		}
+		if err := docker.UpdateResources(ctx, n.String(), w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+// Package policy checks what a cluster requests of the Docker host against
+// the policy of its ProviderConfig. KIND nodes are privileged containers, so
+// host mounts and port mappings reach the host itself, and the Docker network
+// decides what else they reach.
+package policy
+
+import (
+	"fmt"
+	"net/netip"
+	"path"
+	"slices"
+	"strings"
+
+	"sigs.k8s.io/kind/pkg/apis/config/defaults"
+
+	clusterv1alpha1 "github.com/humoflife/provider-kind/apis/cluster/v1alpha1"
+	"github.com/humoflife/provider-kind/apis/v1beta1"
+	"github.com/humoflife/provider-kind/internal/kindnetwork"
+)
+
+const (
+	// defaultListenAddress is the address KIND maps ports on if none is
+	// set.
+	defaultListenAddress = "0.0.0.0"
+
+	// defaultAPIServerAddress is the address KIND publishes the API server
+	// on if none is set.
+	defaultAPIServerAddress = "127.0.0.1"
+
+	// defaultRegistry is the registry of images that name none.
+	defaultRegistry = "docker.io"
+)
+
+// Check returns why the cluster parameters violate the policy, or an empty
+// string if they do not. The network is the Docker network the nodes are
+// created on, or an empty string for the default network.
+func Check(p *v1beta1.Policy, params clusterv1alpha1.ClusterParameters, network string) string {
+	if p == nil {
+		return ""
+	}
+	var violations []string
+
+	if len(params.Nodes) == 0 && !registryAllowed(p, defaults.Image) {
+		violations = append(violations, fmt.Sprintf("node image %q is not from an allowed registry", defaults.Image))
+	}
+	for i, n := range params.Nodes {
+		image := defaults.Image
+		if n.Image != nil {
+			image = *n.Image
+		}
+		if !registryAllowed(p, image) {
+			violations = append(violations, fmt.Sprintf("node %d: image %q is not from an allowed registry", i, image))
+		}
+		for _, m := range n.ExtraMounts {
+			if m.HostPath != "" && !hostPathAllowed(p, m.HostPath) {
+				violations = append(violations, fmt.Sprintf("node %d: host path %q is not allowed", i, m.HostPath))
+			}
+		}
+		for _, pm := range n.ExtraPortMappings {
+			if !portAllowed(p, pm.HostPort) {
+				violations = append(violations, fmt.Sprintf("node %d: host port %d is not allowed", i, pm.HostPort))
+			}
+			addr := defaultListenAddress
+			if pm.ListenAddress != nil {
+				addr = *pm.ListenAddress
+			}
+			if !addressAllowed(p, addr) {
+				violations = append(violations, fmt.Sprintf("node %d: listen address %q is not allowed", i, addr))
+			}
+		}
+	}
+
+	if net := params.Networking; net != nil {
+		if net.APIServerPort != nil && !portAllowed(p, *net.APIServerPort) {
+			violations = append(violations, fmt.Sprintf("API server port %d is not allowed", *net.APIServerPort))
+		}
+	}
+	addr := defaultAPIServerAddress
+	if params.Networking != nil && params.Networking.APIServerAddress != nil {
+		addr = *params.Networking.APIServerAddress
+	}
+	if !addressAllowed(p, addr) {
+		violations = append(violations, fmt.Sprintf("API server address %q is not allowed", addr))
+	}
+
+	if network == "" {
+		network = kindnetwork.Default
+	}
+	if len(p.AllowedNetworks) > 0 && !slices.Contains(p.AllowedNetworks, network) {
+		violations = append(violations, fmt.Sprintf("Docker network %q is not allowed", network))
+	}
+
+	return strings.Join(violations, "; ")
+}
+
+// Namespaced returns the policy that applies to namespaced Clusters. Their
+// authors are tenants, so unless the policy lists the networks they may use,
+// their nodes may only be created on the default network.
+func Namespaced(p *v1beta1.Policy) *v1beta1.Policy {
+	if p != nil && len(p.AllowedNetworks) > 0 {
+		return p
+	}
+	out := &v1beta1.Policy{}
+	if p != nil {
+		out = p.DeepCopy()
+	}
+	out.AllowedNetworks = []string{kindnetwork.Default}
+	return out
+}
+
+// hostPathAllowed reports whether the host path is, or is below, one of the
+// allowed prefixes.
+func hostPathAllowed(p *v1beta1.Policy, hostPath string) bool {
+	if len(p.AllowedHostPathPrefixes) == 0 {
+		return true
+	}
+	clean := path.Clean(hostPath)
+	for _, prefix := range p.AllowedHostPathPrefixes {
+		prefix = path.Clean(prefix)
+		if clean == prefix || strings.HasPrefix(clean, strings.TrimSuffix(prefix, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// portAllowed reports whether the host port is in one of the allowed
+// ranges. Port 0 lets Docker pick a free port, which is always allowed.
+func portAllowed(p *v1beta1.Policy, port int32) bool {
+	if len(p.AllowedHostPorts) == 0 || port == 0 {
+		return true
+	}
+	for _, r := range p.AllowedHostPorts {
+		if port >= r.Min && port <= r.Max {
+			return true
+		}
+	}
+	return false
+}
+
+// addressAllowed reports whether the listen address is one of the allowed
+// addresses.
+func addressAllowed(p *v1beta1.Policy, addr string) bool {
+	if len(p.AllowedListenAddresses) == 0 {
+		return true
+	}
+	want, err := netip.ParseAddr(addr)
+	if err != nil {
+		return slices.Contains(p.AllowedListenAddresses, addr)
+	}
+	for _, a := range p.AllowedListenAddresses {
+		if allowed, err := netip.ParseAddr(a); err == nil && allowed == want {
+			return true
+		}
+	}
+	return false
+}
+
+// registryAllowed reports whether the image is from one of the allowed
+// registries.
+func registryAllowed(p *v1beta1.Policy, image string) bool {
+	if len(p.AllowedImageRegistries) == 0 {
+		return true
+	}
+	return slices.Contains(p.AllowedImageRegistries, registry(image))
+}
+
+// registry returns the registry of an image reference. Like Docker, the
+// first path component names the registry only if it looks like a host.
+func registry(image string) string {
+	first, _, found := strings.Cut(image, "/")
+	if !found || (!strings.ContainsAny(first, ".:") && first != "localhost") {
+		return defaultRegistry
+	}
+	return first
+}
@@ -0,0 +1,229 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+package policy
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/kind/pkg/apis/config/defaults"
+
+	clusterv1alpha1 "github.com/humoflife/provider-kind/apis/cluster/v1alpha1"
+	"github.com/humoflife/provider-kind/apis/v1beta1"
+	"github.com/humoflife/provider-kind/internal/kindnetwork"
+)
+
+func TestCheck(t *testing.T) {
+	type args struct {
+		p       *v1beta1.Policy
+		params  clusterv1alpha1.ClusterParameters
+		network string
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   string
+	}{
+		"NoPolicy": {
+			reason: "Without a policy anything should be allowed.",
+			args: args{
+				params: clusterv1alpha1.ClusterParameters{Nodes: []clusterv1alpha1.Node{{
+					ExtraMounts: []clusterv1alpha1.Mount{{HostPath: "/"}},
+				}}},
+				network: "host",
+			},
+		},
+		"EmptyPolicy": {
+			reason: "Empty lists should allow anything.",
+			args: args{
+				p: &v1beta1.Policy{},
+				params: clusterv1alpha1.ClusterParameters{Nodes: []clusterv1alpha1.Node{{
+					Image:             ptr.To("registry.example.com/kindest/node:v1.31.0"),
+					ExtraMounts:       []clusterv1alpha1.Mount{{HostPath: "/var/run/docker.sock"}},
+					ExtraPortMappings: []clusterv1alpha1.PortMapping{{ContainerPort: 80, HostPort: 80}},
+				}}},
+				network: "host",
+			},
+		},
+		"HostPathBelowPrefix": {
+			reason: "Host paths that are, or are below, an allowed prefix should be allowed.",
+			args: args{
+				p: &v1beta1.Policy{AllowedHostPathPrefixes: []string{"/srv/kind/"}},
+				params: clusterv1alpha1.ClusterParameters{Nodes: []clusterv1alpha1.Node{{
+					ExtraMounts: []clusterv1alpha1.Mount{{HostPath: "/srv/kind"}, {HostPath: "/srv/kind/data"}},
+				}}},
+			},
+		},
+		"HostPathOutsidePrefix": {
+			reason: "Host paths that only share a prefix string, or escape it, should be violations.",
+			args: args{
+				p: &v1beta1.Policy{AllowedHostPathPrefixes: []string{"/srv/kind"}},
+				params: clusterv1alpha1.ClusterParameters{Nodes: []clusterv1alpha1.Node{{
+					ExtraMounts: []clusterv1alpha1.Mount{{HostPath: "/srv/kindling"}, {HostPath: "/srv/kind/../.."}},
+				}}},
+			},
+			want: `node 0: host path "/srv/kindling" is not allowed; node 0: host path "/srv/kind/../.." is not allowed`,
+		},
+		"HostPorts": {
+			reason: "Host ports outside the allowed ranges should be violations, and port 0 should always be allowed.",
+			args: args{
+				p: &v1beta1.Policy{AllowedHostPorts: []v1beta1.PortRange{{Min: 30000, Max: 32767}}},
+				params: clusterv1alpha1.ClusterParameters{
+					Nodes: []clusterv1alpha1.Node{{
+						ExtraPortMappings: []clusterv1alpha1.PortMapping{
+							{ContainerPort: 80, HostPort: 30080},
+							{ContainerPort: 443, HostPort: 443},
+							{ContainerPort: 8080, HostPort: 0},
+						},
+					}},
+					Networking: &clusterv1alpha1.Networking{APIServerPort: ptr.To[int32](6443)},
+				},
+			},
+			want: "node 0: host port 443 is not allowed; API server port 6443 is not allowed",
+		},
+		"DefaultListenAddresses": {
+			reason: "Port mappings should default to 0.0.0.0 and the API server to 127.0.0.1.",
+			args: args{
+				p: &v1beta1.Policy{AllowedListenAddresses: []string{"127.0.0.1"}},
+				params: clusterv1alpha1.ClusterParameters{Nodes: []clusterv1alpha1.Node{{
+					ExtraPortMappings: []clusterv1alpha1.PortMapping{{ContainerPort: 80, HostPort: 30080}},
+				}}},
+			},
+			want: `node 0: listen address "0.0.0.0" is not allowed`,
+		},
+		"EquivalentListenAddress": {
+			reason: "Listen addresses should be compared as addresses, not strings.",
+			args: args{
+				p: &v1beta1.Policy{AllowedListenAddresses: []string{"::1"}},
+				params: clusterv1alpha1.ClusterParameters{
+					Networking: &clusterv1alpha1.Networking{APIServerAddress: ptr.To("0:0:0:0:0:0:0:1")},
+				},
+			},
+		},
+		"DefaultImage": {
+			reason: "The default node image should be from docker.io.",
+			args: args{
+				p: &v1beta1.Policy{AllowedImageRegistries: []string{"registry.example.com"}},
+			},
+			want: fmt.Sprintf("node image %q is not from an allowed registry", defaults.Image),
+		},
+		"ImageRegistries": {
+			reason: "Images should only be allowed from the allowed registries, and those that name none are from docker.io.",
+			args: args{
+				p: &v1beta1.Policy{AllowedImageRegistries: []string{"docker.io", "localhost:5000"}},
+				params: clusterv1alpha1.ClusterParameters{Nodes: []clusterv1alpha1.Node{
+					{Image: ptr.To("kindest/node:v1.31.0")},
+					{Image: ptr.To("localhost:5000/node:v1.31.0")},
+					{Image: ptr.To("ghcr.io/example/node:v1.31.0")},
+				}},
+			},
+			want: `node 2: image "ghcr.io/example/node:v1.31.0" is not from an allowed registry`,
+		},
+		"DefaultNetwork": {
+			reason: "Nodes should be created on the default network if none is set.",
+			args: args{
+				p: &v1beta1.Policy{AllowedNetworks: []string{kindnetwork.Default}},
+			},
+		},
+		"Network": {
+			reason: "Docker networks that are not allowed should be violations.",
+			args: args{
+				p:       &v1beta1.Policy{AllowedNetworks: []string{kindnetwork.Default, "tenant-a"}},
+				network: "host",
+			},
+			want: `Docker network "host" is not allowed`,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := Check(tc.args.p, tc.args.params, tc.args.network)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nCheck(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		image  string
+		want   string
+	}{
+		"Official": {
+			reason: "Official images should be from docker.io.",
+			image:  "ubuntu:24.04",
+			want:   "docker.io",
+		},
+		"User": {
+			reason: "A first path component that is not a host should not name a registry.",
+			image:  "kindest/node:v1.31.0",
+			want:   "docker.io",
+		},
+		"Host": {
+			reason: "A first path component with a dot should name the registry.",
+			image:  "registry.example.com/kindest/node:v1.31.0",
+			want:   "registry.example.com",
+		},
+		"HostPort": {
+			reason: "A first path component with a port should name the registry.",
+			image:  "mirror:5000/kindest/node:v1.31.0",
+			want:   "mirror:5000",
+		},
+		"Localhost": {
+			reason: "localhost should name the registry.",
+			image:  "localhost/node:v1.31.0",
+			want:   "localhost",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := registry(tc.image)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nregistry(%q): -want, +got:\n%s", tc.reason, tc.image, diff)
+			}
+		})
+	}
+}
+
+func TestNamespaced(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		p      *v1beta1.Policy
+		want   *v1beta1.Policy
+	}{
+		"NoPolicy": {
+			reason: "Namespaced Clusters without a policy should only use the default network.",
+			want:   &v1beta1.Policy{AllowedNetworks: []string{kindnetwork.Default}},
+		},
+		"NoNetworks": {
+			reason: "A policy that lists no networks should only allow the default network, and keep its other restrictions.",
+			p:      &v1beta1.Policy{AllowedHostPathPrefixes: []string{"/srv/kind"}},
+			want: &v1beta1.Policy{
+				AllowedHostPathPrefixes: []string{"/srv/kind"},
+				AllowedNetworks:         []string{kindnetwork.Default},
+			},
+		},
+		"Networks": {
+			reason: "A policy that lists networks should apply as is.",
+			p:      &v1beta1.Policy{AllowedNetworks: []string{"tenant-a"}},
+			want:   &v1beta1.Policy{AllowedNetworks: []string{"tenant-a"}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := Namespaced(tc.p)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nNamespaced(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+// Package providerconfigs publishes ProviderConfigs of other Crossplane
+// providers that read the kubeconfig of a KIND cluster from its connection
+// secret. They are handled as unstructured objects, so the providers need
+// not be installed until they are used.
+package providerconfigs
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/humoflife/provider-kind/internal/apiservice"
+)
+
+// Kinds of ProviderConfig, cluster-scoped and namespaced.
+var (
+	Kubernetes           = schema.GroupVersionKind{Group: "kubernetes.crossplane.io", Version: "v1alpha1", Kind: "ProviderConfig"}
+	KubernetesNamespaced = schema.GroupVersionKind{Group: "kubernetes.m.crossplane.io", Version: "v1alpha1", Kind: "ProviderConfig"}
+	Helm                 = schema.GroupVersionKind{Group: "helm.crossplane.io", Version: "v1beta1", Kind: "ProviderConfig"}
+	HelmNamespaced       = schema.GroupVersionKind{Group: "helm.m.crossplane.io", Version: "v1beta1", Kind: "ProviderConfig"}
+)
+
+const (
+	errGet      = "cannot get %s %q"
+	errApply    = "cannot apply %s %q"
+	errDelete   = "cannot delete %s %q"
+	errConflict = "%s %q exists and was not published for this cluster"
+)
+
+// ProviderConfig is a ProviderConfig whose credentials are a kubeconfig in
+// a Secret.
+type ProviderConfig struct {
+	schema.GroupVersionKind
+
+	// Namespace of a namespaced ProviderConfig. Empty for cluster-scoped
+	// ones.
+	Namespace string
+	Name      string
+
+	SecretNamespace string
+	SecretName      string
+	SecretKey       string
+
+	// Publish is false for a ProviderConfig that should not exist.
+	Publish bool
+}
+
+// UpToDate reports whether the ProviderConfig exists and reads the Secret,
+// or does not exist if it should not be published.
+func (pc ProviderConfig) UpToDate(ctx context.Context, kube client.Client, cluster string) (bool, error) {
+	u, err := pc.get(ctx, kube)
+	if err != nil {
+		return false, err
+	}
+	if !pc.Publish {
+		return u == nil || u.GetLabels()[apiservice.LabelCluster] != cluster, nil
+	}
+	if u == nil || u.GetLabels()[apiservice.LabelCluster] != cluster {
+		return false, nil
+	}
+	source, _, _ := unstructured.NestedString(u.Object, "spec", "credentials", "source")
+	name, _, _ := unstructured.NestedString(u.Object, "spec", "credentials", "secretRef", "name")
+	key, _, _ := unstructured.NestedString(u.Object, "spec", "credentials", "secretRef", "key")
+	return source == "Secret" && name == pc.SecretName && key == pc.SecretKey, nil
+}
+
+// Sync creates or updates the ProviderConfig, or deletes it if it should
+// not be published.
+func (pc ProviderConfig) Sync(ctx context.Context, kube client.Client, cluster string) error {
+	if !pc.Publish {
+		return pc.Delete(ctx, kube, cluster)
+	}
+	u, err := pc.get(ctx, kube)
+	if err != nil {
+		return err
+	}
+	if u != nil && u.GetLabels()[apiservice.LabelCluster] != cluster {
+		return errors.Errorf(errConflict, pc.Kind, pc.Name)
+	}
+	if u == nil {
+		u = &unstructured.Unstructured{}
+		u.SetGroupVersionKind(pc.GroupVersionKind)
+		u.SetNamespace(pc.Namespace)
+		u.SetName(pc.Name)
+	}
+	labels := u.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[apiservice.LabelCluster] = cluster
+	u.SetLabels(labels)
+	credentials := map[string]any{
+		"source": "Secret",
+		"secretRef": map[string]any{
+			"namespace": pc.SecretNamespace,
+			"name":      pc.SecretName,
+			"key":       pc.SecretKey,
+		},
+	}
+	if err := unstructured.SetNestedMap(u.Object, credentials, "spec", "credentials"); err != nil {
+		return errors.Wrapf(err, errApply, pc.Kind, pc.Name)
+	}
+
+	if u.GetResourceVersion() == "" {
+		err = kube.Create(ctx, u)
+	} else {
+		err = kube.Update(ctx, u)
+	}
+	return errors.Wrapf(err, errApply, pc.Kind, pc.Name)
+}
+
+// Delete deletes the ProviderConfig if it was published for the cluster.
+// A ProviderConfig whose provider is not installed does not exist.
+func (pc ProviderConfig) Delete(ctx context.Context, kube client.Client, cluster string) error {
+	u, err := pc.get(ctx, kube)
+	if err != nil {
+		return err
+	}
+	if u == nil || u.GetLabels()[apiservice.LabelCluster] != cluster {
+		return nil
+	}
+	return errors.Wrapf(client.IgnoreNotFound(kube.Delete(ctx, u)), errDelete, pc.Kind, pc.Name)
+}
+
+// get returns the ProviderConfig, or nil if it or its kind does not exist.
+func (pc ProviderConfig) get(ctx context.Context, kube client.Client) (*unstructured.Unstructured, error) {
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(pc.GroupVersionKind)
+	err := kube.Get(ctx, types.NamespacedName{Namespace: pc.Namespace, Name: pc.Name}, u)
+	if kerrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+		return nil, nil
+	}
+	return u, errors.Wrapf(err, errGet, pc.Kind, pc.Name)
+}
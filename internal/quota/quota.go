@@ -0,0 +1,218 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+// Package quota counts the KIND clusters and nodes of a ProviderConfig and
+// checks new clusters against its quotas.
+package quota
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
+
+	clusterv1alpha1 "github.com/humoflife/provider-kind/apis/cluster/v1alpha1"
+	namespacedclusterv1alpha1 "github.com/humoflife/provider-kind/apis/namespacedcluster/v1alpha1"
+	"github.com/humoflife/provider-kind/apis/v1beta1"
+)
+
+const (
+	errListClusters           = "cannot list Clusters"
+	errListNamespacedClusters = "cannot list namespaced Clusters"
+)
+
+// roleControlPlane is the KIND control plane node role.
+const roleControlPlane = "control-plane"
+
+// DefaultProviderConfig is the ProviderConfig of clusters that reference
+// none.
+const DefaultProviderConfig = "default"
+
+// reservation is the usage of a cluster that was admitted but not yet
+// counted as created.
+type reservation struct {
+	namespace string
+	nodes     int32
+}
+
+var (
+	mu sync.Mutex
+
+	// reserved holds the reservations of each ProviderConfig, by cluster
+	// UID.
+	reserved = map[string]map[types.UID]reservation{}
+)
+
+// Usage is what the clusters of a ProviderConfig use.
+type Usage struct {
+	Clusters int32
+	Nodes    int32
+
+	// Namespaces counts the namespaced clusters in each namespace.
+	Namespaces map[string]int32
+}
+
+// Count returns the usage of the clusters of the named ProviderConfig. Only
+// clusters that were created, or observed to exist, are counted, so clusters
+// that are rejected do not use up the quotas. The cluster with the supplied
+// UID is not counted, so that a cluster can be checked against the others.
+func Count(ctx context.Context, kube client.Reader, providerConfig string, except types.UID) (Usage, error) {
+	u, _, err := count(ctx, kube, providerConfig, except)
+	return u, err
+}
+
+// count returns the usage of the clusters of the named ProviderConfig, and
+// whether each of its clusters, by UID, was counted.
+func count(ctx context.Context, kube client.Reader, providerConfig string, except types.UID) (Usage, map[types.UID]bool, error) {
+	u := Usage{Namespaces: map[string]int32{}}
+	seen := map[types.UID]bool{}
+
+	cl := &clusterv1alpha1.ClusterList{}
+	if err := kube.List(ctx, cl); err != nil {
+		return Usage{}, nil, errors.Wrap(err, errListClusters)
+	}
+	for i := range cl.Items {
+		c := &cl.Items[i]
+		name := DefaultProviderConfig
+		if ref := c.GetProviderConfigReference(); ref != nil {
+			name = ref.Name
+		}
+		if name != providerConfig {
+			continue
+		}
+		seen[c.GetUID()] = created(c, c.Status.AtProvider)
+		if c.GetUID() == except || !seen[c.GetUID()] {
+			continue
+		}
+		n, _ := nodes(c.Spec.ForProvider)
+		u.Clusters++
+		u.Nodes += n
+	}
+
+	nl := &namespacedclusterv1alpha1.ClusterList{}
+	if err := kube.List(ctx, nl); err != nil {
+		return Usage{}, nil, errors.Wrap(err, errListNamespacedClusters)
+	}
+	for i := range nl.Items {
+		c := &nl.Items[i]
+		name := DefaultProviderConfig
+		if ref := c.Spec.ProviderConfigReference; ref != nil {
+			name = ref.Name
+		}
+		if name != providerConfig {
+			continue
+		}
+		seen[c.GetUID()] = created(c, c.Status.AtProvider)
+		if c.GetUID() == except || !seen[c.GetUID()] {
+			continue
+		}
+		n, _ := nodes(c.Spec.ForProvider)
+		u.Clusters++
+		u.Nodes += n
+		u.Namespaces[c.GetNamespace()]++
+	}
+
+	return u, seen, nil
+}
+
+// Admit checks a cluster of the supplied parameters in the supplied
+// namespace, which is empty for cluster-scoped clusters, against the quotas
+// of the named ProviderConfig. It returns why the cluster would exceed them,
+// or an empty string if it would not, in which case the cluster's usage is
+// reserved until the cluster is counted as created, is deleted, or Release is
+// called. Admissions are serialized, and count the reservations of other
+// clusters, so that clusters created concurrently cannot together exceed the
+// quotas.
+func Admit(ctx context.Context, kube client.Reader, q *v1beta1.Quotas, providerConfig string, uid types.UID, namespace string, p clusterv1alpha1.ClusterParameters) (string, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	u, seen, err := count(ctx, kube, providerConfig, uid)
+	if err != nil {
+		return "", err
+	}
+	for id, r := range reserved[providerConfig] {
+		counted, exists := seen[id]
+		switch {
+		case !exists || counted:
+			delete(reserved[providerConfig], id)
+		case id != uid:
+			u.Clusters++
+			u.Nodes += r.nodes
+			if r.namespace != "" {
+				u.Namespaces[r.namespace]++
+			}
+		}
+	}
+
+	if exceeded := Check(q, u, namespace, p); exceeded != "" {
+		delete(reserved[providerConfig], uid)
+		return exceeded, nil
+	}
+	if reserved[providerConfig] == nil {
+		reserved[providerConfig] = map[types.UID]reservation{}
+	}
+	n, _ := nodes(p)
+	reserved[providerConfig][uid] = reservation{namespace: namespace, nodes: n}
+	return "", nil
+}
+
+// Release drops the reservation of the cluster with the supplied UID, for
+// example because creating it failed.
+func Release(providerConfig string, uid types.UID) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(reserved[providerConfig], uid)
+}
+
+// Check returns why creating a cluster of the supplied parameters in the
+// supplied namespace, which is empty for cluster-scoped clusters, would
+// exceed the quotas, or an empty string if it would not.
+func Check(q *v1beta1.Quotas, u Usage, namespace string, p clusterv1alpha1.ClusterParameters) string {
+	if q == nil {
+		return ""
+	}
+	n, cp := nodes(p)
+	var exceeded []string
+	if q.MaxClusters != nil && u.Clusters+1 > *q.MaxClusters {
+		exceeded = append(exceeded, fmt.Sprintf("%d of %d clusters are in use", u.Clusters, *q.MaxClusters))
+	}
+	if q.MaxNodes != nil && u.Nodes+n > *q.MaxNodes {
+		exceeded = append(exceeded, fmt.Sprintf("%d of %d nodes are in use and the cluster has %d", u.Nodes, *q.MaxNodes, n))
+	}
+	if q.MaxControlPlaneNodes != nil && cp > *q.MaxControlPlaneNodes {
+		exceeded = append(exceeded, fmt.Sprintf("the cluster has %d control plane nodes, more than the maximum of %d", cp, *q.MaxControlPlaneNodes))
+	}
+	if q.MaxClustersPerNamespace != nil && namespace != "" && u.Namespaces[namespace]+1 > *q.MaxClustersPerNamespace {
+		exceeded = append(exceeded, fmt.Sprintf("%d of %d clusters are in use in namespace %s", u.Namespaces[namespace], *q.MaxClustersPerNamespace, namespace))
+	}
+	return strings.Join(exceeded, "; ")
+}
+
+// created reports whether the cluster was created or observed to exist.
+func created(o client.Object, obs clusterv1alpha1.ClusterObservation) bool {
+	return !meta.GetExternalCreateSucceeded(o).IsZero() || len(obs.Nodes) > 0
+}
+
+// nodes returns the number of nodes, and of control plane nodes, of a
+// cluster of the supplied parameters. KIND creates a single control plane
+// node if none are listed.
+func nodes(p clusterv1alpha1.ClusterParameters) (all, controlPlanes int32) {
+	if len(p.Nodes) == 0 {
+		return 1, 1
+	}
+	for _, n := range p.Nodes {
+		all++
+		if n.Role == roleControlPlane {
+			controlPlanes++
+		}
+	}
+	return all, controlPlanes
+}
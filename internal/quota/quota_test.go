@@ -0,0 +1,224 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+package quota
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/v2/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/test"
+
+	clusterv1alpha1 "github.com/humoflife/provider-kind/apis/cluster/v1alpha1"
+	namespacedclusterv1alpha1 "github.com/humoflife/provider-kind/apis/namespacedcluster/v1alpha1"
+	"github.com/humoflife/provider-kind/apis/v1beta1"
+)
+
+func withNodes(roles ...string) clusterv1alpha1.ClusterParameters {
+	p := clusterv1alpha1.ClusterParameters{}
+	for _, r := range roles {
+		p.Nodes = append(p.Nodes, clusterv1alpha1.Node{Role: r})
+	}
+	return p
+}
+
+func TestCheck(t *testing.T) {
+	type args struct {
+		q         *v1beta1.Quotas
+		u         Usage
+		namespace string
+		p         clusterv1alpha1.ClusterParameters
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   string
+	}{
+		"NoQuotas": {
+			reason: "A ProviderConfig without quotas should admit any cluster.",
+			args:   args{u: Usage{Clusters: 100}, p: withNodes("control-plane", "worker")},
+		},
+		"WithinQuotas": {
+			reason: "A cluster within every quota should be admitted.",
+			args: args{
+				q: &v1beta1.Quotas{
+					MaxClusters:             ptr.To[int32](2),
+					MaxNodes:                ptr.To[int32](3),
+					MaxControlPlaneNodes:    ptr.To[int32](1),
+					MaxClustersPerNamespace: ptr.To[int32](1),
+				},
+				u:         Usage{Clusters: 1, Nodes: 1, Namespaces: map[string]int32{"other": 1}},
+				namespace: "tenant",
+				p:         withNodes("control-plane", "worker"),
+			},
+		},
+		"MaxClusters": {
+			reason: "A cluster beyond the maximum number of clusters should be rejected.",
+			args: args{
+				q: &v1beta1.Quotas{MaxClusters: ptr.To[int32](1)},
+				u: Usage{Clusters: 1},
+			},
+			want: "1 of 1 clusters are in use",
+		},
+		"MaxNodesImplicitControlPlane": {
+			reason: "A cluster without listed nodes should count as one node.",
+			args: args{
+				q: &v1beta1.Quotas{MaxNodes: ptr.To[int32](2)},
+				u: Usage{Nodes: 2},
+			},
+			want: "2 of 2 nodes are in use and the cluster has 1",
+		},
+		"MaxControlPlaneNodes": {
+			reason: "A cluster with too many control plane nodes should be rejected regardless of usage.",
+			args: args{
+				q: &v1beta1.Quotas{MaxControlPlaneNodes: ptr.To[int32](1)},
+				p: withNodes("control-plane", "control-plane", "worker"),
+			},
+			want: "the cluster has 2 control plane nodes, more than the maximum of 1",
+		},
+		"MaxClustersPerNamespace": {
+			reason: "A namespaced cluster beyond the maximum of its namespace should be rejected.",
+			args: args{
+				q:         &v1beta1.Quotas{MaxClustersPerNamespace: ptr.To[int32](1)},
+				u:         Usage{Namespaces: map[string]int32{"tenant": 1}},
+				namespace: "tenant",
+			},
+			want: "1 of 1 clusters are in use in namespace tenant",
+		},
+		"ClusterScopedIgnoresPerNamespace": {
+			reason: "Cluster-scoped clusters should not count against a namespace.",
+			args: args{
+				q: &v1beta1.Quotas{MaxClustersPerNamespace: ptr.To[int32](1)},
+				u: Usage{Namespaces: map[string]int32{"": 5}},
+			},
+		},
+		"Several": {
+			reason: "Every exceeded quota should be reported.",
+			args: args{
+				q: &v1beta1.Quotas{MaxClusters: ptr.To[int32](1), MaxNodes: ptr.To[int32](1)},
+				u: Usage{Clusters: 1, Nodes: 1},
+			},
+			want: "1 of 1 clusters are in use; 1 of 1 nodes are in use and the cluster has 1",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := Check(tc.args.q, tc.args.u, tc.args.namespace, tc.args.p)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nCheck(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+// clusters returns a client that lists the supplied namespaced clusters and
+// no cluster-scoped ones.
+func clusters(cs ...namespacedclusterv1alpha1.Cluster) client.Reader {
+	return &test.MockClient{MockList: func(_ context.Context, l client.ObjectList, _ ...client.ListOption) error {
+		if nl, ok := l.(*namespacedclusterv1alpha1.ClusterList); ok {
+			nl.Items = cs
+		}
+		return nil
+	}}
+}
+
+func cluster(uid, namespace, providerConfig string, created bool) namespacedclusterv1alpha1.Cluster {
+	c := namespacedclusterv1alpha1.Cluster{ObjectMeta: metav1.ObjectMeta{UID: types.UID(uid), Namespace: namespace}}
+	if providerConfig != "" {
+		c.Spec.ProviderConfigReference = &xpv1.ProviderConfigReference{Kind: "ProviderConfig", Name: providerConfig}
+	}
+	if created {
+		meta.SetExternalCreateSucceeded(&c, metav1.Now().Time)
+	}
+	return c
+}
+
+func TestAdmit(t *testing.T) {
+	q := &v1beta1.Quotas{MaxClusters: ptr.To[int32](2)}
+
+	type step struct {
+		kube client.Reader
+		uid  string
+		want string
+	}
+
+	cases := map[string]struct {
+		reason         string
+		providerConfig string
+		steps          []step
+	}{
+		"Concurrent": {
+			providerConfig: "pc",
+			reason:         "A cluster admitted but not yet created should hold its place against other clusters.",
+			steps: []step{
+				{kube: clusters(cluster("a", "t", "pc", false), cluster("b", "t", "pc", false), cluster("c", "t", "pc", false)), uid: "a"},
+				{kube: clusters(cluster("a", "t", "pc", false), cluster("b", "t", "pc", false), cluster("c", "t", "pc", false)), uid: "b"},
+				{kube: clusters(cluster("a", "t", "pc", false), cluster("b", "t", "pc", false), cluster("c", "t", "pc", false)), uid: "c", want: "2 of 2 clusters are in use"},
+			},
+		},
+		"Readmit": {
+			providerConfig: "pc",
+			reason:         "A cluster should not count its own reservation when it is admitted again.",
+			steps: []step{
+				{kube: clusters(cluster("a", "t", "pc", true), cluster("b", "t", "pc", false)), uid: "b"},
+				{kube: clusters(cluster("a", "t", "pc", true), cluster("b", "t", "pc", false)), uid: "b"},
+			},
+		},
+		"Deleted": {
+			providerConfig: "pc",
+			reason:         "The reservation of a cluster that no longer exists should be dropped.",
+			steps: []step{
+				{kube: clusters(cluster("a", "t", "pc", true), cluster("b", "t", "pc", false)), uid: "b"},
+				{kube: clusters(cluster("a", "t", "pc", true), cluster("c", "t", "pc", false)), uid: "c"},
+			},
+		},
+		"Created": {
+			providerConfig: "pc",
+			reason:         "The reservation of a cluster should be dropped once it is counted as created, rather than counted twice.",
+			steps: []step{
+				{kube: clusters(cluster("a", "t", "pc", false), cluster("b", "t", "pc", false)), uid: "a"},
+				{kube: clusters(cluster("a", "t", "pc", true), cluster("b", "t", "pc", false)), uid: "b"},
+			},
+		},
+		"OtherProviderConfig": {
+			providerConfig: "pc",
+			reason:         "Clusters of other ProviderConfigs should not count.",
+			steps: []step{
+				{kube: clusters(cluster("a", "t", "other", true), cluster("b", "t", "other", true), cluster("c", "t", "pc", false)), uid: "c"},
+			},
+		},
+		"DefaultProviderConfig": {
+			providerConfig: DefaultProviderConfig,
+			reason:         "Clusters that reference no ProviderConfig should count against the default one.",
+			steps: []step{
+				{kube: clusters(cluster("a", "t", "", true), cluster("b", "t", "", true), cluster("c", "t", "", false)), uid: "c", want: "2 of 2 clusters are in use"},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			reserved = map[string]map[types.UID]reservation{}
+			for i, s := range tc.steps {
+				got, err := Admit(context.Background(), s.kube, q, tc.providerConfig, types.UID(s.uid), "t", clusterv1alpha1.ClusterParameters{})
+				if err != nil {
+					t.Fatalf("\n%s\nAdmit(...) step %d: %v", tc.reason, i, err)
+				}
+				if diff := cmp.Diff(s.want, got); diff != "" {
+					t.Errorf("\n%s\nAdmit(...) step %d: -want, +got:\n%s", tc.reason, i, diff)
+				}
+			}
+		})
+	}
+}
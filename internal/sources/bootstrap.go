@@ -0,0 +1,99 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+package sources
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	clusterv1alpha1 "github.com/humoflife/provider-kind/apis/cluster/v1alpha1"
+	"github.com/humoflife/provider-kind/internal/kindnode"
+)
+
+const (
+	errResolveBootstrapStep = "cannot resolve bootstrap step %q"
+)
+
+// BootstrapSteps resolves the manifests of each bootstrap step, in order.
+func (r Resolver) BootstrapSteps(ctx context.Context, b *clusterv1alpha1.Bootstrap) ([]kindnode.BootstrapStep, error) {
+	if b == nil {
+		return nil, nil
+	}
+	out := make([]kindnode.BootstrapStep, 0, len(b.Steps))
+	for _, s := range b.Steps {
+		var (
+			manifest string
+			err      error
+		)
+		switch {
+		case s.SecretRef != nil && s.ConfigMapRef == nil:
+			manifest, err = r.secretManifests(ctx, *s.SecretRef)
+		case s.ConfigMapRef != nil && s.SecretRef == nil:
+			manifest, err = r.configMapManifests(ctx, *s.ConfigMapRef)
+		default:
+			err = errors.New(errNoSource)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, errResolveBootstrapStep, s.Name)
+		}
+		out = append(out, kindnode.BootstrapStep{Name: s.Name, Manifest: manifest})
+	}
+	return out, nil
+}
+
+// secretManifests returns the selected manifests of a Secret.
+func (r Resolver) secretManifests(ctx context.Context, sel clusterv1alpha1.ManifestSelector) (string, error) {
+	if sel.Key != "" {
+		v, err := r.SecretKey(ctx, clusterv1alpha1.KeySelector{Name: sel.Name, Namespace: sel.Namespace, Key: sel.Key}, "")
+		return string(v), err
+	}
+	s, err := r.Secret(ctx, sel.Name, sel.Namespace)
+	if err != nil {
+		return "", err
+	}
+	docs := map[string]string{}
+	for k, v := range s.Data {
+		docs[k] = string(v)
+	}
+	return joinManifests(docs), nil
+}
+
+// configMapManifests returns the selected manifests of a ConfigMap.
+func (r Resolver) configMapManifests(ctx context.Context, sel clusterv1alpha1.ManifestSelector) (string, error) {
+	if sel.Key != "" {
+		v, err := r.ConfigMapKey(ctx, clusterv1alpha1.KeySelector{Name: sel.Name, Namespace: sel.Namespace, Key: sel.Key}, "")
+		return string(v), err
+	}
+	cm, err := r.ConfigMap(ctx, sel.Name, sel.Namespace)
+	if err != nil {
+		return "", err
+	}
+	docs := map[string]string{}
+	for k, v := range cm.Data {
+		docs[k] = v
+	}
+	for k, v := range cm.BinaryData {
+		docs[k] = string(v)
+	}
+	return joinManifests(docs), nil
+}
+
+// joinManifests joins YAML documents into one stream, in lexical order of
+// their keys.
+func joinManifests(docs map[string]string) string {
+	keys := make([]string, 0, len(docs))
+	for k := range docs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make([]string, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, docs[k])
+	}
+	return strings.Join(out, "\n---\n")
+}
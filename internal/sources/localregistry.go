@@ -0,0 +1,145 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+package sources
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	clusterv1alpha1 "github.com/humoflife/provider-kind/apis/cluster/v1alpha1"
+	registryv1alpha1 "github.com/humoflife/provider-kind/apis/registry/v1alpha1"
+	"github.com/humoflife/provider-kind/internal/docker"
+	"github.com/humoflife/provider-kind/internal/kindnode"
+)
+
+const (
+	errGetRegistry         = "cannot get Registry %q"
+	errRegistryNotReady    = "local registry %q is not ready"
+	errRegistryNetwork     = "local registry %q is attached to Docker network %q, not to network %q of the cluster"
+	errRegistryNotAttached = "local registry %q is not attached to Docker network %q yet"
+	errInspectRegistry     = "cannot inspect local registry container %q"
+
+	localRegistryHelp = "https://kind.sigs.k8s.io/docs/user/local-registry/"
+
+	// registryNetwork is the Docker network Registries are attached to if
+	// they name none.
+	registryNetwork = "kind"
+)
+
+// localRegistryHosting is the ConfigMap described by KEP-1755 that tells
+// tools where to push images for the cluster.
+const localRegistryHosting = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: local-registry-hosting
+  namespace: kube-public
+data:
+  localRegistryHosting.v1: |
+%s`
+
+// LocalRegistry resolves a reference to a Registry managed resource into the
+// registry hosts that must be mirrored to it, and the manifest that
+// advertises it inside the cluster. The registry must be attached to the
+// supplied Docker network of the cluster, since the nodes reach it by its
+// container name.
+func (r Resolver) LocalRegistry(ctx context.Context, ref clusterv1alpha1.LocalRegistryReference, network string) ([]kindnode.Registry, string, error) {
+	reg := &registryv1alpha1.Registry{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Name: ref.Name}, reg); err != nil {
+		return nil, "", errors.Wrapf(err, errGetRegistry, ref.Name)
+	}
+	obs := reg.Status.AtProvider
+	if obs.NetworkEndpoint == "" {
+		return nil, "", errors.Errorf(errRegistryNotReady, ref.Name)
+	}
+	if err := registryAttached(ctx, reg, network); err != nil {
+		return nil, "", err
+	}
+	endpoint := "http://" + obs.NetworkEndpoint
+
+	// Images are pushed to the host endpoint, so the nodes resolve that
+	// name to the registry container. Without a host port the network
+	// endpoint is the only name the registry has.
+	host := obs.HostEndpoint
+	if host == "" {
+		host = obs.NetworkEndpoint
+	}
+	hosts := []kindnode.Registry{{
+		Host:    host,
+		Server:  endpoint,
+		Mirrors: []kindnode.Mirror{{Endpoint: endpoint}},
+	}}
+	if obs.UpstreamHost != "" {
+		hosts = append(hosts, kindnode.Registry{
+			Host:    registryHost(obs.UpstreamHost),
+			Mirrors: []kindnode.Mirror{{Endpoint: endpoint}},
+		})
+	}
+
+	var b strings.Builder
+	if obs.HostEndpoint != "" {
+		fmt.Fprintf(&b, "    host: %q\n", obs.HostEndpoint)
+	}
+	fmt.Fprintf(&b, "    hostFromContainerRuntime: %q\n", obs.NetworkEndpoint)
+	fmt.Fprintf(&b, "    help: %q\n", localRegistryHelp)
+
+	return hosts, fmt.Sprintf(localRegistryHosting, b.String()), nil
+}
+
+// registryAttached returns an error if the registry is not attached to the
+// Docker network. A network that does not exist yet is created with the
+// first cluster on it, and the registry is attached to it once it exists.
+func registryAttached(ctx context.Context, reg *registryv1alpha1.Registry, network string) error {
+	attached := registryNetwork
+	if n := reg.Spec.ForProvider.Network; n != nil {
+		attached = *n
+	}
+	if attached != network {
+		return errors.Errorf(errRegistryNetwork, reg.GetName(), attached, network)
+	}
+
+	exists, err := docker.NetworkExists(ctx, network)
+	if err != nil {
+		return errors.Wrapf(err, errInspectNetwork, network)
+	}
+	if !exists {
+		return nil
+	}
+	name := reg.Status.AtProvider.ContainerName
+	c, err := docker.InspectContainer(ctx, name)
+	if err != nil {
+		return errors.Wrapf(err, errInspectRegistry, name)
+	}
+	if c == nil {
+		return errors.Errorf(errRegistryNotReady, reg.GetName())
+	}
+	if _, ok := c.NetworkSettings.Networks[network]; !ok {
+		return errors.Errorf(errRegistryNotAttached, reg.GetName(), network)
+	}
+	return nil
+}
+
+// mergeRegistries adds the local registry hosts to the configured ones. A
+// host that is configured explicitly keeps its settings and tries the local
+// registry first.
+func mergeRegistries(regs []kindnode.Registry, local []kindnode.Registry) []kindnode.Registry {
+	for _, l := range local {
+		merged := false
+		for i := range regs {
+			if registryHost(regs[i].Host) == registryHost(l.Host) {
+				regs[i].Mirrors = append(l.Mirrors, regs[i].Mirrors...)
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			regs = append(regs, l)
+		}
+	}
+	return regs
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+package sources
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	clusterv1alpha1 "github.com/humoflife/provider-kind/apis/cluster/v1alpha1"
+	"github.com/humoflife/provider-kind/internal/kindnode"
+)
+
+const (
+	errNoMountSource = "exactly one of hostPath, configMapRef, or secretRef must be set"
+	errResolveMount  = "cannot resolve mount %d of node %d"
+
+	// modeConfigMap and modeSecret are the modes of files copied from
+	// ConfigMaps and Secrets, like those of Kubernetes volumes.
+	modeConfigMap = "0644"
+	modeSecret    = "0600"
+)
+
+// NodeFiles resolves the ConfigMap and Secret mounts of each node of the
+// named cluster. Host path mounts are made by KIND and are not included.
+func (r Resolver) NodeFiles(ctx context.Context, cluster string, ns []clusterv1alpha1.Node) (kindnode.NodeFiles, error) {
+	out := kindnode.NodeFiles{}
+	names := nodeNames(cluster, ns)
+	for i, n := range ns {
+		var files kindnode.Files
+		for j, m := range n.ExtraMounts {
+			var (
+				f   kindnode.Files
+				err error
+			)
+			switch {
+			case m.HostPath != "" && m.ConfigMapRef == nil && m.SecretRef == nil:
+				continue
+			case m.ConfigMapRef != nil && m.HostPath == "" && m.SecretRef == nil:
+				f, err = r.configMapFiles(ctx, *m.ConfigMapRef, m.ContainerPath)
+			case m.SecretRef != nil && m.HostPath == "" && m.ConfigMapRef == nil:
+				f, err = r.secretFiles(ctx, *m.SecretRef, m.ContainerPath)
+			default:
+				err = errors.New(errNoMountSource)
+			}
+			if err != nil {
+				return nil, errors.Wrapf(err, errResolveMount, j, i)
+			}
+			files = append(files, f...)
+		}
+		if len(files) > 0 {
+			out[names[i]] = files
+		}
+	}
+	return out, nil
+}
+
+// configMapFiles returns the selected files of a ConfigMap.
+func (r Resolver) configMapFiles(ctx context.Context, sel clusterv1alpha1.MountSource, dest string) (kindnode.Files, error) {
+	if sel.Key != "" {
+		v, err := r.ConfigMapKey(ctx, clusterv1alpha1.KeySelector{Name: sel.Name, Namespace: sel.Namespace, Key: sel.Key}, "")
+		if err != nil {
+			return nil, err
+		}
+		return kindnode.Files{{Path: dest, Content: v, Mode: modeConfigMap}}, nil
+	}
+	cm, err := r.ConfigMap(ctx, sel.Name, sel.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	data := map[string][]byte{}
+	for k, v := range cm.Data {
+		data[k] = []byte(v)
+	}
+	for k, v := range cm.BinaryData {
+		data[k] = v
+	}
+	return dirFiles(data, dest, modeConfigMap), nil
+}
+
+// secretFiles returns the selected files of a Secret.
+func (r Resolver) secretFiles(ctx context.Context, sel clusterv1alpha1.MountSource, dest string) (kindnode.Files, error) {
+	if sel.Key != "" {
+		v, err := r.SecretKey(ctx, clusterv1alpha1.KeySelector{Name: sel.Name, Namespace: sel.Namespace, Key: sel.Key}, "")
+		if err != nil {
+			return nil, err
+		}
+		return kindnode.Files{{Path: dest, Content: v, Mode: modeSecret}}, nil
+	}
+	s, err := r.Secret(ctx, sel.Name, sel.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	return dirFiles(s.Data, dest, modeSecret), nil
+}
+
+// dirFiles returns a file in the directory for each key, in lexical order.
+func dirFiles(data map[string][]byte, dir, mode string) kindnode.Files {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make(kindnode.Files, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, kindnode.File{Path: path.Join(dir, k), Content: data[k], Mode: mode})
+	}
+	return out
+}
+
+// nodeNames returns the container names KIND gives the nodes of the named
+// cluster: <cluster>-<role>, with a counter from 2 on for further nodes of
+// the same role.
+func nodeNames(cluster string, ns []clusterv1alpha1.Node) []string {
+	count := map[string]int{}
+	out := make([]string, 0, len(ns))
+	for _, n := range ns {
+		count[n.Role]++
+		name := cluster + "-" + n.Role
+		if count[n.Role] > 1 {
+			name += fmt.Sprint(count[n.Role])
+		}
+		out = append(out, name)
+	}
+	return out
+}
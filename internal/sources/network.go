@@ -0,0 +1,84 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+package sources
+
+import (
+	"context"
+	"net/netip"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	clusterv1alpha1 "github.com/humoflife/provider-kind/apis/cluster/v1alpha1"
+	networkv1alpha1 "github.com/humoflife/provider-kind/apis/network/v1alpha1"
+	"github.com/humoflife/provider-kind/internal/docker"
+	"github.com/humoflife/provider-kind/internal/kindnetwork"
+	"github.com/humoflife/provider-kind/internal/kindnode"
+)
+
+const (
+	errNetworkExclusive = "at most one of networkRef or dockerNetwork may be set"
+	errGetNetwork       = "cannot get Network %q"
+	errNetworkNotReady  = "network %q is not ready"
+	errInspectNetwork   = "cannot inspect Docker network %q"
+	errNetworkNotFound  = "Docker network %q does not exist"
+	errPoolOutside      = "address pool %s is not within a subnet of Docker network %q"
+)
+
+// DockerNetwork returns the name of the Docker network the cluster nodes
+// are created on, or an empty string for the default network.
+func (r Resolver) DockerNetwork(ctx context.Context, p clusterv1alpha1.ClusterParameters) (string, error) {
+	switch {
+	case p.NetworkRef != nil && p.DockerNetwork != nil:
+		return "", errors.New(errNetworkExclusive)
+	case p.DockerNetwork != nil:
+		return *p.DockerNetwork, nil
+	case p.NetworkRef != nil:
+		n := &networkv1alpha1.Network{}
+		if err := r.Client.Get(ctx, types.NamespacedName{Name: p.NetworkRef.Name}, n); err != nil {
+			return "", errors.Wrapf(err, errGetNetwork, p.NetworkRef.Name)
+		}
+		if n.Status.AtProvider.Name == "" {
+			return "", errors.Errorf(errNetworkNotReady, p.NetworkRef.Name)
+		}
+		return n.Status.AtProvider.Name, nil
+	}
+	return "", nil
+}
+
+// LoadBalancerPool returns the address pool assigned to LoadBalancer
+// Services, or nil if load balancing is disabled. The pool must lie within a
+// subnet of the cluster's Docker network, so that the addresses are
+// reachable from the host and from other containers on it.
+func (r Resolver) LoadBalancerPool(ctx context.Context, p clusterv1alpha1.ClusterParameters) (*kindnode.AddressPool, error) {
+	if p.LoadBalancer == nil {
+		return nil, nil
+	}
+	pool, err := kindnode.ParseAddressPool(p.LoadBalancer.AddressPool)
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := r.DockerNetwork(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		name = kindnetwork.Default
+	}
+	n, err := docker.InspectNetwork(ctx, name)
+	if err != nil {
+		return nil, errors.Wrapf(err, errInspectNetwork, name)
+	}
+	if n == nil {
+		return nil, errors.Errorf(errNetworkNotFound, name)
+	}
+	for _, c := range n.IPAM.Config {
+		if subnet, err := netip.ParsePrefix(c.Subnet); err == nil && pool.Within(subnet) {
+			return &pool, nil
+		}
+	}
+	return nil, errors.Errorf(errPoolOutside, pool, name)
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+package sources
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+
+	clusterv1alpha1 "github.com/humoflife/provider-kind/apis/cluster/v1alpha1"
+	"github.com/humoflife/provider-kind/internal/kindnode"
+)
+
+const (
+	errInvalidHost     = "invalid registry host %q"
+	errResolveRegistry = "cannot resolve registry %q"
+	errResolveMirror   = "cannot resolve mirror %q"
+	errInvalidEndpoint = "invalid mirror endpoint %q"
+	errSecretType      = "Secret %s/%s must be of type %s"
+	errParseAuth       = "cannot parse Docker config in Secret %s/%s"
+	errMarshalAuth     = "cannot render registry credentials"
+)
+
+// dockerConfig is the subset of a Docker config.json used for registry
+// credentials.
+type dockerConfig struct {
+	Auths map[string]json.RawMessage `json:"auths"`
+}
+
+// Registries resolves the registry configuration of a cluster, reading TLS
+// material and credentials from the referenced Secrets. Only credentials for
+// the configured hosts and their mirrors are kept.
+func (r Resolver) Registries(ctx context.Context, regs []clusterv1alpha1.RegistryConfig) (kindnode.Registries, error) {
+	out := kindnode.Registries{}
+	auths := map[string]json.RawMessage{}
+
+	for _, reg := range regs {
+		if reg.Host == "" || strings.ContainsAny(reg.Host, "/ \t\n") || strings.Contains(reg.Host, "..") {
+			return kindnode.Registries{}, errors.Errorf(errInvalidHost, reg.Host)
+		}
+
+		res := kindnode.Registry{Host: reg.Host}
+		if reg.Server != nil {
+			res.Server = *reg.Server
+		}
+
+		t, err := r.tls(ctx, reg.TLS)
+		if err != nil {
+			return kindnode.Registries{}, errors.Wrapf(err, errResolveRegistry, reg.Host)
+		}
+		res.TLS = t
+
+		hosts := map[string]bool{registryHost(reg.Host): true}
+		for _, m := range reg.Mirrors {
+			u, err := url.Parse(m.Endpoint)
+			if err != nil || u.Host == "" {
+				return kindnode.Registries{}, errors.Errorf(errInvalidEndpoint, m.Endpoint)
+			}
+			hosts[registryHost(u.Host)] = true
+
+			mt, err := r.tls(ctx, m.TLS)
+			if err != nil {
+				return kindnode.Registries{}, errors.Wrapf(err, errResolveMirror, m.Endpoint)
+			}
+			mirror := kindnode.Mirror{Endpoint: m.Endpoint, Capabilities: m.Capabilities, TLS: mt}
+			if m.OverridePath != nil {
+				mirror.OverridePath = *m.OverridePath
+			}
+			res.Mirrors = append(res.Mirrors, mirror)
+		}
+
+		if reg.AuthSecretRef != nil {
+			if err := r.auths(ctx, *reg.AuthSecretRef, hosts, auths); err != nil {
+				return kindnode.Registries{}, errors.Wrapf(err, errResolveRegistry, reg.Host)
+			}
+		}
+
+		out.Hosts = append(out.Hosts, res)
+	}
+
+	if len(auths) > 0 {
+		b, err := json.Marshal(dockerConfig{Auths: auths})
+		if err != nil {
+			return kindnode.Registries{}, errors.Wrap(err, errMarshalAuth)
+		}
+		out.Auth = b
+	}
+	return out, nil
+}
+
+// tls resolves the TLS configuration of a registry endpoint.
+func (r Resolver) tls(ctx context.Context, t *clusterv1alpha1.RegistryTLS) (kindnode.TLS, error) {
+	out := kindnode.TLS{}
+	if t == nil {
+		return out, nil
+	}
+	if t.InsecureSkipVerify != nil {
+		out.InsecureSkipVerify = *t.InsecureSkipVerify
+	}
+	cas, err := r.Certificates(ctx, t.CAs)
+	if err != nil {
+		return out, err
+	}
+	out.CAs = cas
+	if ref := t.ClientCertSecretRef; ref != nil {
+		s, err := r.Secret(ctx, ref.Name, ref.Namespace)
+		if err != nil {
+			return out, err
+		}
+		if s.Type != corev1.SecretTypeTLS {
+			return out, errors.Errorf(errSecretType, s.GetNamespace(), s.GetName(), corev1.SecretTypeTLS)
+		}
+		out.ClientCert = s.Data[corev1.TLSCertKey]
+		out.ClientKey = s.Data[corev1.TLSPrivateKeyKey]
+	}
+	return out, nil
+}
+
+// auths copies the credentials for the supplied hosts from a
+// kubernetes.io/dockerconfigjson Secret into into.
+func (r Resolver) auths(ctx context.Context, ref clusterv1alpha1.SecretReference, hosts map[string]bool, into map[string]json.RawMessage) error {
+	s, err := r.Secret(ctx, ref.Name, ref.Namespace)
+	if err != nil {
+		return err
+	}
+	if s.Type != corev1.SecretTypeDockerConfigJson {
+		return errors.Errorf(errSecretType, s.GetNamespace(), s.GetName(), corev1.SecretTypeDockerConfigJson)
+	}
+	cfg := dockerConfig{}
+	if err := json.Unmarshal(s.Data[corev1.DockerConfigJsonKey], &cfg); err != nil {
+		return errors.Wrapf(err, errParseAuth, s.GetNamespace(), s.GetName())
+	}
+	for key, auth := range cfg.Auths {
+		if hosts[registryHost(key)] {
+			into[key] = auth
+		}
+	}
+	return nil
+}
+
+// registryHost normalises a registry host or Docker config key, so that the
+// different spellings of the same registry compare equal.
+func registryHost(s string) string {
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "https://"), "http://")
+	if i := strings.Index(s, "/"); i >= 0 {
+		s = s[:i]
+	}
+	switch s {
+	case "index.docker.io", "registry-1.docker.io":
+		return "docker.io"
+	}
+	return s
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+package sources
+
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	clusterv1alpha1 "github.com/humoflife/provider-kind/apis/cluster/v1alpha1"
+	"github.com/humoflife/provider-kind/apis/v1beta1"
+	"github.com/humoflife/provider-kind/internal/docker"
+)
+
+// NodeResources returns the resource limits of each node of the named
+// cluster, by node name. Limits a node does not set default to those of the
+// supplied ProviderConfig, if any. Nodes without limits are omitted.
+func (r Resolver) NodeResources(pc *v1beta1.ProviderConfig, cluster string, ns []clusterv1alpha1.Node) map[string]docker.Resources {
+	var defaults docker.Resources
+	if pc != nil && pc.Spec.NodeResources != nil {
+		d := pc.Spec.NodeResources
+		defaults = limits(d.CPUs, d.Memory, d.PidsLimit, docker.Resources{})
+	}
+
+	// KIND creates a single control plane node if none are listed, which
+	// gets the defaults.
+	if len(ns) == 0 {
+		ns = []clusterv1alpha1.Node{{Role: "control-plane"}}
+	}
+
+	out := map[string]docker.Resources{}
+	names := nodeNames(cluster, ns)
+	for i, n := range ns {
+		l := defaults
+		if n.Resources != nil {
+			l = limits(n.Resources.CPUs, n.Resources.Memory, n.Resources.PidsLimit, defaults)
+		}
+		if !l.Empty() {
+			out[names[i]] = l
+		}
+	}
+	return out
+}
+
+// limits returns the supplied limits, falling back to the defaults for those
+// that are not set.
+func limits(cpus, memory *resource.Quantity, pids *int64, defaults docker.Resources) docker.Resources {
+	l := defaults
+	if cpus != nil {
+		l.NanoCPUs = cpus.MilliValue() * 1e6
+	}
+	if memory != nil {
+		l.Memory = memory.Value()
+	}
+	if pids != nil {
+		l.PidsLimit = *pids
+	}
+	return l
+}
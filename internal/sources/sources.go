@@ -0,0 +1,212 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+// Package sources resolves the Secret and ConfigMap references used by KIND
+// cluster parameters.
+package sources
+
+import (
+	"context"
+	"slices"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
+
+	clusterv1alpha1 "github.com/humoflife/provider-kind/apis/cluster/v1alpha1"
+	"github.com/humoflife/provider-kind/apis/v1beta1"
+	"github.com/humoflife/provider-kind/internal/kindnetwork"
+	"github.com/humoflife/provider-kind/internal/kindnode"
+)
+
+const (
+	errNoSource            = "exactly one of secretRef or configMapRef must be set"
+	errNoNamespace         = "namespace is required for %s %q"
+	errGetSecret           = "cannot get Secret %s/%s"
+	errGetConfigMap        = "cannot get ConfigMap %s/%s"
+	errGetProviderConfig   = "cannot get ProviderConfig %q"
+	errProviderConfigKind  = "providerConfigRef must refer to a %s, not a %s"
+	errNamespaceNotAllowed = "ProviderConfig %q does not allow namespace %q"
+	errGetCluster          = "cannot get Cluster %q"
+	errMissingKey          = "key %q not found in %s %s/%s"
+	errResolveCertFmt      = "cannot resolve certificate %d"
+	defaultCertificate     = "ca.crt"
+)
+
+// Resolver reads referenced Secrets and ConfigMaps. When Namespace is set,
+// every reference is resolved in that namespace regardless of the namespace
+// it names. Namespaced managed resources use this to stay within their own
+// namespace.
+type Resolver struct {
+	Client    client.Reader
+	Namespace string
+}
+
+// NodeConfig resolves the provider-managed node configuration described by
+// the supplied parameters of the named cluster.
+func (r Resolver) NodeConfig(ctx context.Context, cluster string, p clusterv1alpha1.ClusterParameters) (kindnode.Config, error) {
+	cas, err := r.Certificates(ctx, p.TrustedCAs)
+	if err != nil {
+		return kindnode.Config{}, err
+	}
+	regs, err := r.Registries(ctx, p.Registries)
+	if err != nil {
+		return kindnode.Config{}, err
+	}
+	files, err := r.NodeFiles(ctx, cluster, p.Nodes)
+	if err != nil {
+		return kindnode.Config{}, err
+	}
+	cfg := kindnode.Config{TrustedCAs: cas, Registries: regs, Files: files}
+	if p.LocalRegistryRef != nil {
+		network, err := r.DockerNetwork(ctx, p)
+		if err != nil {
+			return kindnode.Config{}, err
+		}
+		if network == "" {
+			network = kindnetwork.Default
+		}
+		hosts, manifest, err := r.LocalRegistry(ctx, *p.LocalRegistryRef, network)
+		if err != nil {
+			return kindnode.Config{}, err
+		}
+		cfg.Registries.Hosts = mergeRegistries(cfg.Registries.Hosts, hosts)
+		cfg.Manifests = append(cfg.Manifests, manifest)
+	}
+	return cfg, nil
+}
+
+// Certificates returns the PEM data selected by each of the supplied sources,
+// in order.
+func (r Resolver) Certificates(ctx context.Context, srcs []clusterv1alpha1.CertificateSource) ([][]byte, error) {
+	out := make([][]byte, 0, len(srcs))
+	for i, src := range srcs {
+		var (
+			data []byte
+			err  error
+		)
+		switch {
+		case src.SecretRef != nil && src.ConfigMapRef == nil:
+			data, err = r.SecretKey(ctx, *src.SecretRef, defaultCertificate)
+		case src.ConfigMapRef != nil && src.SecretRef == nil:
+			data, err = r.ConfigMapKey(ctx, *src.ConfigMapRef, defaultCertificate)
+		default:
+			err = errors.New(errNoSource)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, errResolveCertFmt, i)
+		}
+		out = append(out, data)
+	}
+	return out, nil
+}
+
+// SecretKey returns the value of the selected Secret key, falling back to
+// def when the selector does not name a key.
+func (r Resolver) SecretKey(ctx context.Context, sel clusterv1alpha1.KeySelector, def string) ([]byte, error) {
+	s, err := r.Secret(ctx, sel.Name, sel.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	key := keyOrDefault(sel.Key, def)
+	v, ok := s.Data[key]
+	if !ok {
+		return nil, errors.Errorf(errMissingKey, key, "Secret", s.GetNamespace(), s.GetName())
+	}
+	return v, nil
+}
+
+// ConfigMapKey returns the value of the selected ConfigMap key, falling back
+// to def when the selector does not name a key. Both data and binaryData are
+// consulted.
+func (r Resolver) ConfigMapKey(ctx context.Context, sel clusterv1alpha1.KeySelector, def string) ([]byte, error) {
+	cm, err := r.ConfigMap(ctx, sel.Name, sel.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	key := keyOrDefault(sel.Key, def)
+	if v, ok := cm.Data[key]; ok {
+		return []byte(v), nil
+	}
+	if v, ok := cm.BinaryData[key]; ok {
+		return v, nil
+	}
+	return nil, errors.Errorf(errMissingKey, key, "ConfigMap", cm.GetNamespace(), cm.GetName())
+}
+
+// Secret returns the named Secret.
+func (r Resolver) Secret(ctx context.Context, name, namespace string) (*corev1.Secret, error) {
+	ns, err := r.namespace("Secret", name, namespace)
+	if err != nil {
+		return nil, err
+	}
+	s := &corev1.Secret{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Name: name, Namespace: ns}, s); err != nil {
+		return nil, errors.Wrapf(err, errGetSecret, ns, name)
+	}
+	return s, nil
+}
+
+// ConfigMap returns the named ConfigMap.
+func (r Resolver) ConfigMap(ctx context.Context, name, namespace string) (*corev1.ConfigMap, error) {
+	ns, err := r.namespace("ConfigMap", name, namespace)
+	if err != nil {
+		return nil, err
+	}
+	cm := &corev1.ConfigMap{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Name: name, Namespace: ns}, cm); err != nil {
+		return nil, errors.Wrapf(err, errGetConfigMap, ns, name)
+	}
+	return cm, nil
+}
+
+// ProviderConfig returns the ProviderConfig of the supplied kind and name.
+// When Namespace is set, a ProviderConfig that lists namespaces must list
+// it, so that only the namespaces an administrator allowed may use it.
+func (r Resolver) ProviderConfig(ctx context.Context, kind, name string) (*v1beta1.ProviderConfig, error) {
+	if kind != v1beta1.ProviderConfigKind {
+		return nil, errors.Errorf(errProviderConfigKind, v1beta1.ProviderConfigKind, kind)
+	}
+	pc := &v1beta1.ProviderConfig{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Name: name}, pc); err != nil {
+		return nil, errors.Wrapf(err, errGetProviderConfig, name)
+	}
+	if r.Namespace != "" && len(pc.Spec.Namespaces) > 0 && !slices.Contains(pc.Spec.Namespaces, r.Namespace) {
+		return nil, errors.Errorf(errNamespaceNotAllowed, name, r.Namespace)
+	}
+	return pc, nil
+}
+
+// ClusterName returns the KIND cluster name of the named cluster-scoped
+// Cluster: its external name, or its name if it has none.
+func (r Resolver) ClusterName(ctx context.Context, name string) (string, error) {
+	c := &clusterv1alpha1.Cluster{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Name: name}, c); err != nil {
+		return "", errors.Wrapf(err, errGetCluster, name)
+	}
+	if n := meta.GetExternalName(c); n != "" {
+		return n, nil
+	}
+	return c.GetName(), nil
+}
+
+func (r Resolver) namespace(kind, name, namespace string) (string, error) {
+	if r.Namespace != "" {
+		return r.Namespace, nil
+	}
+	if namespace == "" {
+		return "", errors.Errorf(errNoNamespace, kind, name)
+	}
+	return namespace, nil
+}
+
+func keyOrDefault(key, def string) string {
+	if key != "" {
+		return key
+	}
+	return def
+}
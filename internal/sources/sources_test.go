@@ -0,0 +1,65 @@
+/*
+Copyright 2024 The provider-kind authors.
+*/
+
+package sources
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/test"
+
+	"github.com/humoflife/provider-kind/apis/v1beta1"
+)
+
+func TestProviderConfig(t *testing.T) {
+	type args struct {
+		namespace  string
+		namespaces []string
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   error
+	}{
+		"ClusterScoped": {
+			reason: "Cluster-scoped resources should be allowed to use any ProviderConfig.",
+			args:   args{namespaces: []string{"team-a"}},
+		},
+		"NoNamespaces": {
+			reason: "A ProviderConfig that lists no namespaces should be allowed from every namespace.",
+			args:   args{namespace: "team-a"},
+		},
+		"Listed": {
+			reason: "A ProviderConfig should be allowed from the namespaces it lists.",
+			args:   args{namespace: "team-a", namespaces: []string{"team-a", "team-b"}},
+		},
+		"NotListed": {
+			reason: "A ProviderConfig should not be allowed from namespaces it does not list.",
+			args:   args{namespace: "team-c", namespaces: []string{"team-a", "team-b"}},
+			want:   errors.Errorf(errNamespaceNotAllowed, "default", "team-c"),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			r := Resolver{
+				Client: &test.MockClient{MockGet: test.NewMockGetFn(nil, func(obj client.Object) error {
+					obj.(*v1beta1.ProviderConfig).Spec.Namespaces = tc.args.namespaces
+					return nil
+				})},
+				Namespace: tc.args.namespace,
+			}
+			_, err := r.ProviderConfig(context.Background(), v1beta1.ProviderConfigKind, "default")
+			if diff := cmp.Diff(tc.want, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nProviderConfig(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}